@@ -0,0 +1,137 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service"
+)
+
+// STRUCT: 適用するDMLのダンプ定義名(--localの場合はローカル用DML)
+var applyDml string
+var applyLocal bool
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply [bundle-dir]",
+	Short: "apply transfer bundle to product database.",
+	Long:  "verify transfer bundle, drop existing schemas and objects (clean.sql), apply ddl and dml to product database of selected profile, and verify row counts with manifest. (bundle-dir default: dist/<tool>/<app>(<key>))",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		// PROCESS: 現在時刻(Elapse計測用)
+		now := time.Now()
+
+		// PROCESS: config, データベース(Sqlboiler)コネクションの取得
+		config, conns, cleanUp := infra.LeadConfig(version, applyRunFlags)
+		defer cleanUp()
+		bundleDir := config.TransferDir()
+		if len(args) > 0 {
+			bundleDir = filepath.Clean(args[0])
+		}
+
+		// PROCESS: 適用するダンプファイル(ダンプ定義から取得)
+		dumps, err := infra.LoadDumpProfiles(config.Base.DumpFile)
+		if err != nil {
+			return err
+		}
+		if applyLocal {
+			applyDml = LOCAL_DML
+		}
+		ddl, err := infra.FindDumpProfile(dumps, AWS_DDL)
+		if err != nil {
+			return err
+		}
+		dml, err := infra.FindDumpProfile(dumps, applyDml)
+		if err != nil {
+			return err
+		}
+		dmlFile := dml.File
+
+		// PROCESS: 成果物の検証(改ざん/欠落がある場合は適用しない)
+		manifest, verifyMsg, verified := service.VerifyBundle(bundleDir)
+		if verified != nil {
+			fmt.Println(verifyMsg)
+			return verified
+		}
+
+		// PROCESS: 適用(スキーマのDROP → clean.sql → DDL → DML)
+		// INFO: 1つのpsqlセッション/トランザクションで適用する(いずれかでエラーの場合は全体をロールバックし、既存データは残る)
+		// INFO: clean.sqlはスキーマ自体を削除しないため、DDLの`CREATE SCHEMA`の前に移行先のスキーマをDROPする(DDLのダンプは変更しない)
+		preamble := []string{}
+		for _, schema := range service.PRODUCT_SCHEMAS {
+			preamble = append(preamble, fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE;", schema))
+		}
+		files := []string{CLEAN_SQL, ddl.File, dmlFile}
+		s := time.Now()
+		result := "⭕"
+		paths := make([]string, len(files))
+		for i, file := range files {
+			paths[i] = path.Join(bundleDir, file)
+		}
+		failed := config.ProductDB.LoadWithPreamble(preamble, paths...)
+		if failed != nil {
+			result = "❌ (rolled back)"
+		}
+		stepMsg := "\n## Apply Steps\n\n"
+		stepMsg += fmt.Sprintf("- **elapsed**: %s (single transaction)\n- **result**: %s\n\n", infra.ElapsedStr(s), result)
+		stepMsg += "  | # | FILE |\n"
+		stepMsg += "  |--:|---|\n"
+		for i, sql := range preamble {
+			stepMsg += fmt.Sprintf("  | %d. | `%s` |\n", i+1, sql)
+		}
+		for i, file := range files {
+			stepMsg += fmt.Sprintf("  | %d. | %s |\n", len(preamble)+i+1, file)
+		}
+
+		// PROCESS: 適用件数の検証
+		countMsg := ""
+		if failed == nil {
+			expected, err := service.ExpectedRowCounts(manifest, path.Join(bundleDir, dmlFile))
+			if err != nil {
+				return err
+			}
+			actual, err := service.CountRows(context.Background(), conns.ProductDB, service.PRODUCT_SCHEMAS)
+			if err != nil {
+				return err
+			}
+			countMsg, failed = service.VerifyRowCounts(expected, actual)
+		}
+
+		// PROCESS: 処理時間計測
+		elapse := infra.ElapsedStr(now)
+
+		// PROCESS: サマリー出力
+		msg := "# Bundle Apply Result\n\n"
+		msg += fmt.Sprintf("- **operation datetime**: %s\n", now.Format("2006/01/02 15:04:05"))
+		msg += fmt.Sprintf("- **bundle**: %s\n", bundleDir)
+		msg += fmt.Sprintf("- **profile**: %s\n", profileStr(config))
+		msg += fmt.Sprintf("- **target**: %s:%d/%s (%s)\n", config.ProductDB.Host, config.ProductDB.Port, config.ProductDB.Database, config.ProductDB.ContainerName)
+		msg += fmt.Sprintf("- **dml file**: %s\n", dmlFile)
+		msg += fmt.Sprintf("- **total elapsed time**: %s\n", elapse)
+		msg += failedMsg(failed)
+		msg += verifyMsg
+		msg += stepMsg
+		msg += countMsg
+		fmt.Println(msg)
+
+		log.Printf("total elapsed time … %s\n", elapse)
+		return failed
+	},
+}
+
+// FUNCTION:
+func init() {
+	// PROCESS:フラグ値を変数にBind
+	applyCmd.Flags().StringVar(&applyDml, "dml", AWS_DML, "dump profile name of dml to apply.")
+	applyCmd.Flags().BoolVar(&applyLocal, "local", false, "apply dml for local environment. (same as --dml dml-local)")
+}
@@ -0,0 +1,54 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service"
+)
+
+// approveCmd represents the approve command
+var approveCmd = &cobra.Command{
+	Use:   "approve [review-book]",
+	Short: "import approvals from cleansing review book.",
+	Long:  "import findings approved (APPROVE=OK) in cleansing review book into approval data, and apply them on next cleansing run. (review-book default: work/<tool>/<key>/cleansing-review.xlsx)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		// PROCESS: 現在時刻(Elapse計測用)
+		now := time.Now()
+
+		// PROCESS: configの取得(DB接続なし)
+		config, err := infra.ReadConfig(version)
+		if err != nil {
+			return err
+		}
+
+		// PROCESS: レビューブック(未指定の場合はクレンジング出力先)
+		bookFile := config.ReviewBookFile()
+		if len(args) > 0 {
+			bookFile = args[0]
+		}
+
+		// PROCESS: 承認データの作成
+		msg, err := service.ImportApprovals(config, bookFile)
+		if err != nil {
+			return err
+		}
+		fmt.Println(msg)
+
+		// PROCESS: 処理時間計測
+		log.Printf("total elapsed time … %s\n", infra.ElapsedStr(now))
+		return nil
+	},
+}
+
+// FUNCTION:
+func init() {
+}
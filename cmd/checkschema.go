@@ -0,0 +1,60 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service"
+)
+
+// checkSchemaCmd represents the check-schema command
+var checkSchemaCmd = &cobra.Command{
+	Use:   "check-schema",
+	Short: "check schema drift of legacy database and clean schema.",
+	Long:  "compare tables, columns, types and nullability of legacy database and clean schema with generated models, and output drift report.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		// PROCESS: 現在時刻(Elapse計測用)
+		now := time.Now()
+
+		// PROCESS: config, データベース(Sqlboiler)コネクションの取得
+		config, conns, cleanUp := infra.LeadConfig(version, applyRunFlags)
+		defer cleanUp()
+
+		// PROCESS: スキーマ差分の検証
+		report, drift := service.CheckSchema(context.Background(), config, conns)
+		if report == "" {
+			return drift
+		}
+
+		// PROCESS: Log File出力
+		msg := "# Schema Drift Report\n\n"
+		msg += fmt.Sprintf("- **operation datetime**: %s\n", now.Format("2006/01/02 15:04:05"))
+		msg += fmt.Sprintf("- **transfer tool version**: %s\n", config.Base.ToolVersion)
+		msg += fmt.Sprintf("- **load legacy DB key**: %s\n", config.Base.LegacyDataKey)
+		msg += configMsg(config)
+		msg += failedMsg(drift)
+		msg += report
+
+		logPath := path.Join(config.CleansingDir(), ".schema-check.md")
+		if err := infra.WriteLog(logPath, msg, &now); err != nil {
+			return err
+		}
+		fmt.Println(report)
+
+		log.Printf("total elapsed time … %s\n", infra.ElapsedStr(now))
+		return drift
+	},
+}
+
+// FUNCTION:
+func init() {
+}
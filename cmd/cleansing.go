@@ -4,6 +4,7 @@ Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"path"
@@ -12,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/teru-0529/data-transfer-sandbox/service"
+	"github.com/teru-0529/data-transfer-sandbox/service/cleansing"
 )
 
 // cleansingCmd represents the cleansing command
@@ -21,46 +23,82 @@ var cleansingCmd = &cobra.Command{
 	Long:  "data check and clensing service from legacy database.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		// PROCESS: 現在時刻(Elapse計測用)
-		now := time.Now()
-
 		// PROCESS: config, データベース(Sqlboiler)コネクションの取得
-		config, conns, cleanUp := infra.LeadConfig(version)
+		config, conns, cleanUp := infra.LeadConfig(version, applyRunFlags)
 		defer cleanUp()
-		distDir := config.CleansingDir()
 
-		// PROCESS: クレンジング実行
-		clensingMsg := service.Cleansing(conns)
+		return runCleansing(config, conns, cleansing.SubsetSpec{})
+	},
+}
+
+// FUNCTION:
+func init() {
+	// PROCESS:フラグ値を変数にBind
+	cleansingCmd.Flags().BoolVar(&allowDrift, "allow-drift", false, "continue cleansing with warnings even if schema drift is detected.")
+}
 
-		// PROCESS: データダンプ
+// FUNCTION: クレンジングの実行(ダンプ、ログ出力を含む)
+func runCleansing(config infra.Config, conns infra.DbConnection, spec cleansing.SubsetSpec) error {
+
+	// PROCESS: 現在時刻(Elapse計測用)
+	now := time.Now()
+	distDir := config.CleansingDir()
+
+	// PROCESS: クレンジング実行
+	run, cancel := infra.NewRunContext(config.Run)
+	defer cancel()
+	report, refused := service.Cleansing(run, config, conns, allowDrift, spec)
+	interrupted := infra.Interruption(run)
+
+	// PROCESS: データダンプ(中断時/中止時はダンプしない)
+	if interrupted == nil && refused == nil {
 		filePath := path.Join(distDir, WORK_DML)
 		if err := config.WorkDB.Dump(filePath, dmlWorkArgs()); err != nil {
 			return err
 		}
 
-		// PROCESS: 処理時間計測
-		elapse := infra.ElapsedStr(now)
+		// PROCESS: ダンプ時の件数(load時の検証用)
+		counts, err := service.CountRows(context.Background(), conns.WorkDB, service.WORK_SCHEMAS)
+		if err != nil {
+			return err
+		}
+		if err := counts.Save(path.Join(distDir, WORK_COUNTS)); err != nil {
+			return err
+		}
+	}
+
+	// PROCESS: 処理時間計測
+	elapse := infra.ElapsedStr(now)
 
-		// PROCESS: Log File出力
-		msg := "# Data Cleansing Result\n\n"
-		msg += fmt.Sprintf("- **operation datetime**: %s\n", now.Format("2006/01/02 15:04:05"))
-		msg += fmt.Sprintf("- **transfer tool version**: %s\n", config.Base.ToolVersion)
-		msg += fmt.Sprintf("- **load legacy DB key**: %s\n", config.Base.LegacyDataKey)
-		msg += fmt.Sprintf("- **total elapsed time**: %s\n", elapse)
-		msg += clensingMsg
+	// PROCESS: Log File出力(markdown/HTML/JSON)
+	report.Title = "Data Cleansing Result"
+	report.AddItem("operation datetime", now.Format("2006/01/02 15:04:05"))
+	report.AddItem("transfer tool version", config.Base.ToolVersion)
+	report.AddItem("load legacy DB key", config.Base.LegacyDataKey)
+	report.AddItems(configItems(config)...)
+	report.AddItem("total elapsed time", elapse)
+	report.AddItems(interruptedItems(interrupted)...)
+	report.AddItems(failedItems(refused)...)
 
-		logPath := path.Join(distDir, ".cleansing-log.md")
-		if err := infra.WriteLog(logPath, msg, &now); err != nil {
+	if err := report.Write(distDir, CLEANSING_LOG, &now); err != nil {
+		return err
+	}
+
+	// PROCESS: レビューブック出力(業務担当者による指摘確認用)
+	if report.Cleansing != nil {
+		if err := report.Cleansing.WriteReviewBook(config.ReviewBookFile()); err != nil {
 			return err
 		}
+	}
 
-		log.Printf("total elapsed time … %s\n", elapse)
-		return nil
-	},
-}
-
-// FUNCTION:
-func init() {
+	log.Printf("total elapsed time … %s\n", elapse)
+	if refused != nil {
+		return refused
+	}
+	if interrupted != nil {
+		return fmt.Errorf("cleansing interrupted: %v", interrupted)
+	}
+	return nil
 }
 
 // FUNCTION:
@@ -73,5 +111,6 @@ func dmlWorkArgs() []string {
 		"--format=P",
 		"--data-only",
 		"--schema=clean",
+		"--schema=quarantine",
 	}
 }
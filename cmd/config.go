@@ -0,0 +1,78 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "show or validate effective configuration.",
+	Long:  "show or validate effective configuration merged from flags, environment variables, config file(profile) and .env file.",
+}
+
+// configShowCmd represents the config show command
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "show effective configuration (passwords are masked).",
+	Long:  "show effective configuration in config file format (passwords are masked).",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := infra.ReadConfig(version, applyRunFlags)
+		if err != nil {
+			return err
+		}
+
+		str, err := config.Show()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# profile: %s\n", profileStr(config))
+		fmt.Print(str)
+		return nil
+	},
+}
+
+// configValidateCmd represents the config validate command
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "validate effective configuration.",
+	Long:  "validate effective configuration without connecting databases.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := infra.ReadConfig(version, applyRunFlags)
+		if err != nil {
+			fmt.Printf("NG: %v\n", err)
+			return fmt.Errorf("invalid configuration: %v", err)
+		}
+
+		errs := service.ValidateConfig(config)
+		for _, err := range errs {
+			fmt.Printf("NG: %v\n", err)
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("invalid configuration (profile: %s): %d error(s)", profileStr(config), len(errs))
+		}
+		fmt.Printf("OK: configuration is valid (profile: %s)\n", profileStr(config))
+		return nil
+	},
+}
+
+// FUNCTION:
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+}
+
+// FUNCTION: プロファイル名(未指定の場合は`-`)
+func profileStr(config infra.Config) string {
+	if config.Base.Profile == "" {
+		return "-"
+	}
+	return config.Base.Profile
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service/generate"
+)
+
+// STRUCT: 合成データの生成条件(コマンドライン指定値)
+var generateSpec generate.Spec
+var ruleRates map[string]string
+var generateOutput string
+var replaceLegacy bool
+
+// generateCmd represents the generate command
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "generate synthetic legacy data for tests and demos.",
+	Long:  "generate synthetic operators, products, orders and order_details with injected errors for every cleansing rule, and write them to SQL file or legacy database.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		// PROCESS: 現在時刻(Elapse計測用)
+		now := time.Now()
+
+		// PROCESS: 生成条件(ルール毎の混入率)
+		generateSpec.RuleRates = map[string]float64{}
+		for id, value := range ruleRates {
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid error rate of %s: %s", id, value)
+			}
+			generateSpec.RuleRates[id] = rate
+		}
+		if err := generateSpec.Validate(); err != nil {
+			return err
+		}
+
+		// PROCESS: データ生成
+		data := generate.Generate(generateSpec)
+
+		// PROCESS: 出力(SQLファイル/移行元DB)
+		// INFO: 移行元DBの既存データをTRUNCATEするため、--replaceの指定を必須とする
+		if generateOutput != "" {
+			if err := data.WriteSQL(generateOutput); err != nil {
+				return err
+			}
+			log.Printf("generated sql file [%s]\n", generateOutput)
+		} else {
+			if !replaceLegacy {
+				return fmt.Errorf("generate into legacy database truncates existing data: specify --replace, or --output to write sql file")
+			}
+			_, conns, cleanUp := infra.LeadConfig(version, applyRunFlags)
+			defer cleanUp()
+			if err := data.Insert(context.Background(), conns.LegacyDB); err != nil {
+				return err
+			}
+			log.Print("generated data inserted into legacy database")
+		}
+		fmt.Println(data.Report())
+
+		log.Printf("total elapsed time … %s\n", infra.ElapsedStr(now))
+		return nil
+	},
+}
+
+// FUNCTION:
+func init() {
+	// PROCESS:フラグ値を変数にBind
+	flags := generateCmd.Flags()
+	flags.IntVar(&generateSpec.Operators, "operators", 50, "number of operators. (max 9999)")
+	flags.IntVar(&generateSpec.Products, "products", 200, "number of products. (max 9999)")
+	flags.IntVar(&generateSpec.Orders, "orders", 1000, "number of orders. (max 99999)")
+	flags.IntVar(&generateSpec.MaxDetails, "max-details", 5, "max number of order_details per order.")
+	flags.Uint64Var(&generateSpec.Seed, "seed", 1, "random seed, same seed generates same data.")
+	flags.Float64Var(&generateSpec.ErrorRate, "error-rate", 0.05, "default rate of injected errors for every rule. (0-1)")
+	flags.StringToStringVar(&ruleRates, "rule-rate", nil, "rate of injected errors per rule. (e.g. --rule-rate \"#1-02=0.1,split=0.2\")")
+	flags.StringVar(&generateSpec.DateLayout, "date-layout", "20060102", "format of order_date. (DATE_LAYOUT)")
+	flags.StringVarP(&generateOutput, "output", "o", "", "output sql file path instead of legacy database.")
+	flags.BoolVar(&replaceLegacy, "replace", false, "truncate and replace data of legacy database.")
+}
@@ -4,6 +4,7 @@ Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -15,6 +16,8 @@ import (
 	"github.com/teru-0529/data-transfer-sandbox/service"
 )
 
+var skipVerify bool
+
 // loadCmd represents the load command
 var loadCmd = &cobra.Command{
 	Use:   "load",
@@ -26,30 +29,69 @@ var loadCmd = &cobra.Command{
 		now := time.Now()
 
 		// PROCESS: config, データベース(Sqlboiler)コネクションの取得
-		config, conns, cleanUp := infra.LeadConfig(version)
+		config, conns, cleanUp := infra.LeadConfig(version, applyRunFlags)
 		defer cleanUp()
 		distDir := config.CleansingDir()
 
 		// PROCESS: ファイルが存在しない場合エラー
 		loadfilePath := path.Join(distDir, WORK_DML)
-		if f, err := os.Stat(loadfilePath); os.IsNotExist(err) || f.IsDir() {
-			return fmt.Errorf("not exist loadfile[%s]: %s", loadfilePath, err.Error())
+		if f, err := os.Stat(loadfilePath); err != nil || f.IsDir() {
+			return fmt.Errorf("not exist loadfile[%s]", loadfilePath)
+		}
+
+		// PROCESS: 期待件数(ダンプ作成時の件数)の読込み(--skip-verifyの場合は件数のみ出力)
+		var expected service.RowCounts
+		if !skipVerify {
+			counts, err := service.LoadRowCounts(path.Join(distDir, WORK_COUNTS))
+			if err != nil {
+				return err
+			}
+			expected = counts
 		}
 
 		// PROCESS: データロード先(workDB)トランケート
-		service.TruncateCleanDbAll(conns)
+		if err := service.TruncateCleanDbAll(conns); err != nil {
+			return err
+		}
 
 		// PROCESS: データロード
 		if err := config.WorkDB.Load(loadfilePath); err != nil {
 			return err
 		}
 
+		// PROCESS: ロード件数の検証
+		actual, err := service.CountRows(context.Background(), conns.WorkDB, service.WORK_SCHEMAS)
+		if err != nil {
+			return err
+		}
+		verifyMsg, verified := service.VerifyRowCounts(expected, actual)
+
 		// PROCESS: 処理時間計測
-		log.Printf("total elapsed time … %s\n", infra.ElapsedStr(now))
-		return nil
+		elapse := infra.ElapsedStr(now)
+
+		// PROCESS: Log File出力
+		msg := "# Clean DB Load Result\n\n"
+		msg += fmt.Sprintf("- **operation datetime**: %s\n", now.Format("2006/01/02 15:04:05"))
+		msg += fmt.Sprintf("- **transfer tool version**: %s\n", config.Base.ToolVersion)
+		msg += fmt.Sprintf("- **load legacy DB key**: %s\n", config.Base.LegacyDataKey)
+		msg += fmt.Sprintf("- **load file**: %s\n", WORK_DML)
+		msg += fmt.Sprintf("- **total elapsed time**: %s\n", elapse)
+		msg += failedMsg(verified)
+		msg += verifyMsg
+
+		logPath := path.Join(distDir, ".load-log.md")
+		if err := infra.WriteLog(logPath, msg, &now); err != nil {
+			return err
+		}
+		fmt.Println(verifyMsg)
+
+		log.Printf("total elapsed time … %s\n", elapse)
+		return verified
 	},
 }
 
 // FUNCTION:
 func init() {
+	// PROCESS:フラグ値を変数にBind
+	loadCmd.Flags().BoolVar(&skipVerify, "skip-verify", false, "load without row count verification. (when dml-work.counts.json does not exist)")
 }
@@ -0,0 +1,80 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service"
+)
+
+// STRUCT: 回帰テストのLEGACY_DATA_KEY(通常の実行結果を上書きしない)
+const REGRESSION_KEY = "regression"
+
+var goldenDir string
+var updateGolden bool
+
+// regressCmd represents the regress command
+var regressCmd = &cobra.Command{
+	Use:   "regress",
+	Short: "run cleansing and transfer over fixed synthetic data and compare the results with golden files.",
+	Long:  "replace legacy database with fixed-seed synthetic data, run cleansing and transfer, and compare clean/product tables and reports with golden files. (--update rewrites golden files)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		// PROCESS: 現在時刻(Elapse計測用)
+		now := time.Now()
+
+		// INFO: 移行元DBの既存データをTRUNCATEするため、--replaceの指定を必須とする
+		if !replaceLegacy {
+			return fmt.Errorf("regress truncates existing data of legacy database: specify --replace")
+		}
+
+		// PROCESS: config, データベース(Sqlboiler)コネクションの取得
+		// INFO: マッピング定義/個別指定値はゴールデンファイルのディレクトリから読込み、全ルールを有効にする(集約結果はビューと突合せる)
+		config, conns, cleanUp := infra.LeadConfig(version, applyRunFlags, func(config *infra.Config) {
+			config.Base.LegacyDataKey = REGRESSION_KEY
+			config.Base.MappingFile = path.Join(goldenDir, "name-mapping.yaml")
+			config.Base.OverrideDir = path.Join(goldenDir, "overrides")
+			config.Run.DisabledRules = nil
+			config.Run.TransferViewCheck = true
+		})
+		defer cleanUp()
+
+		// PROCESS: 回帰テスト実行
+		run, cancel := infra.NewRunContext(config.Run)
+		defer cancel()
+		snapshot, err := service.TakeSnapshot(run, config, conns)
+		if err != nil {
+			return err
+		}
+
+		// PROCESS: ゴールデンファイルの更新/比較(インメモリによる回帰テスト(go test)とは格納先を分ける)
+		dbGoldenDir := path.Join(goldenDir, service.DB_GOLDEN_DIR)
+		if updateGolden {
+			if err := snapshot.Save(dbGoldenDir); err != nil {
+				return err
+			}
+			log.Printf("golden files updated [%s] … %d files\n", dbGoldenDir, len(snapshot))
+			log.Printf("total elapsed time … %s\n", infra.ElapsedStr(now))
+			return nil
+		}
+		msg, err := snapshot.Compare(dbGoldenDir)
+		fmt.Println(msg)
+		log.Printf("total elapsed time … %s\n", infra.ElapsedStr(now))
+		return err
+	},
+}
+
+// FUNCTION:
+func init() {
+	// PROCESS:フラグ値を変数にBind
+	regressCmd.Flags().StringVar(&goldenDir, "golden-dir", "testdata/golden", "directory of golden files. (results are compared under <golden-dir>/db)")
+	regressCmd.Flags().BoolVar(&updateGolden, "update", false, "rewrite golden files with current results. (review with `git diff`)")
+	regressCmd.Flags().BoolVar(&replaceLegacy, "replace", false, "truncate and replace data of legacy database.")
+}
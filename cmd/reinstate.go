@@ -0,0 +1,45 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service"
+)
+
+// reinstateCmd represents the reinstate command
+var reinstateCmd = &cobra.Command{
+	Use:   "reinstate",
+	Short: "reinstate approved quarantine records on next cleansing.",
+	Long:  "move quarantine records approved (reinstate=true) in work database back into the next cleansing run.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		// PROCESS: 現在時刻(Elapse計測用)
+		now := time.Now()
+
+		// PROCESS: config, データベース(Sqlboiler)コネクションの取得
+		config, conns, cleanUp := infra.LeadConfig(version, applyRunFlags)
+		defer cleanUp()
+
+		// PROCESS: 再登録データの作成
+		msg, err := service.Reinstate(config, conns)
+		if err != nil {
+			return err
+		}
+		fmt.Println(msg)
+
+		// PROCESS: 処理時間計測
+		log.Printf("total elapsed time … %s\n", infra.ElapsedStr(now))
+		return nil
+	},
+}
+
+// FUNCTION:
+func init() {
+}
@@ -4,38 +4,77 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"runtime/debug"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service"
 )
 
 // STRUCT: リリース情報
 var (
 	version     string
+	gitCommit   string
 	releaseDate string
 )
 
 // STRUCT: ワークDBのダンプファイル名
 const WORK_DML = "dml-work.sql.gz"
+const WORK_COUNTS = "dml-work.counts.json"
 
-const LOCAL_DML = "dml-local.sql.gz"
-const AWS_DDL = "ddl.sql.gz"
-const AWS_DML = "dml.sql.gz"
+// STRUCT: ダンプ定義名(apply対象)
+const LOCAL_DML = "dml-local"
+const AWS_DDL = "ddl"
+const AWS_DML = "dml"
 
+// STRUCT: 移行成果物のDROP用SQL
+const CLEAN_SQL = "clean.sql"
+
+// STRUCT: ログファイル名(拡張子はレンダラー毎)
+const CLEANSING_LOG = ".cleansing-log"
+const TRANSFER_LOG = ".transfer-log"
+
+// STRUCT: 受注分割の内訳(JSON)
+const SPLITS_FILE = ".transfer-splits.json"
+
+// STRUCT: 設定ファイル/プロファイル
 var cfgFile string
+var profile string
+
+// STRUCT: スキーマ差分を許容して実行する(cleansing/transfer)
+var allowDrift bool
+
+// STRUCT: 実行設定(コマンドライン指定値)
+var runFlags struct {
+	fetchLimit       int
+	maxOpenConns     int
+	maxIdleConns     int
+	connMaxLifetime  time.Duration
+	statementTimeout time.Duration
+	deadline         time.Duration
+}
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "data-transfer-sandbox",
 	Short: "data transfer service from present system database to new system database.",
 	Long:  "data transfer service from present system database to new system database.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// PROCESS: 設定ファイルの適用(環境変数に未設定の項目のみ)
+		return infra.ApplyConfigFile(cfgFile, profile)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 // FUNCTION:
-func Execute(ver string, date string) {
+func Execute(ver string, commit string, date string) {
 	version = ver
+	gitCommit = commit
 	releaseDate = date
 
 	err := rootCmd.Execute()
@@ -51,4 +90,106 @@ func init() {
 	rootCmd.AddCommand(cleansingCmd)
 	rootCmd.AddCommand(loadCmd)
 	rootCmd.AddCommand(transferCmd)
+	rootCmd.AddCommand(reinstateCmd)
+	rootCmd.AddCommand(approveCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(checkSchemaCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(subsetCmd)
+	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(regressCmd)
+
+	// PROCESS:フラグ値を変数にBind(指定した場合は環境変数の設定値を上書き)
+	flags := rootCmd.PersistentFlags()
+	flags.StringVarP(&cfgFile, "config", "c", "", "config file(.yaml/.yml/.toml) with default settings and profiles.")
+	flags.StringVarP(&profile, "profile", "p", "", "profile name in config file. (e.g. local, staging-rehearsal, production-rehearsal)")
+	flags.IntVar(&runFlags.fetchLimit, "fetch-limit", 10000, "fetch batch size. (FETCH_LIMIT)")
+	flags.IntVar(&runFlags.maxOpenConns, "max-open-conns", 10, "max open connections per database. (DB_MAX_OPEN_CONNS)")
+	flags.IntVar(&runFlags.maxIdleConns, "max-idle-conns", 10, "max idle connections per database. (DB_MAX_IDLE_CONNS)")
+	flags.DurationVar(&runFlags.connMaxLifetime, "conn-max-lifetime", 300*time.Second, "connection max lifetime. (DB_CONN_MAX_LIFETIME)")
+	flags.DurationVar(&runFlags.statementTimeout, "statement-timeout", 0, "statement timeout for cleansing/transfer, 0 is unlimited. (CLEANSING_STATEMENT_TIMEOUT/TRANSFER_STATEMENT_TIMEOUT)")
+	flags.DurationVar(&runFlags.deadline, "deadline", 0, "deadline of whole run, 0 is unlimited. (RUN_DEADLINE)")
+}
+
+// FUNCTION: コマンドライン指定値による実行設定の上書き
+func applyRunFlags(config *infra.Config) {
+	flags := rootCmd.PersistentFlags()
+	if flags.Changed("fetch-limit") {
+		config.Run.FetchLimit = runFlags.fetchLimit
+	}
+	if flags.Changed("max-open-conns") {
+		config.Run.MaxOpenConns = runFlags.maxOpenConns
+	}
+	if flags.Changed("max-idle-conns") {
+		config.Run.MaxIdleConns = runFlags.maxIdleConns
+	}
+	if flags.Changed("conn-max-lifetime") {
+		config.Run.ConnMaxLifetime = runFlags.connMaxLifetime
+	}
+	if flags.Changed("statement-timeout") {
+		config.Run.CleansingStatementTimeout = runFlags.statementTimeout
+		config.Run.TransferStatementTimeout = runFlags.statementTimeout
+	}
+	if flags.Changed("deadline") {
+		config.Run.RunDeadline = runFlags.deadline
+	}
+}
+
+// FUNCTION: 実行設定のログメッセージ
+func configMsg(config infra.Config) string {
+	return service.ItemsMsg(configItems(config))
+}
+
+// FUNCTION: 実行設定のレポート項目
+func configItems(config infra.Config) []service.ReportItem {
+	items := []service.ReportItem{{Key: "profile", Value: profileStr(config)}}
+	if len(config.Run.DisabledRules) > 0 {
+		items = append(items, service.ReportItem{Key: "disabled rules", Value: strings.Join(config.Run.DisabledRules, ", ")})
+	}
+	if config.Run.TransferViewCheck {
+		items = append(items, service.ReportItem{Key: "view check", Value: "w_orders/w_order_details"})
+	}
+	return items
+}
+
+// FUNCTION: 中断時のログメッセージ
+func interruptedMsg(interrupted error) string {
+	return service.ItemsMsg(interruptedItems(interrupted))
+}
+
+// FUNCTION: 中断時のレポート項目
+func interruptedItems(interrupted error) []service.ReportItem {
+	if interrupted == nil {
+		return nil
+	}
+	return []service.ReportItem{{Key: "status", Value: fmt.Sprintf("INTERRUPTED (%v) … partial result", interrupted), Alert: true}}
+}
+
+// FUNCTION: 中止/検証エラー時のログメッセージ
+func failedMsg(failed error) string {
+	return service.ItemsMsg(failedItems(failed))
+}
+
+// FUNCTION: 中止/検証エラー時のレポート項目
+func failedItems(failed error) []service.ReportItem {
+	if failed == nil {
+		return nil
+	}
+	return []service.ReportItem{{Key: "status", Value: fmt.Sprintf("FAILED (%v)", failed), Alert: true}}
+}
+
+// FUNCTION: gitコミット(リリースビルド以外はビルド情報から取得、取得できない場合は`unknown`)
+func commitStr() string {
+	if gitCommit != "" && gitCommit != "unknown" {
+		return gitCommit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "unknown"
 }
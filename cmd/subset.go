@@ -0,0 +1,54 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service/cleansing"
+)
+
+// STRUCT: サブセットの出力先(LEGACY_DATA_KEYの接尾辞、全件の実行結果を上書きしない)
+const SUBSET_SUFFIX = "-subset"
+
+// STRUCT: サブセットの抽出条件(コマンドライン指定値)
+var subsetSpec cleansing.SubsetSpec
+
+// subsetCmd represents the subset command
+var subsetCmd = &cobra.Command{
+	Use:   "subset",
+	Short: "cleansing and transfer with referentially consistent subset of legacy database.",
+	Long:  "pick seed orders by order date range/order numbers, pull referenced order_details, products and operators, and run cleansing and transfer over them. (output key: LEGACY_DATA_KEY-subset)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		// PROCESS: 抽出条件の指定なしはエラー(全件の場合はcleansing/transferを実行する)
+		if !subsetSpec.Enabled() {
+			return fmt.Errorf("subset requires at least one of --from/--to/--order-no/--limit")
+		}
+
+		// PROCESS: config, データベース(Sqlboiler)コネクションの取得
+		config, conns, cleanUp := infra.LeadConfig(version, applyRunFlags, func(config *infra.Config) {
+			config.Base.LegacyDataKey += SUBSET_SUFFIX
+		})
+		defer cleanUp()
+
+		// PROCESS: クレンジング(サブセット) → 移行
+		if err := runCleansing(config, conns, subsetSpec); err != nil {
+			return err
+		}
+		return runTransfer(config, conns)
+	},
+}
+
+// FUNCTION:
+func init() {
+	// PROCESS:フラグ値を変数にBind
+	subsetCmd.Flags().StringVar(&subsetSpec.From, "from", "", "order date from. (DATE_LAYOUT format, e.g. 20250101)")
+	subsetCmd.Flags().StringVar(&subsetSpec.To, "to", "", "order date to. (DATE_LAYOUT format, e.g. 20250131)")
+	subsetCmd.Flags().IntSliceVar(&subsetSpec.OrderNos, "order-no", nil, "order numbers. (e.g. --order-no 1001,1002)")
+	subsetCmd.Flags().IntVar(&subsetSpec.Limit, "limit", 0, "max number of seed orders in order_no order, 0 is unlimited.")
+	subsetCmd.Flags().BoolVar(&allowDrift, "allow-drift", false, "continue with warnings even if schema drift is detected.")
+}
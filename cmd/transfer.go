@@ -6,7 +6,9 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"os"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -21,113 +23,126 @@ var transferCmd = &cobra.Command{
 	Long:  "data transfer service to product database.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		// PROCESS: 現在時刻(Elapse計測用)
-		now := time.Now()
-
 		// PROCESS: config, データベース(Sqlboiler)コネクションの取得
-		config, conns, cleanUp := infra.LeadConfig(version)
+		config, conns, cleanUp := infra.LeadConfig(version, applyRunFlags)
 		defer cleanUp()
-		distDir := config.TransferDir()
 
-		// PROCESS: データ移行実行
-		transferMsg := service.Transfer(conns)
+		return runTransfer(config, conns)
+	},
+}
 
-		// PROCESS: データダンプ(ローカル用DML)
-		filePathLocal := path.Join(distDir, LOCAL_DML)
-		if err := config.ProductDB.Dump(filePathLocal, dmlLocalArgs()); err != nil {
-			return err
-		}
+// FUNCTION:
+func init() {
+	// PROCESS:フラグ値を変数にBind
+	transferCmd.Flags().BoolVar(&allowDrift, "allow-drift", false, "continue transfer with warnings even if product schema drift is detected.")
+}
 
-		// PROCESS: データダンプ(AWS用DDL)
-		filePathDdl := path.Join(distDir, AWS_DDL)
-		if err := config.ProductDB.Dump(filePathDdl, ddlArgs()); err != nil {
-			return err
-		}
+// FUNCTION: 移行の実行(ダンプ、マニフェスト作成、ログ出力を含む)
+func runTransfer(config infra.Config, conns infra.DbConnection) error {
 
-		// PROCESS: データダンプ(AWS用DML)
-		filePathDml := path.Join(distDir, AWS_DML)
-		if err := config.ProductDB.Dump(filePathDml, dmlArgs()); err != nil {
-			return err
-		}
+	// PROCESS: 現在時刻(Elapse計測用)
+	now := time.Now()
+	distDir := config.TransferDir()
 
-		// PROCESS: 処理時間計測
-		elapse := infra.ElapsedStr(now)
+	// PROCESS: ダンプ定義の読込み(定義誤りの場合はTRUNCATE前に中止する)
+	dumps, err := infra.LoadDumpProfiles(config.Base.DumpFile)
+	if err != nil {
+		return err
+	}
+	if err := infra.ValidateMaskingSalt(dumps, config.Base.MaskingSalt); err != nil {
+		return err
+	}
 
-		// PROCESS: Log File出力
-		msg := "# Data Transfer Result\n\n"
-		msg += fmt.Sprintf("- **operation datetime**: %s\n", now.Format("2006/01/02 15:04:05"))
-		msg += fmt.Sprintf("- **transfer tool version**: %s\n", config.Base.ToolVersion)
-		msg += fmt.Sprintf("- **production schema version**: %s\n", config.Base.AppVersion)
-		msg += fmt.Sprintf("- **load legacy DB key**: %s\n", config.Base.LegacyDataKey)
-		msg += fmt.Sprintf("- **total elapsed time**: %s\n", elapse)
-		msg += transferMsg
+	// PROCESS: データ移行実行
+	run, cancel := infra.NewRunContext(config.Run)
+	defer cancel()
+	report, refused := service.Transfer(run, config, conns, allowDrift)
+	interrupted := infra.Interruption(run)
 
-		logPath := path.Join(distDir, ".transfer-log.md")
-		if err := infra.WriteLog(logPath, msg, &now); err != nil {
+	// PROCESS: 受注分割の内訳(JSON)出力
+	if report.Transfer != nil {
+		if err := report.Transfer.Splits.Save(path.Join(distDir, SPLITS_FILE)); err != nil {
 			return err
 		}
+	}
 
-		// PROCESS: cleansingLogのコピー
-		infra.FileCopy(config.CleansingDir(), distDir, ".cleansing-log.md")
+	// PROCESS: 中断時/中止時はダンプしない
+	masked := map[string]map[string]int{}
+	if interrupted == nil && refused == nil {
+		// PROCESS: データダンプ(ダンプ定義毎)
+		// INFO: マスキングルールがあるダンプは、出力時に仮名に置換する
+		for _, dump := range dumps {
+			counts, err := config.ProductDB.DumpWith(path.Join(distDir, dump.File), dump, config.Base.MaskingSalt)
+			if err != nil {
+				return err
+			}
+			masked[dump.Name] = counts
+		}
+	}
 
-		// PROCESS: clean.sql(テーブル/シーケンス/ファンクション/EnumのDROP)のコピー
-		infra.FileCopy("materials", distDir, "clean.sql")
+	// PROCESS: 処理時間計測
+	elapse := infra.ElapsedStr(now)
+
+	// PROCESS: Log File出力(markdown/HTML/JSON)
+	report.Title = "Data Transfer Result"
+	report.AddItem("operation datetime", now.Format("2006/01/02 15:04:05"))
+	report.AddItem("transfer tool version", config.Base.ToolVersion)
+	report.AddItem("production schema version", config.Base.AppVersion)
+	report.AddItem("load legacy DB key", config.Base.LegacyDataKey)
+	report.AddItems(configItems(config)...)
+	report.AddItems(dumpsItems(dumps, masked)...)
+	report.AddItem("total elapsed time", elapse)
+	report.AddItems(interruptedItems(interrupted)...)
+	report.AddItems(failedItems(refused)...)
+
+	if err := report.Write(distDir, TRANSFER_LOG, &now); err != nil {
+		return err
+	}
 
-		log.Printf("total elapsed time … %s\n", elapse)
-		return nil
-	},
-}
+	// PROCESS: cleansingLogのコピー
+	for _, file := range service.ReportFiles(CLEANSING_LOG) {
+		infra.FileCopy(config.CleansingDir(), distDir, file)
+	}
 
-// FUNCTION:
-func init() {
-}
+	// PROCESS: clean.sql(テーブル/シーケンス/ファンクション/EnumのDROP)のコピー
+	infra.FileCopy("materials", distDir, CLEAN_SQL)
 
-// FUNCTION:
-func dmlLocalArgs() []string {
-	return []string{
-		"--no-owner",
-		"--no-privileges",
-		"--no-security-labels",
-		"--encoding=UTF-8",
-		"--format=P",
-		// INFO: DML情報のみ
-		"--data-only",
-		// INFO: 作成対象のテーブルを記載
-		"--table=orders.operators",
-		"--table=orders.products",
-		// INFO: Load中のトリガー無効化
-		"--disable-triggers",
+	// PROCESS: マニフェスト(ファイルのハッシュ値/件数)の作成
+	// INFO: 中断時/中止時は成果物が不完全なため作成しない(既存のマニフェストは削除する)
+	os.Remove(path.Join(distDir, infra.MANIFEST_FILE))
+	if interrupted == nil && refused == nil {
+		if _, err := service.CreateManifest(run, config, conns.ProductDB, commitStr(), now, distDir); err != nil {
+			return err
+		}
 	}
-}
 
-// FUNCTION:
-func ddlArgs() []string {
-	return []string{
-		"--no-owner",
-		"--no-privileges",
-		"--no-security-labels",
-		"--encoding=UTF-8",
-		"--format=P",
-		// INFO: DDL情報のみ
-		"--schema-only",
-		// INFO: 作成対象外のスキーマを記載
-		"--exclude-schema=public",
+	log.Printf("total elapsed time … %s\n", elapse)
+	if refused != nil {
+		return refused
+	}
+	if interrupted != nil {
+		return fmt.Errorf("transfer interrupted: %v", interrupted)
 	}
+	return nil
 }
 
-// FUNCTION:
-func dmlArgs() []string {
-	return []string{
-		"--no-owner",
-		"--no-privileges",
-		"--no-security-labels",
-		"--encoding=UTF-8",
-		"--format=P",
-		// INFO: DML情報のみ
-		"--data-only",
-		// INFO: 作成対象外のテーブルを記載
-		"--exclude-table=public.*",
-		// INFO: Load中のトリガー無効化
-		"--disable-triggers",
+// FUNCTION: ダンプ定義のレポート項目(マスキングしたダンプはカラム毎の置換件数を併記)
+func dumpsItems(dumps []infra.DumpProfile, masked map[string]map[string]int) []service.ReportItem {
+	names := make([]string, len(dumps))
+	for i, dump := range dumps {
+		names[i] = fmt.Sprintf("%s(%s)", dump.Name, dump.File)
+	}
+	items := []service.ReportItem{{Key: "dumps", Value: strings.Join(names, ", ")}}
+	for _, dump := range dumps {
+		if len(dump.Masks) == 0 {
+			continue
+		}
+		columns := make([]string, len(dump.Masks))
+		for i, rule := range dump.Masks {
+			column := fmt.Sprintf("%s.%s", rule.Table, rule.Column)
+			columns[i] = fmt.Sprintf("%s(%d)", column, masked[dump.Name][column])
+		}
+		items = append(items, service.ReportItem{Key: fmt.Sprintf("masked(%s)", dump.Name), Value: strings.Join(columns, ", ")})
 	}
+	return items
 }
@@ -0,0 +1,66 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service"
+)
+
+// STRUCT: 検証後にアーカイブ(tar.gz)を作成する
+var archive bool
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify [bundle-dir]",
+	Short: "verify transfer bundle with manifest.",
+	Long:  "verify sha256 and size of files in transfer bundle with manifest.json, and create tar.gz archive by option flag. (bundle-dir default: dist/<tool>/<app>(<key>))",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		// PROCESS: 検証対象のディレクトリ(未指定の場合はconfigの移行出力先)
+		var bundleDir string
+		if len(args) > 0 {
+			bundleDir = args[0]
+		} else {
+			config, err := infra.ReadConfig(version)
+			if err != nil {
+				return err
+			}
+			bundleDir = config.TransferDir()
+		}
+		bundleDir = filepath.Clean(bundleDir)
+
+		// PROCESS: 成果物の検証
+		manifest, report, verified := service.VerifyBundle(bundleDir)
+		if report == "" {
+			return verified
+		}
+		fmt.Println(report)
+		if verified != nil {
+			return verified
+		}
+
+		// PROCESS: アーカイブの作成(検証OKの場合のみ)
+		if archive {
+			archivePath := fmt.Sprintf("%s.tar.gz", bundleDir)
+			if err := manifest.Archive(bundleDir, archivePath); err != nil {
+				return err
+			}
+			log.Printf("archive created [%s]\n", archivePath)
+		}
+		return nil
+	},
+}
+
+// FUNCTION:
+func init() {
+	// PROCESS:フラグ値を変数にBind
+	verifyCmd.Flags().BoolVar(&archive, "archive", false, "create tar.gz archive of verified bundle.")
+}
@@ -18,7 +18,7 @@ var versionCmd = &cobra.Command{
 	Long:  "Show semantic version.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if full {
-			fmt.Printf("version: %s (releasedAt: %s)", version, releaseDate)
+			fmt.Printf("version: %s (commit: %s, releasedAt: %s)", version, commitStr(), releaseDate)
 		} else {
 			fmt.Print(version)
 		}
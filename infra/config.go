@@ -6,6 +6,7 @@ package infra
 // TITLE:環境変数の読込み
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -24,9 +25,13 @@ const TEMP_PATH string = "/tmp/dump.sql"
 
 var TEMP_GZ_PATH string = fmt.Sprintf("%s.gz", TEMP_PATH)
 
+// STRUCT: 移行元DB(MariaDB)のタイムゾーンの既定値(`LEGACY_MARIADB_TIME_ZONE`が未設定の場合)
+const LEGACY_TIME_ZONE = "Asia/Tokyo"
+
 // STRUCT:
 type Config struct {
 	Base      BaseConfig `envconfig:""`
+	Run       RunConfig  `envconfig:""`
 	LegacyDB  DbConfig   `envconfig:"LEGACY_MARIADB"`
 	WorkDB    DbConfig   `envconfig:"WORK_POSTGRES"`
 	ProductDB DbConfig   `envconfig:"PRODUCT_POSTGRES"`
@@ -34,23 +39,74 @@ type Config struct {
 
 // 基本設定
 type BaseConfig struct {
-	LegacyDataKey string `envconfig:"LEGACY_DATA_KEY" required:"true"`
-	AppVersion    string `envconfig:"APP_VERSION" default:"v0.0.1"`
-	ToolVersion   string
+	LegacyDataKey    string `envconfig:"LEGACY_DATA_KEY" required:"true"`
+	AppVersion       string `envconfig:"APP_VERSION" default:"v0.0.1"`
+	MappingFile      string `envconfig:"MAPPING_FILE" default:"materials/name-mapping.yaml"`
+	OverrideDir      string `envconfig:"OVERRIDE_DIR" default:"materials/overrides"`
+	DumpFile         string `envconfig:"DUMP_FILE" default:"materials/dump-profiles.yaml"`
+	MaskingSalt      string `envconfig:"MASKING_SALT"`
+	WorkDir          string `envconfig:"WORK_DIR" default:"work"`
+	DistDir          string `envconfig:"DIST_DIR" default:"dist"`
+	Profile          string `envconfig:"CONFIG_PROFILE"`
+	PassFile         string `envconfig:"PGPASSFILE"`
+	MigrationTable   string `envconfig:"MIGRATION_TABLE" default:"public.schema_migrations"`
+	MigrationVersion int64  `envconfig:"MIGRATION_VERSION"`
+	ToolVersion      string
+}
+
+// 実行設定
+type RunConfig struct {
+	FetchLimit                int           `envconfig:"FETCH_LIMIT" default:"10000"`
+	DateLayout                string        `envconfig:"DATE_LAYOUT" default:"20060102"`
+	MaxOpenConns              int           `envconfig:"DB_MAX_OPEN_CONNS" default:"10"`
+	MaxIdleConns              int           `envconfig:"DB_MAX_IDLE_CONNS" default:"10"`
+	ConnMaxLifetime           time.Duration `envconfig:"DB_CONN_MAX_LIFETIME" default:"300s"`
+	CleansingStatementTimeout time.Duration `envconfig:"CLEANSING_STATEMENT_TIMEOUT" default:"0s"`
+	TransferStatementTimeout  time.Duration `envconfig:"TRANSFER_STATEMENT_TIMEOUT" default:"0s"`
+	RunDeadline               time.Duration `envconfig:"RUN_DEADLINE" default:"0s"`
+	RetryMax                  int           `envconfig:"RETRY_MAX" default:"5"`
+	RetryInterval             time.Duration `envconfig:"RETRY_INTERVAL" default:"1s"`
+	RetryMaxInterval          time.Duration `envconfig:"RETRY_MAX_INTERVAL" default:"30s"`
+	DisabledRules             []string      `envconfig:"DISABLED_RULES" default:"#4-03"`
+	TransferViewCheck         bool          `envconfig:"TRANSFER_VIEW_CHECK" default:"false"`
 }
 
 // データベース接続設定
 type DbConfig struct {
 	User          string `envconfig:"USER" required:"true"`
-	Password      string `envconfig:"PASSWORD" required:"true"`
+	Password      string `envconfig:"PASSWORD"`
+	PasswordFile  string `envconfig:"PASSWORD_FILE"`
 	Host          string `envconfig:"HOST" default:"localhost"`
 	Port          int    `envconfig:"PORT" required:"true"`
 	Database      string `envconfig:"DB" required:"true"`
 	ContainerName string `envconfig:"CONTAINER" required:"true"`
+	SslMode       string `envconfig:"SSLMODE" default:"disable"` //disable/preferred(MariaDBのみ)/require/verify-ca/verify-full
+	SslRootCert   string `envconfig:"SSLROOTCERT"`               //CA証明書
+	SslCert       string `envconfig:"SSLCERT"`                   //クライアント証明書
+	SslKey        string `envconfig:"SSLKEY"`                    //クライアント秘密鍵
+	Charset       string `envconfig:"CHARSET" default:"utf8mb4"` //文字コード(MariaDBのみ)
+	TimeZone      string `envconfig:"TIME_ZONE"`                 //タイムゾーン(MariaDB:loc、PostgreSQL:timezone、未指定の場合はDBの既定、移行元DBはLEGACY_TIME_ZONE)
+	Params        string `envconfig:"PARAMS"`                    //追加のDSNパラメータ(`key=value&key=value`形式、既定のパラメータを上書き)
+}
+
+// FUNCTION: config(flagsでコマンドラインの指定値を上書きする)
+func LeadConfig(version string, flags ...func(*Config)) (Config, DbConnection, func()) {
+	config, err := ReadConfig(version, flags...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if errs := config.Validate(); len(errs) > 0 {
+		log.Fatal(errors.Join(errs...))
+	}
+
+	// PROCESS: データベース(Sqlboiler)コネクションの取得
+	conns, cleanUp := initDB(&config)
+
+	return config, conns, cleanUp
 }
 
-// FUNCTION:
-func LeadConfig(version string) (Config, DbConnection, func()) {
+// FUNCTION: configの読込み(DB接続なし、優先順位:フラグ > 環境変数 > 設定ファイル > .envファイル > 既定値)
+func ReadConfig(version string, flags ...func(*Config)) (Config, error) {
 	// PROCESS: envファイルのロード
 	_, err := os.Stat(".env")
 	if !os.IsNotExist(err) {
@@ -61,42 +117,80 @@ func LeadConfig(version string) (Config, DbConnection, func()) {
 	// PROCESS: オブジェクトに変換
 	var config Config
 	if err = envconfig.Process("", &config); err != nil {
-		log.Fatal(err)
+		return config, err
 	}
 	config.Base.ToolVersion = version
 
-	// PROCESS: データベース(Sqlboiler)コネクションの取得
-	conns, cleanUp := initDB(&config)
+	// PROCESS: 移行元DBのタイムゾーン(未設定の場合は既定値、空を指定した場合はDSNに付与しない)
+	if _, exist := os.LookupEnv("LEGACY_MARIADB_TIME_ZONE"); !exist {
+		config.LegacyDB.TimeZone = LEGACY_TIME_ZONE
+	}
+	for _, flag := range flags {
+		flag(&config)
+	}
 
-	return config, conns, cleanUp
+	// PROCESS: パスワードの解決(ファイルからの読込み)
+	for _, db := range []*DbConfig{&config.LegacyDB, &config.WorkDB, &config.ProductDB} {
+		if err := db.resolvePassword(config.Base.PassFile); err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+// FUNCTION: ファイルをコンテナ上のDBにLoadする(`.gz`以外は非圧縮のSQLファイル)
+// INFO: 複数のファイルは指定順に1つのpsqlセッション/トランザクションで適用する(いずれかでエラーの場合は全体をロールバック)
+func (config DbConfig) Load(loadfilePaths ...string) error {
+	return config.LoadWithPreamble(nil, loadfilePaths...)
 }
 
-// FUNCTION: ファイルをコンテナ上のDBにLoadする
-func (config DbConfig) Load(loadfilePath string) error {
+// FUNCTION: ファイルをコンテナ上のDBにLoadする(preambleはファイルの前に同じトランザクションで実行するSQL)
+func (config DbConfig) LoadWithPreamble(preamble []string, loadfilePaths ...string) error {
 	s := time.Now()
 
 	// PROCESS: コンテナ内にコピー
-	// docker cp {dumpfile.sql.gz} {work-db}:{/tmp/dumpfile.sql.gz}
-	copyArgs := []string{"cp", loadfilePath, fmt.Sprintf("%s:%s", config.ContainerName, TEMP_GZ_PATH)}
-	if err := dockerExec(copyArgs); err != nil {
-		return fmt.Errorf("failed to copy load file: %v", err)
+	// docker cp {dumpfile.sql.gz} {work-db}:{/tmp/dump-1.sql.gz}
+	readers := make([]string, len(loadfilePaths))
+	for i, loadfilePath := range loadfilePaths {
+		tempPath, reader := fmt.Sprintf("%s-%d.gz", TEMP_PATH, i+1), "gzip -d -c"
+		if filepath.Ext(loadfilePath) != ".gz" {
+			tempPath, reader = fmt.Sprintf("%s-%d", TEMP_PATH, i+1), "cat"
+		}
+		copyArgs := []string{"cp", loadfilePath, fmt.Sprintf("%s:%s", config.ContainerName, tempPath)}
+		if err := dockerExec(copyArgs); err != nil {
+			return fmt.Errorf("failed to copy load file[%s]: %v", filepath.Base(loadfilePath), err)
+		}
+		readers[i] = fmt.Sprintf("%s %s", reader, tempPath)
 	}
 
 	// PROCESS: cleanDBにデータロード
-	// docker exec -e PGPASSWORD={password} -i {work-db} bash -c gzip -d -c {/tmp/dumpfile.sql.gz} | psql -U {postgres} -d {workDB}
-	command := fmt.Sprintf("gzip -d -c %s | psql -U %s -d %s", TEMP_GZ_PATH, config.User, config.Database)
+	// docker exec -e PGPASSWORD -i {work-db} bash -c set -o pipefail; { gzip -d -c {/tmp/dump-1.sql.gz}; ... } | psql -v ON_ERROR_STOP=1 --single-transaction -U {postgres} -d {workDB} [-c {preamble} ... -f -]
+	// INFO: パスワードは子プロセスの環境変数で受け渡す(コマンドラインに含めない)
+	// INFO: SQLエラー発生時は処理を中断し、ロード全体をロールバックする(preambleを含む)
+	psqlArgs := []string{"-v ON_ERROR_STOP=1 --single-transaction", "-U", config.User, "-d", config.Database}
+	if len(preamble) > 0 {
+		for _, sql := range preamble {
+			psqlArgs = append(psqlArgs, "-c", shellQuote(sql))
+		}
+		psqlArgs = append(psqlArgs, "-f", "-")
+	}
+	command := fmt.Sprintf("set -o pipefail; { %s; } | psql %s", strings.Join(readers, "; "), strings.Join(psqlArgs, " "))
 	loadArgs := []string{
 		"exec",
-		"-e", fmt.Sprintf("PGPASSWORD=%s", config.Password),
+		"-e", "PGPASSWORD",
 		"-i", config.ContainerName,
 		"bash", "-c", command,
 	}
-	if err := dockerExec(loadArgs); err != nil {
+	if err := dockerExec(loadArgs, config.passwordEnv()...); err != nil {
 		return fmt.Errorf("failed to db load: %v", err)
 	}
 
 	duration := time.Since(s).Seconds()
-	log.Printf("load completed [%s] … %3.2fs\n", filepath.Base(loadfilePath), duration)
+	names := make([]string, len(loadfilePaths))
+	for i, loadfilePath := range loadfilePaths {
+		names[i] = filepath.Base(loadfilePath)
+	}
+	log.Printf("load completed [%s] … %3.2fs\n", strings.Join(names, ", "), duration)
 	return nil
 }
 
@@ -113,15 +207,15 @@ func (config DbConfig) Dump(dumpfilePath string, extArgs []string) error {
 	}
 
 	// PROCESS: cleanDBをダンプ
-	// docker exec -e PGPASSWORD={password} -i {work-db} bash -c pg_dump -U {postgres} -d {workDB} {--data-only --schema=clean} > {/tmp/dump.sql} && gzip {/tmp/dump.sql}
+	// docker exec -e PGPASSWORD -i {work-db} bash -c pg_dump -U {postgres} -d {workDB} {--data-only --schema=clean} > {/tmp/dump.sql} && gzip {/tmp/dump.sql}
 	command := fmt.Sprintf("pg_dump -U %s -d %s %s > %s && gzip -f %s", config.User, config.Database, strings.Join(extArgs, " "), TEMP_PATH, TEMP_PATH)
 	dumpArgs := []string{
 		"exec",
-		"-e", fmt.Sprintf("PGPASSWORD=%s", config.Password),
+		"-e", "PGPASSWORD",
 		"-i", config.ContainerName,
 		"bash", "-c", command,
 	}
-	if err := dockerExec(dumpArgs); err != nil {
+	if err := dockerExec(dumpArgs, config.passwordEnv()...); err != nil {
 		return fmt.Errorf("failed to db dump: %v", err)
 	}
 
@@ -137,11 +231,14 @@ func (config DbConfig) Dump(dumpfilePath string, extArgs []string) error {
 	return nil
 }
 
-// FUNCTION: Dockerコマンド実行
-func dockerExec(args []string) error {
+// FUNCTION: Dockerコマンド実行(envを指定した場合は子プロセスの環境変数とする)
+func dockerExec(args []string, env ...string) error {
 	cmd := exec.Command("docker", args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if len(env) > 0 {
+		cmd.Env = env
+	}
 
 	// PROCESS: コマンドを実行
 
@@ -151,15 +248,30 @@ func dockerExec(args []string) error {
 	return nil
 }
 
-// FUNCTION: データ変換出力先のディレクトリ:`dist/toolversion/appVersion(legacyDataKey)`
+// FUNCTION: データ変換出力先のディレクトリ:`distDir/toolversion/appVersion(legacyDataKey)`
 func (config Config) TransferDir() string {
 	dirName := fmt.Sprintf("%s(%s)", config.Base.AppVersion, config.Base.LegacyDataKey)
-	return path.Join("dist", path.Join(config.Base.ToolVersion, dirName))
+	return path.Join(config.Base.DistDir, path.Join(config.Base.ToolVersion, dirName))
 }
 
-// FUNCTION: クレンジング出力先のディレクトリ:`work/toolversion/legacyDataKey`
+// FUNCTION: クレンジング出力先のディレクトリ:`workDir/toolversion/legacyDataKey`
 func (config Config) CleansingDir() string {
-	return path.Join("work", path.Join(config.Base.ToolVersion, config.Base.LegacyDataKey))
+	return path.Join(config.Base.WorkDir, path.Join(config.Base.ToolVersion, config.Base.LegacyDataKey))
+}
+
+// FUNCTION: 再登録データのファイル:`work/toolversion/legacyDataKey/reinstate.yaml`
+func (config Config) ReinstateFile() string {
+	return path.Join(config.CleansingDir(), "reinstate.yaml")
+}
+
+// FUNCTION: 承認データのファイル:`work/toolversion/legacyDataKey/approvals.yaml`
+func (config Config) ApprovalFile() string {
+	return path.Join(config.CleansingDir(), "approvals.yaml")
+}
+
+// FUNCTION: レビューブックのファイル:`work/toolversion/legacyDataKey/cleansing-review.xlsx`
+func (config Config) ReviewBookFile() string {
+	return path.Join(config.CleansingDir(), "cleansing-review.xlsx")
 }
 
 // FUNCTION: ユニックスタイムからの秒数に変換し、フォーマット
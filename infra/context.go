@@ -6,6 +6,11 @@ package infra
 import (
 	"context"
 	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/volatiletech/null/v8"
 	"golang.org/x/text/language"
@@ -16,24 +21,132 @@ import (
 
 // STRUCT: アプリコンテキスト
 type AppCtx struct {
-	Ctx           context.Context
-	DateLayout    string           //日付文字列フォーマット
-	Limit         int              //検索時最大件数
-	OperationUser null.String      //更新ユーザー名
-	Printer       *message.Printer //printer(数値をカンマ区切りで出力するために利用)
+	Ctx              context.Context     //SQL実行用(実行全体から派生、中断時は実行中のSQLもキャンセル)
+	Run              context.Context     //実行全体(中断/実行期限)
+	StatementTimeout time.Duration       //SQL実行タイムアウト(0の場合は無制限)
+	DateLayout       string              //日付文字列フォーマット
+	Limit            int                 //検索時最大件数
+	OperationUser    null.String         //更新ユーザー名
+	Printer          *message.Printer    //printer(数値をカンマ区切りで出力するために利用)
+	Overrides        Overrides           //個別指定値
+	Retry            RetryPolicy         //リトライ設定
+	Retries          *RetryCounter       //リトライ回数(テーブル単位)
+	DisabledRules    map[string]struct{} //無効化したクレンジングルール
 }
 
 // FUNCTION: context setting
-func NewCtx() AppCtx {
+func NewCtx(run context.Context, config RunConfig, statementTimeout time.Duration, overrides Overrides) AppCtx {
+	run = withInterrupt(run)
 	return AppCtx{
-		Ctx:           context.Background(),
-		DateLayout:    "20060102",
-		Limit:         10000,
-		OperationUser: null.StringFrom("DATA_TRANSFER"),
-		Printer:       message.NewPrinter(language.Japanese),
+		Ctx:              run,
+		Run:              run,
+		StatementTimeout: statementTimeout,
+		DateLayout:       config.DateLayout,
+		Limit:            config.FetchLimit,
+		OperationUser:    null.StringFrom("DATA_TRANSFER"),
+		Printer:          message.NewPrinter(language.Japanese),
+		Overrides:        overrides,
+		Retry: RetryPolicy{
+			Max:         config.RetryMax,
+			Interval:    config.RetryInterval,
+			MaxInterval: config.RetryMaxInterval,
+		},
+		DisabledRules: disabledRules(config.DisabledRules),
 	}
 }
 
+// FUNCTION: 無効化したクレンジングルール
+func disabledRules(ids []string) map[string]struct{} {
+	rules := map[string]struct{}{}
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			rules[id] = struct{}{}
+		}
+	}
+	return rules
+}
+
+// FUNCTION: クレンジングルールの有効判定
+func (ctx AppCtx) RuleEnabled(id string) bool {
+	_, disabled := ctx.DisabledRules[id]
+	return !disabled
+}
+
+// STRUCT: 中断関数のキー(実行全体のコンテキストに格納)
+type interruptKey struct{}
+
+// FUNCTION: 実行全体のコンテキスト(Ctrl-Cもしくは実行期限で中断、SQLのタイムアウト/キャンセルでも中断)
+func NewRunContext(config RunConfig) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx = withInterrupt(ctx)
+	if config.RunDeadline <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, config.RunDeadline)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// FUNCTION: 中断関数の付与(付与済みの場合はそのまま)
+func withInterrupt(ctx context.Context) context.Context {
+	if _, exist := ctx.Value(interruptKey{}).(context.CancelCauseFunc); exist {
+		return ctx
+	}
+	ctx, interrupt := context.WithCancelCause(ctx)
+	return context.WithValue(ctx, interruptKey{}, interrupt)
+}
+
+// FUNCTION: 中断の理由(中断していない場合はnil)
+func Interruption(run context.Context) error {
+	if run.Err() == nil {
+		return nil
+	}
+	return context.Cause(run)
+}
+
+// FUNCTION: 中断有無(実行中のSQLはキャンセルし、次のSQLの実行前に判定する)
+func (ctx AppCtx) Interrupted() bool {
+	return ctx.Run.Err() != nil
+}
+
+// FUNCTION: SQLのタイムアウト/キャンセルの場合は実行全体を中断する(データの指摘として扱わない)
+func (ctx AppCtx) InterruptIf(err error) bool {
+	if err == nil || !IsInterruption(err) {
+		return false
+	}
+	if interrupt, exist := ctx.Run.Value(interruptKey{}).(context.CancelCauseFunc); exist {
+		interrupt(fmt.Errorf("sql interrupted: %w", err))
+	}
+	return true
+}
+
+// FUNCTION: SQLエラー時の異常終了(タイムアウト/キャンセルの場合は中断)
+func (ctx AppCtx) Fatal(err error) {
+	if ctx.InterruptIf(err) {
+		log.Printf("interrupted: %v\n", err)
+		return
+	}
+	log.Fatalln(err)
+}
+
+// FUNCTION: 中断後も実行するSQL用(結果件数の取得等)
+func (ctx AppCtx) Detached() AppCtx {
+	ctx.Ctx = context.WithoutCancel(ctx.Ctx)
+	return ctx
+}
+
+// FUNCTION: SQL実行タイムアウトを設定したコンテキスト
+func (ctx AppCtx) WithStatementTimeout() (AppCtx, context.CancelFunc) {
+	if ctx.StatementTimeout <= 0 {
+		return ctx, func() {}
+	}
+	stmtCtx, cancel := context.WithTimeout(ctx.Ctx, ctx.StatementTimeout)
+	ctx.Ctx = stmtCtx
+	return ctx, cancel
+}
+
 // FUNCTION: Limit単位の呼び出し回数
 func (ctx AppCtx) LapNumber(val int) int {
 	// LIMIT=5
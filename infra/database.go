@@ -9,10 +9,12 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"time"
+	"net/url"
+	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
+	_ "time/tzdata"
 )
 
 // STRUCT:
@@ -25,11 +27,25 @@ type DbConnection struct {
 // FUNCTION: DB setting
 func initDB(config *Config) (DbConnection, func()) {
 
+	// PROCESS: DSN作成
+	legacyDns, err := genMysqlDns(config.LegacyDB)
+	if err != nil {
+		log.Fatalf("db(mysql) dsn failed: %v", err)
+	}
+	workDns, err := genPsqlDns(config.WorkDB)
+	if err != nil {
+		log.Fatalf("db(postgres) dsn failed: %v", err)
+	}
+	productDns, err := genPsqlDns(config.ProductDB)
+	if err != nil {
+		log.Fatalf("db(postgres) dsn failed: %v", err)
+	}
+
 	// PROCESS: Connection作成
 	cons := DbConnection{
-		LegacyDB:  createCon(genMysqlDns(config.LegacyDB)),
-		WorkDB:    createCon(genPsqlDns(config.WorkDB)),
-		ProductDB: createCon(genPsqlDns(config.ProductDB)),
+		LegacyDB:  config.Run.createCon(config.LegacyDB, "mysql", legacyDns),
+		WorkDB:    config.Run.createCon(config.WorkDB, "postgres", workDns),
+		ProductDB: config.Run.createCon(config.ProductDB, "postgres", productDns),
 	}
 	return cons, func() {
 		cons.LegacyDB.Close()
@@ -39,48 +55,109 @@ func initDB(config *Config) (DbConnection, func()) {
 }
 
 // FUNCTION: connection
-func createCon(dbtype string, dns string) *sql.DB {
+// INFO: ログにはパスワードをマスクしたDSN/エラーメッセージを出力する
+func (config RunConfig) createCon(db DbConfig, dbtype string, dns string) *sql.DB {
 
 	// PROCESS:database open
 	con, err := sql.Open(dbtype, dns)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("db(%s) open failed [%s]: %s", dbtype, db.Redact(dns), db.Redact(err.Error()))
 	}
 
 	// PROCESS:connection pool settings
-	con.SetMaxIdleConns(10)
-	con.SetMaxOpenConns(10)
-	con.SetConnMaxLifetime(300 * time.Second)
+	con.SetMaxIdleConns(config.MaxIdleConns)
+	con.SetMaxOpenConns(config.MaxOpenConns)
+	con.SetConnMaxLifetime(config.ConnMaxLifetime)
 
 	// PROCESS:connection test
 	if err = con.Ping(); err != nil {
-		log.Fatal(err)
+		log.Fatalf("db(%s) connection failed [%s]: %s", dbtype, db.Redact(dns), db.Redact(err.Error()))
 	}
 
-	log.Printf("db(%s) connection prepared [%s]\n", dbtype, dns)
+	log.Printf("db(%s) connection prepared [%s]\n", dbtype, db.Redact(dns))
 	return con
 }
 
-// FUNCTION: psqlDNS
-func genPsqlDns(config DbConfig) (string, string) {
+// FUNCTION: psqlDNS(パスワードはURLエンコード、PARAMSで既定のパラメータを上書き)
+func genPsqlDns(config DbConfig) (string, error) {
+	params := url.Values{}
+	params.Set("sslmode", config.SslMode)
+	for key, value := range map[string]string{
+		"sslrootcert": config.SslRootCert,
+		"sslcert":     config.SslCert,
+		"sslkey":      config.SslKey,
+		"timezone":    config.TimeZone,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	if err := mergeParams(params, config.Params); err != nil {
+		return "", err
+	}
 
-	return "postgres", fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
-		config.User,
-		config.Password,
-		config.Host,
-		config.Port,
-		config.Database,
-	)
+	dns := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.User, config.Password),
+		Host:     fmt.Sprintf("%s:%d", config.Host, config.Port),
+		Path:     config.Database,
+		RawQuery: params.Encode(),
+	}
+	return dns.String(), nil
 }
 
-// FUNCTION: mysqlDNS
-func genMysqlDns(config DbConfig) (string, string) {
+// FUNCTION: mysqlDNS(PARAMSで既定のパラメータを上書き)
+// INFO: ドライバはloc以外の値をデコードしないため、パラメータはエンコードせずに結合する
+func genMysqlDns(config DbConfig) (string, error) {
+	tlsName, err := mysqlTls(config)
+	if err != nil {
+		return "", err
+	}
 
-	return "mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8&parseTime=true&loc=Asia%%2FTokyo",
+	keys := []string{"charset", "parseTime", "tls"}
+	params := map[string]string{
+		"charset":   config.Charset,
+		"parseTime": "true",
+		"tls":       tlsName,
+	}
+	// INFO: タイムゾーンは指定した場合のみ付与する
+	if config.TimeZone != "" {
+		keys = append(keys, "loc")
+		params["loc"] = url.QueryEscape(config.TimeZone)
+	}
+	for _, param := range strings.Split(config.Params, "&") {
+		key, value, found := strings.Cut(param, "=")
+		if !found || key == "" {
+			continue
+		}
+		if _, exist := params[key]; !exist {
+			keys = append(keys, key)
+		}
+		params[key] = value
+	}
+	strs := make([]string, len(keys))
+	for i, key := range keys {
+		strs[i] = key + "=" + params[key]
+	}
+
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s",
 		config.User,
 		config.Password,
 		config.Host,
 		config.Port,
 		config.Database,
-	)
+		strings.Join(strs, "&"),
+	), nil
+}
+
+// FUNCTION: 追加のDSNパラメータ(`key=value&key=value`形式)
+func mergeParams(params url.Values, extra string) error {
+	values, err := url.ParseQuery(extra)
+	if err != nil {
+		return fmt.Errorf("invalid dsn params[%s]: %s", extra, err.Error())
+	}
+	for key := range values {
+		params.Set(key, values.Get(key))
+	}
+	return nil
 }
@@ -0,0 +1,186 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+// TITLE:ダンプ定義(pg_dumpの対象/オプション)
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// STRUCT: ダンプの種類(空の場合はDDL/DMLの両方)
+var DUMP_MODES = []string{"", "schema-only", "data-only"}
+
+// STRUCT: 全ダンプ共通のオプション
+var COMMON_DUMP_ARGS = []string{
+	"--no-owner",
+	"--no-privileges",
+	"--no-security-labels",
+	"--encoding=UTF-8",
+	"--format=P",
+}
+
+// STRUCT: 成果物として予約済みのファイル名(ダンプの出力先に指定できない)
+var RESERVED_FILES = []string{
+	MANIFEST_FILE, "clean.sql", ".transfer-splits.json",
+	".transfer-log.md", ".transfer-log.html", ".transfer-log.json",
+	".cleansing-log.md", ".cleansing-log.html", ".cleansing-log.json",
+	"cleansing-review.xlsx",
+}
+
+// STRUCT: ダンプ定義
+type DumpProfile struct {
+	Name            string     `yaml:"name"`
+	File            string     `yaml:"file"`             //出力ファイル名(`.sql.gz`)
+	Mode            string     `yaml:"mode"`             //schema-only/data-only(空の場合は両方)
+	Schemas         []string   `yaml:"schemas"`          //対象スキーマ(pg_dumpのパターン)
+	ExcludeSchemas  []string   `yaml:"exclude_schemas"`  //対象外スキーマ
+	Tables          []string   `yaml:"tables"`           //対象テーブル
+	ExcludeTables   []string   `yaml:"exclude_tables"`   //対象外テーブル
+	DisableTriggers bool       `yaml:"disable_triggers"` //Load中のトリガー無効化(data-onlyのみ)
+	Masks           []MaskRule `yaml:"masks"`            //マスキングルール(DMLを含むダンプのみ)
+}
+
+// STRUCT: ダンプ定義ファイル
+type DumpFile struct {
+	Dumps []DumpProfile `yaml:"dumps"`
+}
+
+// STRUCT: 既定のダンプ定義(ダンプ定義ファイルが存在しない場合)
+var DEFAULT_DUMP_PROFILES = []DumpProfile{
+	{Name: "dml-local", File: "dml-local.sql.gz", Mode: "data-only", Tables: []string{"orders.operators", "orders.products"}, DisableTriggers: true,
+		Masks: []MaskRule{{Table: "orders.operators", Column: "operator_name", Prefix: "担当者"}}},
+	{Name: "ddl", File: "ddl.sql.gz", Mode: "schema-only", ExcludeSchemas: []string{"public"}},
+	{Name: "dml", File: "dml.sql.gz", Mode: "data-only", ExcludeTables: []string{"public.*"}, DisableTriggers: true},
+}
+
+// FUNCTION: ダンプ定義の読込み(ファイルが存在しない場合は既定のダンプ定義)
+func LoadDumpProfiles(filePath string) ([]DumpProfile, error) {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return DEFAULT_DUMP_PROFILES, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read dump file: %s", err.Error())
+	}
+
+	var file DumpFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse dump file[%s]: %s", filePath, err.Error())
+	}
+	if err := ValidateDumpProfiles(file.Dumps); err != nil {
+		return nil, fmt.Errorf("invalid dump file[%s]: %s", filePath, err.Error())
+	}
+	return file.Dumps, nil
+}
+
+// FUNCTION: ダンプ定義の検証(名前/ファイル名の重複、種類、パターン)
+func ValidateDumpProfiles(profiles []DumpProfile) error {
+	if len(profiles) == 0 {
+		return fmt.Errorf("no dumps defined")
+	}
+	names, files := []string{}, []string{}
+	for i, profile := range profiles {
+		switch {
+		case profile.Name == "":
+			return fmt.Errorf("dumps[%d]: name is required", i)
+		case slices.Contains(names, profile.Name):
+			return fmt.Errorf("dumps[%d]: duplicated name `%s`", i, profile.Name)
+		case !strings.HasSuffix(profile.File, ".sql.gz") || filepath.Base(profile.File) != profile.File:
+			return fmt.Errorf("dumps[%s]: file must be a file name with `.sql.gz` extension: `%s`", profile.Name, profile.File)
+		case slices.Contains(files, profile.File) || slices.Contains(RESERVED_FILES, profile.File):
+			return fmt.Errorf("dumps[%s]: duplicated or reserved file `%s`", profile.Name, profile.File)
+		case !slices.Contains(DUMP_MODES, profile.Mode):
+			return fmt.Errorf("dumps[%s]: mode must be one of schema-only/data-only or empty: `%s`", profile.Name, profile.Mode)
+		case profile.DisableTriggers && profile.Mode != "data-only":
+			return fmt.Errorf("dumps[%s]: disable_triggers requires mode data-only", profile.Name)
+		case len(profile.Masks) > 0 && profile.Mode == "schema-only":
+			return fmt.Errorf("dumps[%s]: masks requires data in dump", profile.Name)
+		}
+		for _, rule := range profile.Masks {
+			if err := rule.validate(); err != nil {
+				return fmt.Errorf("dumps[%s]: %s", profile.Name, err.Error())
+			}
+		}
+		for _, pattern := range slices.Concat(profile.Schemas, profile.ExcludeSchemas, profile.Tables, profile.ExcludeTables) {
+			if strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("dumps[%s]: empty pattern", profile.Name)
+			}
+		}
+		names = append(names, profile.Name)
+		files = append(files, profile.File)
+	}
+	return nil
+}
+
+// FUNCTION: ダンプ定義の検索
+func FindDumpProfile(profiles []DumpProfile, name string) (DumpProfile, error) {
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return DumpProfile{}, fmt.Errorf("dump profile[%s] not found", name)
+}
+
+// FUNCTION: pg_dumpの引数
+// INFO: パターンはbash経由で実行するため、シングルクォートで囲む(`*`等の展開を防ぐ)
+func (profile DumpProfile) Args() []string {
+	args := slices.Clone(COMMON_DUMP_ARGS)
+	if profile.Mode != "" {
+		args = append(args, "--"+profile.Mode)
+	}
+	for _, option := range []struct {
+		flag     string
+		patterns []string
+	}{
+		{"--schema", profile.Schemas},
+		{"--exclude-schema", profile.ExcludeSchemas},
+		{"--table", profile.Tables},
+		{"--exclude-table", profile.ExcludeTables},
+	} {
+		for _, pattern := range option.patterns {
+			args = append(args, fmt.Sprintf("%s=%s", option.flag, shellQuote(pattern)))
+		}
+	}
+	if profile.DisableTriggers {
+		args = append(args, "--disable-triggers")
+	}
+	return args
+}
+
+// FUNCTION: ダンプ定義に従ってダンプする(ルール毎のマスキング件数を返す)
+// INFO: マスキングルールがある場合は一時ファイルにダンプした後、マスキングして出力する(移行先DBは変更しない)
+func (config DbConfig) DumpWith(dumpfilePath string, profile DumpProfile, salt string) (map[string]int, error) {
+	if len(profile.Masks) == 0 {
+		return nil, config.Dump(dumpfilePath, profile.Args())
+	}
+
+	temp, err := os.CreateTemp("", "dump-*.sql.gz")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create temp file: %s", err.Error())
+	}
+	temp.Close()
+	defer os.Remove(temp.Name())
+
+	if err := config.Dump(temp.Name(), profile.Args()); err != nil {
+		return nil, err
+	}
+	counts, err := MaskDump(temp.Name(), dumpfilePath, profile.Masks, salt)
+	if err != nil {
+		os.Remove(dumpfilePath)
+		return nil, err
+	}
+	return counts, nil
+}
+
+// FUNCTION: シングルクォートで囲む(`'`は`'\''`に置換)
+func shellQuote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+}
@@ -67,6 +67,37 @@ func WriteText(filePath string, msg string) error {
 	return nil
 }
 
+// FUNCTION: ファイルへの書き込み(一時ファイルに書き込んでから置き換える、失敗時は元のファイルを残す)
+func WriteTextAtomic(filePath string, msg string) error {
+	// PROCESS: フォルダが存在しない場合作成する
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return fmt.Errorf("cannot create directory: %s", err.Error())
+	}
+
+	// PROCESS: 一時ファイルへの書き込み(同一フォルダに作成して置き換える)
+	temp, err := os.CreateTemp(dir, filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("cannot create file: %s", err.Error())
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.WriteString(msg); err != nil {
+		temp.Close()
+		return fmt.Errorf("cannot write file: %s", err.Error())
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("cannot write file: %s", err.Error())
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("cannot write file: %s", err.Error())
+	}
+	if err := os.Rename(temp.Name(), filePath); err != nil {
+		return fmt.Errorf("cannot replace file: %s", err.Error())
+	}
+	return nil
+}
+
 // FUNCTION: ファイルのコピー
 func FileCopy(srcDir string, distDir string, fileName string) error {
 
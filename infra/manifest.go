@@ -0,0 +1,211 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+// TITLE:成果物のマニフェスト(ファイルのハッシュ値/サイズ、実行情報)
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// STRUCT: マニフェストのファイル名
+const MANIFEST_FILE = "manifest.json"
+
+// STRUCT: マニフェスト
+type Manifest struct {
+	ToolVersion   string           `json:"toolVersion"`
+	AppVersion    string           `json:"appVersion"`
+	LegacyDataKey string           `json:"legacyDataKey"`
+	GitCommit     string           `json:"gitCommit"`
+	StartedAt     time.Time        `json:"startedAt"`
+	FinishedAt    time.Time        `json:"finishedAt"`
+	Files         []ManifestFile   `json:"files"`
+	RowCounts     map[string]int64 `json:"rowCounts"`
+}
+
+// STRUCT: マニフェストのファイル情報
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// FUNCTION: 成果物のファイル一覧(ディレクトリ直下のファイルのみ、マニフェストと履歴ディレクトリは対象外)
+func BundleFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read directory: %s", err.Error())
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == MANIFEST_FILE {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// FUNCTION: ファイルのハッシュ値(SHA-256)とサイズ
+func HashFile(filePath string) (ManifestFile, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("cannot open file: %s", err.Error())
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("cannot read file: %s", err.Error())
+	}
+	return ManifestFile{Name: filepath.Base(filePath), Size: size, Sha256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// FUNCTION: 成果物のファイル情報を登録
+func (m *Manifest) AddFiles(dir string) error {
+	names, err := BundleFiles(dir)
+	if err != nil {
+		return err
+	}
+	m.Files = []ManifestFile{}
+	for _, name := range names {
+		file, err := HashFile(path.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, file)
+	}
+	return nil
+}
+
+// FUNCTION: マニフェストの出力
+func (m Manifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteText(path.Join(dir, MANIFEST_FILE), string(data)+"\n")
+}
+
+// FUNCTION: マニフェストの読込み
+func LoadManifest(dir string) (Manifest, error) {
+	var manifest Manifest
+	data, err := os.ReadFile(path.Join(dir, MANIFEST_FILE))
+	if err != nil {
+		return manifest, fmt.Errorf("cannot read manifest: %s", err.Error())
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("cannot parse manifest[%s]: %s", path.Join(dir, MANIFEST_FILE), err.Error())
+	}
+	return manifest, nil
+}
+
+// FUNCTION: 成果物のアーカイブ(tar.gz、マニフェストと登録ファイルを`ディレクトリ名/`配下に格納)
+func (m Manifest) Archive(dir string, archivePath string) error {
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("cannot create file: %s", err.Error())
+	}
+	defer out.Close()
+
+	gw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gw)
+
+	names := []string{MANIFEST_FILE}
+	for _, file := range m.Files {
+		names = append(names, file.Name)
+	}
+	for _, name := range names {
+		if err := addTar(tw, path.Join(dir, name), path.Join(filepath.Base(dir), name)); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("cannot write archive: %s", err.Error())
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("cannot write archive: %s", err.Error())
+	}
+	return nil
+}
+
+// FUNCTION: ファイルをアーカイブに追加
+func addTar(tw *tar.Writer, filePath string, name string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("cannot open file: %s", err.Error())
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("cannot read file: %s", err.Error())
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("cannot write archive: %s", err.Error())
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("cannot write archive: %s", err.Error())
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("cannot write archive: %s", err.Error())
+	}
+	return nil
+}
+
+// FUNCTION: ダンプファイル(.sql.gz)にデータが含まれるテーブル(`COPY schema.table ... FROM stdin;`)
+func DumpTables(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file: %s", err.Error())
+	}
+	defer file.Close()
+
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read dump file[%s]: %s", filePath, err.Error())
+	}
+	defer gr.Close()
+
+	tables := []string{}
+	reader := bufio.NewReader(gr)
+	inCopy := false
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		// INFO: データ行はCOPY文と誤認しないよう`\.`まで読み飛ばす
+		case inCopy:
+			inCopy = line != `\.`
+		case strings.HasPrefix(line, "COPY ") && strings.HasSuffix(line, "FROM stdin;"):
+			name, _, _ := strings.Cut(strings.TrimPrefix(line, "COPY "), " ")
+			tables = append(tables, name)
+			inCopy = true
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot read dump file[%s]: %s", filePath, err.Error())
+		}
+	}
+	return tables, nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+// TITLE:マスキング(ダンプファイルの個人情報/顧客情報を仮名に置換)
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// STRUCT: 仮名のハッシュ部分の既定の長さ(16進数の桁数)
+const DEFAULT_MASK_LENGTH = 12
+
+// STRUCT: マスキングルール
+type MaskRule struct {
+	Table  string `yaml:"table"`  //`schema.table`
+	Column string `yaml:"column"` //カラム名
+	Prefix string `yaml:"prefix"` //仮名の接頭辞(省略時はカラム名)
+	Length int    `yaml:"length"` //ハッシュ部分の桁数(省略時は12、4～64)
+}
+
+// FUNCTION: 仮名(同じ入力値は常に同じ仮名、接頭辞-HMAC-SHA256の先頭n桁)
+// INFO: ソルトを変えない限り、結合先のカラムに同じルールを指定すれば同じ仮名になる
+func (rule MaskRule) Pseudonym(value string, salt string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(value))
+	prefix := rule.Prefix
+	if prefix == "" {
+		prefix = rule.Column
+	}
+	length := rule.Length
+	if length == 0 {
+		length = DEFAULT_MASK_LENGTH
+	}
+	return fmt.Sprintf("%s-%s", prefix, hex.EncodeToString(mac.Sum(nil))[:length])
+}
+
+// FUNCTION: マスキングルールの検証
+func (rule MaskRule) validate() error {
+	switch {
+	case rule.Table == "" || rule.Column == "":
+		return fmt.Errorf("mask rule requires table and column: `%s.%s`", rule.Table, rule.Column)
+	case rule.Length != 0 && (rule.Length < 4 || rule.Length > 64):
+		return fmt.Errorf("mask rule length must be 4-64: `%s.%s`", rule.Table, rule.Column)
+	case strings.ContainsAny(rule.Prefix, "\t\n\r\\"):
+		return fmt.Errorf("mask rule prefix contains control character: `%s.%s`", rule.Table, rule.Column)
+	}
+	return nil
+}
+
+// FUNCTION: マスキングのソルトの検証(マスキングルールを含むダンプ定義がある場合は必須)
+// INFO: ソルトが空の場合、既知の値のHMACから仮名を逆引きできるため匿名化にならない
+func ValidateMaskingSalt(profiles []DumpProfile, salt string) error {
+	if salt != "" {
+		return nil
+	}
+	names := []string{}
+	for _, profile := range profiles {
+		if len(profile.Masks) > 0 {
+			names = append(names, profile.Name)
+		}
+	}
+	if len(names) > 0 {
+		return fmt.Errorf("MASKING_SALT is required for dumps with masks: `%s`", strings.Join(names, "`, `"))
+	}
+	return nil
+}
+
+// FUNCTION: ダンプファイル(.sql.gz、plain形式)のCOPYデータをマスキングする(ルール毎の置換件数を返す)
+// INFO: 対象テーブルがダンプに存在し、対象カラムが存在しない場合は、マスキング漏れを防ぐためエラーとする
+// INFO: 対象テーブルのCOPYがダンプに存在しないルール(テーブル名の誤り、スキーマの指定漏れ等)もエラーとする
+func MaskDump(srcPath string, dstPath string, rules []MaskRule, salt string) (map[string]int, error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file: %s", err.Error())
+	}
+	defer src.Close()
+	gr, err := gzip.NewReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read dump file[%s]: %s", srcPath, err.Error())
+	}
+	defer gr.Close()
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create file: %s", err.Error())
+	}
+	defer dst.Close()
+	gw := gzip.NewWriter(dst)
+	writer := bufio.NewWriter(gw)
+
+	counts := map[string]int{}
+	matched := map[string]struct{}{} //COPYが存在したルール(`schema.table.column`)
+	reader := bufio.NewReader(gr)
+	var targets map[int]MaskRule //COPY中のテーブルのカラム位置 → ルール
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("cannot read dump file[%s]: %s", srcPath, readErr.Error())
+		}
+
+		switch {
+		case targets != nil && strings.TrimRight(line, "\r\n") == `\.`:
+			targets = nil
+		case targets != nil:
+			line = maskRow(line, targets, salt, counts)
+		case strings.HasPrefix(line, "COPY ") && strings.HasSuffix(strings.TrimRight(line, "\r\n"), "FROM stdin;"):
+			if targets, err = copyTargets(line, rules); err != nil {
+				return nil, err
+			}
+			for _, rule := range targets {
+				matched[rule.key()] = struct{}{}
+			}
+		}
+
+		if _, err := writer.WriteString(line); err != nil {
+			return nil, fmt.Errorf("cannot write dump file: %s", err.Error())
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("cannot write dump file: %s", err.Error())
+	}
+	if err := gw.Close(); err != nil {
+		return nil, fmt.Errorf("cannot write dump file: %s", err.Error())
+	}
+
+	// PROCESS: COPYが存在しないルール
+	unmatched := []string{}
+	for _, rule := range rules {
+		if _, exist := matched[rule.key()]; !exist {
+			unmatched = append(unmatched, rule.key())
+		}
+	}
+	if len(unmatched) > 0 {
+		return nil, fmt.Errorf("mask table not found in dump (no COPY section): `%s`", strings.Join(unmatched, "`, `"))
+	}
+	return counts, nil
+}
+
+// FUNCTION: ルールのキー(`schema.table.column`)
+func (rule MaskRule) key() string {
+	return fmt.Sprintf("%s.%s", rule.Table, rule.Column)
+}
+
+// FUNCTION: COPY文のカラム位置とマスキングルールの対応(`COPY schema.table (col, col) FROM stdin;`)
+func copyTargets(line string, rules []MaskRule) (map[int]MaskRule, error) {
+	table, rest, _ := strings.Cut(strings.TrimPrefix(line, "COPY "), " ")
+	start, end := strings.Index(rest, "("), strings.LastIndex(rest, ")")
+	columns := []string{}
+	if start >= 0 && end > start {
+		for _, column := range strings.Split(rest[start+1:end], ",") {
+			columns = append(columns, strings.Trim(strings.TrimSpace(column), `"`))
+		}
+	}
+
+	targets := map[int]MaskRule{}
+	for _, rule := range rules {
+		if rule.Table != table {
+			continue
+		}
+		index := slices.Index(columns, rule.Column)
+		if index < 0 {
+			return nil, fmt.Errorf("mask column not found in dump: `%s.%s`", rule.Table, rule.Column)
+		}
+		targets[index] = rule
+	}
+	return targets, nil
+}
+
+// FUNCTION: データ行(タブ区切り、`\N`はNULL)のマスキング
+func maskRow(line string, targets map[int]MaskRule, salt string, counts map[string]int) string {
+	if len(targets) == 0 {
+		return line
+	}
+	body := strings.TrimRight(line, "\r\n")
+	fields := strings.Split(body, "\t")
+	for index, rule := range targets {
+		if index >= len(fields) || fields[index] == `\N` {
+			continue
+		}
+		fields[index] = rule.Pseudonym(fields[index], salt)
+		counts[rule.key()]++
+	}
+	return strings.Join(fields, "\t") + line[len(body):]
+}
@@ -0,0 +1,235 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+import (
+	"compress/gzip"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TITLE: マスキング(ダンプファイルの個人情報/顧客情報を仮名に置換)
+
+// STRUCT: テスト用のマスキングルール
+var testMaskRules = []MaskRule{
+	{Table: "orders.operators", Column: "operator_name", Prefix: "担当者"},
+	{Table: "orders.orders", Column: "customer_name", Prefix: "顧客", Length: 8},
+}
+
+// FUNCTION: COPY文のカラム位置とマスキングルールの対応
+func TestCopyTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    map[int]string //カラム位置 → ルールのキー
+		wantErr string
+	}{
+		{
+			name: "target column",
+			line: "COPY orders.operators (operator_id, operator_name, created_at) FROM stdin;\n",
+			want: map[int]string{1: "orders.operators.operator_name"},
+		},
+		{
+			name: "quoted column",
+			line: "COPY orders.orders (order_no, \"customer_name\") FROM stdin;\n",
+			want: map[int]string{1: "orders.orders.customer_name"},
+		},
+		{
+			name: "table without rules",
+			line: "COPY orders.products (product_id, product_name) FROM stdin;\n",
+			want: map[int]string{},
+		},
+		{
+			name:    "missing column",
+			line:    "COPY orders.operators (operator_id, created_at) FROM stdin;\n",
+			wantErr: "mask column not found in dump: `orders.operators.operator_name`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := copyTargets(tt.line, testMaskRules)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[int]string{}
+			for index, rule := range targets {
+				got[index] = rule.key()
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// FUNCTION: データ行のマスキング(NULL、列数不足、改行コード)
+func TestMaskRow(t *testing.T) {
+	rule := testMaskRules[0]
+	targets := map[int]MaskRule{1: rule}
+	masked := rule.Pseudonym("山田太郎", "salt")
+	tests := []struct {
+		name  string
+		line  string
+		want  string
+		count int
+	}{
+		{name: "value", line: "AB001\t山田太郎\t2025-01-10\n", want: "AB001\t" + masked + "\t2025-01-10\n", count: 1},
+		{name: "null", line: "AB001\t\\N\t2025-01-10\n", want: "AB001\t\\N\t2025-01-10\n", count: 0},
+		{name: "short row", line: "AB001\n", want: "AB001\n", count: 0},
+		{name: "crlf", line: "AB001\t山田太郎\r\n", want: "AB001\t" + masked + "\r\n", count: 1},
+		{name: "last line without newline", line: "AB001\t山田太郎", want: "AB001\t" + masked, count: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := map[string]int{}
+			if got := maskRow(tt.line, targets, "salt", counts); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if counts[rule.key()] != tt.count {
+				t.Errorf("count: got %d, want %d", counts[rule.key()], tt.count)
+			}
+		})
+	}
+}
+
+// FUNCTION: ダンプファイルのマスキング(COPYデータのみ置換、同じ値は同じ仮名、COPYが存在しないルールはエラー)
+func TestMaskDump(t *testing.T) {
+	dump := strings.Join([]string{
+		"-- operator_name 山田太郎 in comment",
+		"COPY orders.operators (operator_id, operator_name) FROM stdin;",
+		"AB001\t山田太郎",
+		"CD002\t\\N",
+		"EF003\t山田太郎",
+		"\\.",
+		"COPY orders.products (product_id, product_name) FROM stdin;",
+		"P0001\t山田太郎",
+		"\\.",
+		"",
+	}, "\n")
+	name := testMaskRules[0].Pseudonym("山田太郎", "salt")
+
+	tests := []struct {
+		name    string
+		rules   []MaskRule
+		want    string
+		counts  map[string]int
+		wantErr string
+	}{
+		{
+			name:  "mask operators",
+			rules: testMaskRules[:1],
+			want: strings.Join([]string{
+				"-- operator_name 山田太郎 in comment",
+				"COPY orders.operators (operator_id, operator_name) FROM stdin;",
+				"AB001\t" + name,
+				"CD002\t\\N",
+				"EF003\t" + name,
+				"\\.",
+				"COPY orders.products (product_id, product_name) FROM stdin;",
+				"P0001\t山田太郎",
+				"\\.",
+				"",
+			}, "\n"),
+			counts: map[string]int{"orders.operators.operator_name": 2},
+		},
+		{
+			name:    "unmatched rule",
+			rules:   testMaskRules,
+			wantErr: "mask table not found in dump (no COPY section): `orders.orders.customer_name`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			srcPath, dstPath := filepath.Join(dir, "src.sql.gz"), filepath.Join(dir, "dst.sql.gz")
+			writeGzip(t, srcPath, dump)
+
+			counts, err := MaskDump(srcPath, dstPath, tt.rules, "salt")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readGzip(t, dstPath); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+			if !maps.Equal(counts, tt.counts) {
+				t.Errorf("counts: got %v, want %v", counts, tt.counts)
+			}
+		})
+	}
+}
+
+// FUNCTION: マスキングのソルトの検証
+func TestValidateMaskingSalt(t *testing.T) {
+	tests := []struct {
+		name    string
+		salt    string
+		wantErr bool
+	}{
+		{name: "salt is set", salt: "secret"},
+		{name: "salt is empty", salt: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMaskingSalt(DEFAULT_DUMP_PROFILES, tt.salt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := ValidateMaskingSalt([]DumpProfile{{Name: "ddl"}}, ""); err != nil {
+		t.Errorf("dumps without masks: got %v", err)
+	}
+}
+
+// FUNCTION: gzipファイルの作成
+func writeGzip(t *testing.T, filePath string, content string) {
+	t.Helper()
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gw := gzip.NewWriter(file)
+	if _, err := gw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// FUNCTION: gzipファイルの読込み
+func readGzip(t *testing.T, filePath string) string {
+	t.Helper()
+	file, err := os.Open(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+// TITLE:個別指定値(オーバーライド)の読込み
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// STRUCT: 個別指定値(ルールID → レコードキー → 値)
+type Overrides map[string]map[string]string
+
+// FUNCTION: 個別指定値の読込み
+// ディレクトリ内の`{ルールID(#なし)}.csv`もしくは`{ルールID(#なし)}.yaml`を読み込む。(ディレクトリが存在しない場合は空)
+//   - csv: 1列目=レコードキー、2列目=値(1行目が`key`の場合はヘッダとして読み飛ばす)
+//   - yaml: `レコードキー: 値` のマップ
+func LoadOverrides(dir string) (Overrides, error) {
+	overrides := Overrides{}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return overrides, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read override directory: %s", err.Error())
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		filePath := filepath.Join(dir, entry.Name())
+		ext := filepath.Ext(entry.Name())
+		ruleId := overrideRuleId(strings.TrimSuffix(entry.Name(), ext))
+
+		var table map[string]string
+		switch strings.ToLower(ext) {
+		case ".csv":
+			table, err = readOverrideCsv(filePath)
+		case ".yaml", ".yml":
+			table, err = readOverrideYaml(filePath)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// PROCESS: 同一ルールのファイルが複数ある場合はマージ(後勝ち)
+		if _, exist := overrides[ruleId]; !exist {
+			overrides[ruleId] = map[string]string{}
+		}
+		for key, value := range table {
+			overrides[ruleId][key] = value
+		}
+		log.Printf("loaded override table [%s] … %d records\n", entry.Name(), len(table))
+	}
+	return overrides, nil
+}
+
+// FUNCTION: 個別指定値の検索
+func (o Overrides) Lookup(ruleId string, key string) (string, bool) {
+	table, exist := o[ruleId]
+	if !exist {
+		return "", false
+	}
+	value, exist := table[key]
+	return value, exist
+}
+
+// FUNCTION: ファイル名からルールIDを導出(`3-01` → `#3-01`、数字以外で始まる場合はそのまま)
+func overrideRuleId(name string) string {
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		return "#" + name
+	}
+	return name
+}
+
+// FUNCTION: csvの読込み
+func readOverrideCsv(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open override file: %s", err.Error())
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse override file[%s]: %s", filePath, err.Error())
+	}
+
+	table := map[string]string{}
+	for i, row := range rows {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(row[0]), "key") {
+			continue
+		}
+		table[strings.TrimSpace(row[0])] = strings.TrimSpace(row[1])
+	}
+	return table, nil
+}
+
+// FUNCTION: yamlの読込み
+func readOverrideYaml(filePath string) (map[string]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read override file: %s", err.Error())
+	}
+
+	table := map[string]string{}
+	if err := yaml.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("cannot parse override file[%s]: %s", filePath, err.Error())
+	}
+	return table, nil
+}
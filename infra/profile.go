@@ -0,0 +1,288 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+// TITLE:設定ファイル(プロファイル)
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// STRUCT: 環境変数名の接頭辞を付与しないセクション
+var FLAT_SECTIONS = map[string]struct{}{
+	"base": {},
+	"run":  {},
+}
+
+// STRUCT: マスク文字列
+const MASKED = "********"
+
+// STRUCT: 設定ファイル(default:共通設定、profiles:プロファイル毎の上書き設定)
+type ConfigFile struct {
+	Default  map[string]any            `yaml:"default" toml:"default"`
+	Profiles map[string]map[string]any `yaml:"profiles" toml:"profiles"`
+}
+
+// FUNCTION: 設定ファイルの適用(環境変数が未設定の項目のみ、設定ファイルの値を環境変数に設定する)
+func ApplyConfigFile(filePath string, profile string) error {
+	if filePath == "" {
+		if profile != "" {
+			return fmt.Errorf("profile[%s] requires config file (--config)", profile)
+		}
+		return nil
+	}
+
+	// PROCESS: 設定ファイルの読込み
+	file, err := readConfigFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	// PROCESS: 共通設定にプロファイルの設定を上書き
+	values := map[string]string{}
+	flatten("", file.Default, values)
+	if profile != "" {
+		settings, exist := file.Profiles[profile]
+		if !exist {
+			return fmt.Errorf("profile[%s] not found in config file[%s]: available %v", profile, filePath, file.profileNames())
+		}
+		flatten("", settings, values)
+		values["CONFIG_PROFILE"] = profile
+	}
+
+	// PROCESS: 環境変数への設定(環境変数を優先する)
+	for key, value := range values {
+		if _, exist := os.LookupEnv(key); exist {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+	log.Printf("loaded config file [%s] profile[%s]\n", filePath, profile)
+	return nil
+}
+
+// FUNCTION: 設定ファイルの読込み(拡張子でYAML/TOMLを判定)
+func readConfigFile(filePath string) (ConfigFile, error) {
+	var file ConfigFile
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return file, fmt.Errorf("cannot read config file: %s", err.Error())
+	}
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	case ".toml":
+		err = toml.Unmarshal(data, &file)
+	default:
+		return file, fmt.Errorf("unsupported config file type[%s]: use .yaml/.yml/.toml", filePath)
+	}
+	if err != nil {
+		return file, fmt.Errorf("cannot parse config file[%s]: %s", filePath, err.Error())
+	}
+	return file, nil
+}
+
+// FUNCTION: プロファイル名の一覧
+func (file ConfigFile) profileNames() []string {
+	names := []string{}
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FUNCTION: 階層構造を環境変数名に変換(`legacy_mariadb.host` → `LEGACY_MARIADB_HOST`、base/runセクションは接頭辞なし)
+func flatten(prefix string, settings map[string]any, values map[string]string) {
+	for key, value := range settings {
+		name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			if _, exist := FLAT_SECTIONS[strings.ToLower(key)]; exist {
+				flatten(prefix, v, values)
+			} else {
+				flatten(name, v, values)
+			}
+		case []any:
+			strs := make([]string, len(v))
+			for i, item := range v {
+				strs[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(strs, ",")
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+}
+
+// FUNCTION: 有効な設定値の出力(YAML形式、パスワードはマスク)
+func (config Config) Show() (string, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	v := reflect.ValueOf(config)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		section := sectionName(t.Field(i))
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		fv := v.Field(i)
+		ft := fv.Type()
+		for j := 0; j < ft.NumField(); j++ {
+			tag := ft.Field(j).Tag.Get("envconfig")
+			if tag == "" {
+				continue
+			}
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: strings.ToLower(tag)},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: showValue(tag, fv.Field(j).Interface())},
+			)
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: section}, node)
+	}
+
+	data, err := yaml.Marshal(root)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FUNCTION: セクション名(接頭辞なしの場合はフィールド名)
+func sectionName(field reflect.StructField) string {
+	if tag := field.Tag.Get("envconfig"); tag != "" {
+		return strings.ToLower(tag)
+	}
+	return strings.ToLower(field.Name)
+}
+
+// FUNCTION: 出力値(パスワード/ソルトはマスク)
+func showValue(tag string, value any) string {
+	switch v := value.(type) {
+	case time.Duration:
+		return v.String()
+	case []string:
+		return strings.Join(v, ",")
+	case string:
+		if (strings.Contains(tag, "PASSWORD") || strings.Contains(tag, "SALT")) && v != "" {
+			return MASKED
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// FUNCTION: 設定値の検証
+func (config Config) Validate() []error {
+	errs := []error{}
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// PROCESS: 基本設定
+	if config.Base.LegacyDataKey == "" {
+		add("LEGACY_DATA_KEY is empty")
+	}
+	if config.Base.AppVersion == "" {
+		add("APP_VERSION is empty")
+	}
+	if config.Base.WorkDir == "" || config.Base.DistDir == "" {
+		add("WORK_DIR/DIST_DIR must not be empty")
+	}
+	if _, err := LoadOverrides(config.Base.OverrideDir); err != nil {
+		add("OVERRIDE_DIR: %v", err)
+	}
+
+	// PROCESS: 実行設定
+	run := config.Run
+	if run.FetchLimit <= 0 {
+		add("FETCH_LIMIT must be positive: %d", run.FetchLimit)
+	}
+	if run.MaxOpenConns < 0 || run.MaxIdleConns < 0 {
+		add("DB_MAX_OPEN_CONNS/DB_MAX_IDLE_CONNS must not be negative: %d/%d", run.MaxOpenConns, run.MaxIdleConns)
+	}
+	if run.MaxOpenConns > 0 && run.MaxIdleConns > run.MaxOpenConns {
+		add("DB_MAX_IDLE_CONNS(%d) exceeds DB_MAX_OPEN_CONNS(%d)", run.MaxIdleConns, run.MaxOpenConns)
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"DB_CONN_MAX_LIFETIME", run.ConnMaxLifetime},
+		{"CLEANSING_STATEMENT_TIMEOUT", run.CleansingStatementTimeout},
+		{"TRANSFER_STATEMENT_TIMEOUT", run.TransferStatementTimeout},
+		{"RUN_DEADLINE", run.RunDeadline},
+	} {
+		if d.value < 0 {
+			add("%s must not be negative: %s", d.name, d.value)
+		}
+	}
+	if run.RetryMax < 0 {
+		add("RETRY_MAX must not be negative: %d", run.RetryMax)
+	}
+	if run.RetryMax > 0 && (run.RetryInterval <= 0 || run.RetryMaxInterval < run.RetryInterval) {
+		add("RETRY_INTERVAL(%s)/RETRY_MAX_INTERVAL(%s) is invalid", run.RetryInterval, run.RetryMaxInterval)
+	}
+	if _, err := time.Parse(run.DateLayout, time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC).Format(run.DateLayout)); err != nil || run.DateLayout == "" {
+		add("DATE_LAYOUT is invalid: `%s`", run.DateLayout)
+	}
+	ruleId := regexp.MustCompile(`^#\d+-\d{2}$`)
+	for _, id := range run.DisabledRules {
+		// INFO: 前後の空白は無視する(disabledRulesと同じ)
+		if id = strings.TrimSpace(id); id != "" && !ruleId.MatchString(id) {
+			add("DISABLED_RULES contains invalid rule id: `%s`", id)
+		}
+	}
+
+	// PROCESS: データベース接続設定
+	for _, db := range []struct {
+		name   string
+		dbtype string
+		config DbConfig
+	}{
+		{"LEGACY_MARIADB", "mysql", config.LegacyDB},
+		{"WORK_POSTGRES", "postgres", config.WorkDB},
+		{"PRODUCT_POSTGRES", "postgres", config.ProductDB},
+	} {
+		if err := db.config.validateSsl(db.dbtype); err != nil {
+			add("%s_SSL*: %v", db.name, err)
+		}
+		if _, err := time.LoadLocation(db.config.TimeZone); err != nil {
+			add("%s_TIME_ZONE is invalid: %v", db.name, err)
+		}
+		if _, err := url.ParseQuery(db.config.Params); err != nil {
+			add("%s_PARAMS is invalid: %v", db.name, err)
+		}
+		if db.config.Password == "" {
+			add("%s_PASSWORD is empty: set PASSWORD, PASSWORD_FILE or PGPASSFILE", db.name)
+		}
+		if db.config.Host == "" {
+			add("%s_HOST is empty", db.name)
+		}
+		if db.config.Port <= 0 || db.config.Port > 65535 {
+			add("%s_PORT is out of range: %d", db.name, db.config.Port)
+		}
+	}
+	if config.WorkDB.Host == config.ProductDB.Host && config.WorkDB.Port == config.ProductDB.Port && config.WorkDB.Database == config.ProductDB.Database {
+		add("WORK_POSTGRES and PRODUCT_POSTGRES point to the same database")
+	}
+	return errs
+}
@@ -0,0 +1,153 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+// TITLE:リトライ(一時的なDBエラー)
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// STRUCT: リトライ設定
+type RetryPolicy struct {
+	Max         int           //最大リトライ回数
+	Interval    time.Duration //初回の待機時間(以降は2倍ずつ増加)
+	MaxInterval time.Duration //最大の待機時間
+}
+
+// STRUCT: リトライ回数
+type RetryCounter struct {
+	count int
+}
+
+// FUNCTION: リトライ回数の生成
+func NewRetryCounter() *RetryCounter {
+	return &RetryCounter{}
+}
+
+// FUNCTION: リトライ回数
+func (c *RetryCounter) Count() int {
+	if c == nil {
+		return 0
+	}
+	return c.count
+}
+
+// FUNCTION: リトライ回数の加算
+func (c *RetryCounter) increment() {
+	if c != nil {
+		c.count++
+	}
+}
+
+// FUNCTION: 一時的なエラーの場合は指数バックオフでリトライする(制約違反等の恒久的なエラーはリトライしない)
+// INFO: SQL実行タイムアウトは試行毎に設定する(待機時間はタイムアウトに含めない)
+func Retry[T any](ctx AppCtx, fn func(context.Context) (T, error)) (T, error) {
+	interval := ctx.Retry.Interval
+	for attempt := 1; ; attempt++ {
+		stmtCtx, cancel := ctx.WithStatementTimeout()
+		result, err := fn(stmtCtx.Ctx)
+		cancel()
+		if err == nil || !IsTransient(err) || attempt > ctx.Retry.Max || ctx.Interrupted() {
+			return result, err
+		}
+
+		// PROCESS: 待機後にリトライ(中断時は待機せずに終了)
+		ctx.Retries.increment()
+		log.Printf("transient db error, retry(%d/%d) after %s: %v\n", attempt, ctx.Retry.Max, interval, err)
+		select {
+		case <-time.After(interval):
+		case <-ctx.Ctx.Done():
+			return result, err
+		}
+		interval = min(interval*2, ctx.Retry.MaxInterval)
+	}
+}
+
+// FUNCTION: リトライ(戻り値なし)
+func RetryExec(ctx AppCtx, fn func(context.Context) error) error {
+	_, err := Retry(ctx, func(c context.Context) (struct{}, error) {
+		return struct{}{}, fn(c)
+	})
+	return err
+}
+
+// FUNCTION: リトライ(INSERT用)
+// INFO: 一時的なエラーでも前回の試行が登録済みの場合があるため、リトライ後の一意制約違反は登録済み(成功)とする
+func RetryInsert(ctx AppCtx, fn func(context.Context) error) error {
+	attempt := 0
+	return RetryExec(ctx, func(c context.Context) error {
+		attempt++
+		err := fn(c)
+		if attempt > 1 && IsDuplicate(err) {
+			log.Printf("duplicate key after retry(%d), treated as inserted: %v\n", attempt-1, err)
+			return nil
+		}
+		return err
+	})
+}
+
+// FUNCTION: 一意制約違反の判定(PostgreSQL 23505:unique_violation、MySQL 1062:duplicate_entry)
+func IsDuplicate(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// FUNCTION: SQLのタイムアウト/キャンセルの判定(PostgreSQL 57014:query_canceled を含む)
+func IsInterruption(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+// FUNCTION: 一時的なエラーの判定(接続断、デッドロック、直列化失敗、MySQL 2006/2013)
+func IsTransient(err error) bool {
+	// INFO: タイムアウト/キャンセルはリトライしない
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// PROCESS: 接続断
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	// PROCESS: PostgreSQL(40001:serialization_failure、40P01:deadlock_detected、08xxx:connection_exception、57P0x:shutdown)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		return code == "40001" || code == "40P01" || strings.HasPrefix(code, "08") || strings.HasPrefix(code, "57P0")
+	}
+
+	// PROCESS: MySQL/MariaDB(1205:lock_wait_timeout、1213:deadlock、2006:server_gone_away、2013:lost_connection)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1205, 1213, 2006, 2013:
+			return true
+		}
+	}
+	return false
+}
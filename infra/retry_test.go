@@ -0,0 +1,100 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// TITLE: リトライ(一時的なDBエラー)
+
+// FUNCTION: SQL実行タイムアウトは試行毎(待機時間がタイムアウトを超えても次の試行は実行できる)
+func TestRetryStatementTimeout(t *testing.T) {
+	config := RunConfig{FetchLimit: 2, DateLayout: "20060102", RetryMax: 2, RetryInterval: 80 * time.Millisecond, RetryMaxInterval: 80 * time.Millisecond}
+	ctx := NewCtx(context.Background(), config, 50*time.Millisecond, Overrides{})
+	ctx.Retries = NewRetryCounter()
+
+	attempts := 0
+	result, err := Retry(ctx, func(c context.Context) (int, error) {
+		attempts++
+		if c.Err() != nil {
+			return 0, c.Err()
+		}
+		if _, exist := c.Deadline(); !exist {
+			t.Errorf("attempt %d: statement timeout is not set", attempts)
+		}
+		if attempts == 1 {
+			return 0, driver.ErrBadConn
+		}
+		return attempts, nil
+	})
+	if err != nil || result != 2 {
+		t.Fatalf("got (%d, %v), want (2, nil)", result, err)
+	}
+	if ctx.Retries.Count() != 1 {
+		t.Errorf("retries: got %d, want 1", ctx.Retries.Count())
+	}
+	if ctx.Interrupted() {
+		t.Errorf("run is interrupted: %v", Interruption(ctx.Run))
+	}
+}
+
+// FUNCTION: エラーの分類(一時的なエラー/一意制約違反/タイムアウト・キャンセル)
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		transient    bool
+		duplicate    bool
+		interruption bool
+	}{
+		{name: "nil", err: nil},
+		{name: "other error", err: errors.New("syntax error")},
+		{name: "bad conn", err: driver.ErrBadConn, transient: true},
+		{name: "wrapped connection reset", err: fmt.Errorf("cannot insert: %w", syscall.ECONNRESET), transient: true},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, transient: true},
+		{name: "pq 23505 unique_violation", err: &pq.Error{Code: "23505"}, duplicate: true},
+		{name: "pq 23503 foreign_key_violation", err: &pq.Error{Code: "23503"}},
+		{name: "pq 40001 serialization_failure", err: &pq.Error{Code: "40001"}, transient: true},
+		{name: "pq 40P01 deadlock_detected", err: &pq.Error{Code: "40P01"}, transient: true},
+		{name: "pq 08006 connection_failure", err: &pq.Error{Code: "08006"}, transient: true},
+		{name: "pq 08001 wrapped", err: fmt.Errorf("cannot fetch: %w", &pq.Error{Code: "08001"}), transient: true},
+		{name: "pq 57P01 admin_shutdown", err: &pq.Error{Code: "57P01"}, transient: true},
+		{name: "pq 57014 query_canceled", err: &pq.Error{Code: "57014"}, interruption: true},
+		{name: "mysql 1062 duplicate_entry", err: &mysql.MySQLError{Number: 1062}, duplicate: true},
+		{name: "mysql 1452 foreign_key", err: &mysql.MySQLError{Number: 1452}},
+		{name: "mysql 1205 lock_wait_timeout", err: &mysql.MySQLError{Number: 1205}, transient: true},
+		{name: "mysql 1213 deadlock", err: &mysql.MySQLError{Number: 1213}, transient: true},
+		{name: "mysql 2006 server_gone_away", err: &mysql.MySQLError{Number: 2006}, transient: true},
+		{name: "mysql 2013 lost_connection", err: fmt.Errorf("cannot fetch: %w", &mysql.MySQLError{Number: 2013}), transient: true},
+		{name: "mysql invalid conn", err: mysql.ErrInvalidConn, transient: true},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, interruption: true},
+		{name: "wrapped deadline exceeded", err: fmt.Errorf("cannot insert: %w", context.DeadlineExceeded), interruption: true},
+		{name: "wrapped canceled", err: fmt.Errorf("cannot fetch: %w", context.Canceled), interruption: true},
+		{name: "canceled with connection error", err: errors.Join(context.Canceled, driver.ErrBadConn), interruption: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.transient {
+				t.Errorf("IsTransient: got %v, want %v", got, tt.transient)
+			}
+			if got := IsDuplicate(tt.err); got != tt.duplicate {
+				t.Errorf("IsDuplicate: got %v, want %v", got, tt.duplicate)
+			}
+			if got := IsInterruption(tt.err); got != tt.interruption {
+				t.Errorf("IsInterruption: got %v, want %v", got, tt.interruption)
+			}
+		})
+	}
+}
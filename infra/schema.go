@@ -0,0 +1,182 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+// TITLE:スキーマ情報(information_schema)
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// STRUCT: カラム情報
+type DbColumn struct {
+	Name       string
+	DataType   string
+	Nullable   bool
+	HasDefault bool
+}
+
+// STRUCT: テーブル情報(テーブル名 → カラム名 → カラム情報)
+type DbSchema map[string]map[string]DbColumn
+
+// FUNCTION: スキーマ情報の取得(dbtype:postgres/mysql)
+func FetchSchema(ctx context.Context, db *sql.DB, dbtype string, schema string) (DbSchema, error) {
+	placeholder := "$1"
+	if dbtype == "mysql" {
+		placeholder = "?"
+	}
+	query := fmt.Sprintf(`SELECT table_name, column_name, data_type, is_nullable, column_default IS NOT NULL
+		FROM information_schema.columns WHERE table_schema = %s ORDER BY table_name, ordinal_position`, placeholder)
+
+	rows, err := db.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := DbSchema{}
+	for rows.Next() {
+		var table, nullable string
+		var column DbColumn
+		if err := rows.Scan(&table, &column.Name, &column.DataType, &nullable, &column.HasDefault); err != nil {
+			return nil, err
+		}
+		column.Nullable = nullable == "YES"
+		column.DataType = strings.ToLower(column.DataType)
+		if _, exist := result[table]; !exist {
+			result[table] = map[string]DbColumn{}
+		}
+		result[table][column.Name] = column
+	}
+	return result, rows.Err()
+}
+
+// FUNCTION: テーブルのカラム名一覧(ソート済み)
+func (s DbSchema) ColumnNames(table string) []string {
+	names := []string{}
+	for name := range s[table] {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// FUNCTION: モデルのカラム名一覧(sqlboilerの`XxxColumns`構造体から取得)
+func ModelColumns(columns any) []string {
+	v := reflect.ValueOf(columns)
+	names := make([]string, v.NumField())
+	for i := range names {
+		names[i] = v.Field(i).String()
+	}
+	return names
+}
+
+// STRUCT: モデルのカラム情報
+type ModelField struct {
+	Name     string
+	GoType   string //int/string/bool/time/float
+	Nullable bool   //null.Xxx型
+}
+
+// STRUCT: Goの型と互換性のあるDBの型(information_schema.columns.data_type)
+var COMPATIBLE_TYPES = map[string][]string{
+	"int":    {"tinyint", "smallint", "mediumint", "int", "integer", "bigint"},
+	"string": {"char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set", "character", "character varying", "user-defined"},
+	"bool":   {"tinyint", "bit", "boolean"},
+	"time":   {"date", "datetime", "timestamp", "timestamp without time zone", "timestamp with time zone"},
+	"float":  {"float", "double", "double precision", "real", "decimal", "numeric"},
+}
+
+// FUNCTION: モデルのカラム情報(sqlboilerのモデル構造体の`boil`タグから取得)
+func ModelFields(model any) []ModelField {
+	t := reflect.TypeOf(model)
+	fields := []ModelField{}
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("boil")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := t.Field(i).Type
+		nullable := strings.HasPrefix(ft.PkgPath(), "github.com/volatiletech/null")
+		fields = append(fields, ModelField{Name: name, GoType: goTypeName(ft.Name()), Nullable: nullable})
+	}
+	return fields
+}
+
+// FUNCTION: Goの型名の正規化(null.Int64 → int、time.Time → time)
+func goTypeName(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(name, "int") || strings.HasPrefix(name, "uint"):
+		return "int"
+	case strings.HasPrefix(name, "float"):
+		return "float"
+	default:
+		return name
+	}
+}
+
+// STRUCT: カラムの差分
+type ColumnDrift struct {
+	Table    string
+	Missing  []string //モデルに存在し、DBに存在しないカラム
+	Extra    []string //DBに存在し、モデルに存在しないカラム
+	Mismatch []string //型/NULL許可の不一致(`カラム名: 内容`)
+}
+
+// FUNCTION: カラムの差分(モデルのカラム一覧とDBのカラム一覧を比較)
+func CompareColumns(table string, modelColumns []string, schema DbSchema) ColumnDrift {
+	drift := ColumnDrift{Table: table, Missing: []string{}, Extra: []string{}}
+	for _, name := range modelColumns {
+		if _, exist := schema[table][name]; !exist {
+			drift.Missing = append(drift.Missing, name)
+		}
+	}
+	for _, name := range schema.ColumnNames(table) {
+		if !slices.Contains(modelColumns, name) {
+			drift.Extra = append(drift.Extra, name)
+		}
+	}
+	return drift
+}
+
+// FUNCTION: カラムの差分(カラムの過不足に加え、型とNULL許可を比較)
+func CompareModel(table string, fields []ModelField, schema DbSchema) ColumnDrift {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+	drift := CompareColumns(table, names, schema)
+	drift.Mismatch = []string{}
+
+	for _, field := range fields {
+		column, exist := schema[table][field.Name]
+		if !exist {
+			continue
+		}
+		if compatible, known := COMPATIBLE_TYPES[field.GoType]; known && !slices.Contains(compatible, column.DataType) {
+			drift.Mismatch = append(drift.Mismatch, fmt.Sprintf("%s: type `%s` is incompatible with model `%s`", field.Name, column.DataType, field.GoType))
+		}
+		if column.Nullable && !field.Nullable {
+			drift.Mismatch = append(drift.Mismatch, fmt.Sprintf("%s: nullable column but model is not null", field.Name))
+		}
+	}
+	return drift
+}
+
+// FUNCTION: 差分あり
+func (d ColumnDrift) HasDrift() bool {
+	return len(d.Missing) > 0 || len(d.Extra) > 0 || len(d.Mismatch) > 0
+}
+
+// FUNCTION: 破壊的な差分あり(カラム不足、型/NULL許可の不一致)
+func (d ColumnDrift) IsBreaking() bool {
+	return len(d.Missing) > 0 || len(d.Mismatch) > 0
+}
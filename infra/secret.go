@@ -0,0 +1,120 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+// TITLE:秘密情報(パスワード)
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FUNCTION: パスワードの解決(優先順位:PASSWORD > PASSWORD_FILE > PGPASSFILE形式の認証ファイル)
+func (config *DbConfig) resolvePassword(passFile string) error {
+	if config.Password != "" {
+		return nil
+	}
+
+	// PROCESS: パスワードファイル(ファイルの内容をパスワードとする)
+	if config.PasswordFile != "" {
+		data, err := os.ReadFile(config.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("cannot read password file: %s", err.Error())
+		}
+		config.Password = strings.TrimRight(string(data), "\r\n")
+		return nil
+	}
+
+	// PROCESS: 認証ファイル(hostname:port:database:username:password)
+	if passFile != "" {
+		password, err := lookupPassFile(passFile, *config)
+		if err != nil {
+			return err
+		}
+		config.Password = password
+	}
+	return nil
+}
+
+// FUNCTION: 認証ファイルの検索(最初に一致した行、`*`は任意の値に一致)
+func lookupPassFile(filePath string, config DbConfig) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("cannot read passfile: %s", err.Error())
+	}
+	defer file.Close()
+
+	keys := []string{config.Host, strconv.Itoa(config.Port), config.Database, config.User}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitPassLine(line)
+		if len(fields) != 5 {
+			continue
+		}
+		if matchPassFields(fields[:4], keys) {
+			return fields[4], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("cannot read passfile: %s", err.Error())
+	}
+	return "", nil
+}
+
+// FUNCTION: 認証ファイルの行を分割(`\:`、`\\`はエスケープ)
+func splitPassLine(line string) []string {
+	fields := []string{}
+	var field strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(r)
+		}
+	}
+	return append(fields, field.String())
+}
+
+// FUNCTION: 認証ファイルの項目の一致判定
+func matchPassFields(fields []string, keys []string) bool {
+	for i, field := range fields {
+		if field != "*" && field != keys[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// FUNCTION: 文字列中のパスワードをマスク(DSN、エラーメッセージ等のログ出力用)
+func (config DbConfig) Redact(str string) string {
+	if config.Password == "" {
+		return str
+	}
+	userinfo := strings.TrimPrefix(url.UserPassword("", config.Password).String(), ":")
+	for _, secret := range []string{config.Password, userinfo, url.QueryEscape(config.Password)} {
+		str = strings.ReplaceAll(str, secret, MASKED)
+	}
+	return str
+}
+
+// FUNCTION: 子プロセスの環境変数(パスワードをコマンドラインに含めない)
+func (config DbConfig) passwordEnv() []string {
+	return append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", config.Password))
+}
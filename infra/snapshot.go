@@ -0,0 +1,106 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+// TITLE:テーブル/ビューのスナップショット(回帰テストのゴールデンファイル用)
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// STRUCT: NULLの表記(COPYのテキスト形式と同じ)
+const NULL_STR = `\N`
+
+// STRUCT: 値のエスケープ(タブ/改行を含む値で行が崩れないようにする)
+var TSV_ESCAPER = strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// FUNCTION: スキーマ内のテーブル/ビュー名の一覧(PostgreSQL)
+func Relations(ctx context.Context, db *sql.DB, schema string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema = $1 ORDER BY table_name", schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// FUNCTION: テーブル/ビューの内容(タブ区切り、1行目はカラム名、全カラムでソート、excludesのカラムは除外)
+func SnapshotTable(ctx context.Context, db *sql.DB, schema string, table string, excludes []string) (string, error) {
+	names, err := orderedColumns(ctx, db, schema, table)
+	if err != nil {
+		return "", err
+	}
+	columns := []string{}
+	for _, name := range names {
+		if !slices.Contains(excludes, name) {
+			columns = append(columns, name)
+		}
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("no columns to snapshot: %s.%s", schema, table)
+	}
+
+	// PROCESS: 全カラムをテキストに変換して取得
+	selects, orders := make([]string, len(columns)), make([]string, len(columns))
+	for i, name := range columns {
+		selects[i] = fmt.Sprintf(`COALESCE("%s"::text, '%s')`, name, NULL_STR)
+		orders[i] = fmt.Sprintf("%d", i+1)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s.%s ORDER BY %s", strings.Join(selects, ", "), schema, table, strings.Join(orders, ", "))
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return "", fmt.Errorf("cannot snapshot %s.%s: %s", schema, table, err.Error())
+	}
+	defer rows.Close()
+
+	var builder strings.Builder
+	builder.WriteString(strings.Join(columns, "\t") + "\n")
+	values := make([]string, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		for i, value := range values {
+			values[i] = TSV_ESCAPER.Replace(value)
+		}
+		builder.WriteString(strings.Join(values, "\t") + "\n")
+	}
+	return builder.String(), rows.Err()
+}
+
+// FUNCTION: カラム名の一覧(定義順)
+func orderedColumns(ctx context.Context, db *sql.DB, schema string, table string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT column_name FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 ORDER BY ordinal_position", schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
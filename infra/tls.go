@@ -0,0 +1,122 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package infra
+
+// TITLE:TLS設定
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// STRUCT: SSLモード
+var PSQL_SSL_MODES = []string{"disable", "require", "verify-ca", "verify-full"}
+var MYSQL_SSL_MODES = []string{"disable", "preferred", "require", "verify-ca", "verify-full"}
+
+// FUNCTION: MariaDBのtlsパラメータ(証明書の指定/検証が必要な場合はTLS設定を登録する)
+func mysqlTls(config DbConfig) (string, error) {
+	switch config.SslMode {
+	case "", "disable":
+		return "false", nil
+	case "preferred":
+		return "preferred", nil
+	case "require":
+		if config.SslCert == "" && config.SslRootCert == "" {
+			return "skip-verify", nil
+		}
+	case "verify-ca", "verify-full":
+	default:
+		return "", fmt.Errorf("unsupported sslmode[%s]: use %v", config.SslMode, MYSQL_SSL_MODES)
+	}
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("custom-%s-%d", config.Host, config.Port)
+	if err := mysql.RegisterTLSConfig(name, tlsConfig); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// FUNCTION: TLS設定(require:検証なし、verify-ca:CA証明書のみ検証、verify-full:ホスト名を含めて検証)
+func (config DbConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: config.Host}
+
+	// PROCESS: CA証明書(未指定の場合はシステムの証明書)
+	if config.SslRootCert != "" {
+		pem, err := os.ReadFile(config.SslRootCert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read sslrootcert: %s", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid sslrootcert[%s]", config.SslRootCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// PROCESS: クライアント証明書
+	if config.SslCert != "" || config.SslKey != "" {
+		cert, err := tls.LoadX509KeyPair(config.SslCert, config.SslKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load sslcert/sslkey: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	// PROCESS: 検証レベル
+	switch config.SslMode {
+	case "require":
+		tlsConfig.InsecureSkipVerify = true
+	case "verify-ca":
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = verifyCa(tlsConfig.RootCAs)
+	}
+	return tlsConfig, nil
+}
+
+// FUNCTION: CA証明書のみ検証(ホスト名は検証しない)
+func verifyCa(roots *x509.CertPool) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("no server certificate")
+		}
+		opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+}
+
+// FUNCTION: SSLモードの検証
+func (config DbConfig) validateSsl(dbtype string) error {
+	modes := PSQL_SSL_MODES
+	if dbtype == "mysql" {
+		modes = MYSQL_SSL_MODES
+	}
+	if !slices.Contains(modes, config.SslMode) {
+		return fmt.Errorf("unsupported sslmode[%s]: use %v", config.SslMode, modes)
+	}
+	for _, file := range []string{config.SslRootCert, config.SslCert, config.SslKey} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("cannot read certificate file: %s", err.Error())
+		}
+	}
+	if (config.SslCert == "") != (config.SslKey == "") {
+		return fmt.Errorf("sslcert and sslkey must be specified together")
+	}
+	return nil
+}
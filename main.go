@@ -7,9 +7,10 @@ import "github.com/teru-0529/data-transfer-sandbox/cmd"
 
 var (
 	version = "dev"
+	commit  = "unknown"
 	date    = "unknown"
 )
 
 func main() {
-	cmd.Execute(version, date)
+	cmd.Execute(version, commit, date)
 }
@@ -0,0 +1,119 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service/transfer"
+)
+
+// TITLE: 移行成果物(マニフェストの作成/検証)
+
+// STRUCT: 移行先DBの件数取得対象スキーマ
+var PRODUCT_SCHEMAS = []string{transfer.SCHEMA}
+
+// FUNCTION: マニフェストの作成(成果物のファイル出力後に実行する)
+func CreateManifest(ctx context.Context, config infra.Config, db *sql.DB, gitCommit string, startedAt time.Time, dir string) (infra.Manifest, error) {
+	manifest := infra.Manifest{
+		ToolVersion:   config.Base.ToolVersion,
+		AppVersion:    config.Base.AppVersion,
+		LegacyDataKey: config.Base.LegacyDataKey,
+		GitCommit:     gitCommit,
+		StartedAt:     startedAt,
+	}
+
+	// PROCESS: 移行先DBの件数
+	counts, err := CountRows(ctx, db, PRODUCT_SCHEMAS)
+	if err != nil {
+		return manifest, err
+	}
+	manifest.RowCounts = counts
+
+	// PROCESS: ファイルのハッシュ値
+	if err := manifest.AddFiles(dir); err != nil {
+		return manifest, err
+	}
+	manifest.FinishedAt = time.Now()
+
+	return manifest, manifest.Save(dir)
+}
+
+// FUNCTION: 成果物の検証(マニフェストとファイルのハッシュ値/サイズを比較、未登録ファイルもエラー)
+func VerifyBundle(dir string) (infra.Manifest, string, error) {
+	manifest, err := infra.LoadManifest(dir)
+	if err != nil {
+		return manifest, "", err
+	}
+	names, err := infra.BundleFiles(dir)
+	if err != nil {
+		return manifest, "", err
+	}
+
+	msg := "\n## Bundle Verification\n\n"
+	msg += fmt.Sprintf("- **transfer tool version**: %s\n", manifest.ToolVersion)
+	msg += fmt.Sprintf("- **production schema version**: %s\n", manifest.AppVersion)
+	msg += fmt.Sprintf("- **load legacy DB key**: %s\n", manifest.LegacyDataKey)
+	msg += fmt.Sprintf("- **git commit**: %s\n", manifest.GitCommit)
+	msg += fmt.Sprintf("- **run**: %s - %s\n\n", manifest.StartedAt.Format("2006/01/02 15:04:05"), manifest.FinishedAt.Format("2006/01/02 15:04:05"))
+	msg += "  | # | FILE | SIZE | SHA-256 | RESULT |\n"
+	msg += "  |--:|---|--:|---|:-:|\n"
+
+	ngCount := 0
+	listed := []string{}
+	for i, file := range manifest.Files {
+		listed = append(listed, file.Name)
+		result := "⭕"
+		actual, err := infra.HashFile(path.Join(dir, file.Name))
+		switch {
+		case err != nil:
+			result = "❌ missing"
+		case actual.Size != file.Size:
+			result = fmt.Sprintf("❌ size(%d)", actual.Size)
+		case actual.Sha256 != file.Sha256:
+			result = "❌ sha256"
+		}
+		if result != "⭕" {
+			ngCount++
+		}
+		msg += fmt.Sprintf("  | %d. | %s | %d | %s | %s |\n", i+1, file.Name, file.Size, file.Sha256, result)
+	}
+
+	// PROCESS: マニフェストに登録されていないファイル
+	for _, name := range names {
+		if slices.Contains(listed, name) {
+			continue
+		}
+		ngCount++
+		msg += fmt.Sprintf("  | - | %s | - | - | ❌ unlisted |\n", name)
+	}
+
+	if ngCount > 0 {
+		return manifest, msg, fmt.Errorf("bundle verification failed: %d file(s) mismatched", ngCount)
+	}
+	return manifest, msg, nil
+}
+
+// FUNCTION: 適用後の期待件数(マニフェストの件数のうちDMLに含まれるテーブル、含まれないテーブルは0件)
+func ExpectedRowCounts(manifest infra.Manifest, dmlPath string) (RowCounts, error) {
+	tables, err := infra.DumpTables(dmlPath)
+	if err != nil {
+		return nil, err
+	}
+	expected := RowCounts{}
+	for name, count := range manifest.RowCounts {
+		if slices.Contains(tables, name) {
+			expected[name] = count
+		} else {
+			expected[name] = 0
+		}
+	}
+	return expected, nil
+}
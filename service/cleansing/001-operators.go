@@ -4,16 +4,13 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package cleansing
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
-	"github.com/volatiletech/sqlboiler/v4/boil"
-	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 // STRUCT: 詳細メッセージ
@@ -37,17 +34,51 @@ type OperatorRecord struct {
 }
 
 // FUNCTION: 更新
-func (r *OperatorRecord) checkAndPersist(ctx infra.AppCtx, db *sql.DB, refData *RefData) Piece {
+func (r *OperatorRecord) checkAndPersist(ctx infra.AppCtx, writer CleanWriter, refData *RefData) Piece {
+
+	// PROCESS: 再登録データ(承認済みの隔離データ)の適用(再登録による変更を記録)
+	fetched := r.record
+	reinstated := r.applyReinstate(refData)
+	origin := r.record
+	r.msg.bp.diffChanged(QUARANTINE_TABLES[legacy.TableNames.Operators], r.values(fetched), r.values(origin), "")
 
 	// PROCESS: check #1-01:
-	r.checkOperatorName(refData.OperatorNameSet)
+	if ctx.RuleEnabled("#1-01") {
+		r.checkOperatorName(refData.OperatorNameSet)
+	}
 
 	// PROCESS:TODO: check #1-02:
-	r.checkOperatorId()
+	if ctx.RuleEnabled("#1-02") {
+		r.checkOperatorId(ctx, refData.OperatorIdSet)
+	}
+
+	// PROCESS: 承認データ(レビューブックで承認済みのルール)の適用
+	r.msg.bp.applyApproval(refData.Approvals.lookup(legacy.TableNames.Operators, r.legacyKey()))
+
+	// PROCESS: 再登録データの場合は除外しない
+	if reinstated {
+		r.msg.bp.reinstated()
+	}
+
+	// PROCESS: 移行元/クレンジング後の値(レビュー用)
+	r.msg.bp.setValues(r.values(fetched), r.values(r.record))
 
 	// PROCESS: REMOVE判定時は登録なし
 	if !r.msg.bp.isRemove() {
-		r.persiste(ctx, db)
+		r.persiste(ctx, writer)
+	}
+
+	// PROCESS: 中断時(SQLのタイムアウト/キャンセル)は変更履歴/隔離データを登録しない
+	if r.msg.bp.interrupted {
+		return *r.msg.bp
+	}
+
+	// PROCESS: MODIFY判定時(登録エラーを除く)は変更履歴を登録
+	changeLog(ctx, writer, legacy.TableNames.Operators, r.legacyKey(), r.msg.bp)
+
+	// PROCESS: REMOVE判定時(登録エラーを含む)は隔離データとして登録
+	if r.msg.bp.isRemove() {
+		quarantine(ctx, writer, legacy.TableNames.Operators, r.legacyKey(), r.values(origin), r.msg.bp)
 	}
 
 	// PROCESS: REMOVE/MODIFY判定時は詳細情報の出力あり
@@ -55,16 +86,38 @@ func (r *OperatorRecord) checkAndPersist(ctx infra.AppCtx, db *sql.DB, refData *
 		*r.setTo = append(*r.setTo, *r.msg)
 	}
 
-	// PROCESS:INFO: 正常登録時に[担当者名]登録
+	// PROCESS:INFO: 正常登録時に[担当者ID/担当者名]登録
 	if !r.msg.bp.isRemove() {
+		refData.OperatorIdSet[r.record.OperatorID] = struct{}{}
 		refData.OperatorNameSet[r.record.OperatorName] = struct{}{}
 	}
 
 	return *r.msg.bp
 }
 
+// FUNCTION: 移行元キー
+func (r *OperatorRecord) legacyKey() string {
+	return r.msg.OperatorId
+}
+
+// FUNCTION: カラム値(QUARANTINE_TABLESのカラム順)
+func (r *OperatorRecord) values(rec legacy.Operator) []any {
+	return []any{rec.OperatorID, rec.OperatorName}
+}
+
+// FUNCTION: 再登録データの適用
+func (r *OperatorRecord) applyReinstate(refData *RefData) bool {
+	values, exist := refData.Reinstate.lookup(legacy.TableNames.Operators, r.legacyKey())
+	if !exist {
+		return false
+	}
+	r.record.OperatorID = reinstateStr(values, legacy.OperatorColumns.OperatorID, r.record.OperatorID)
+	r.record.OperatorName = reinstateStr(values, legacy.OperatorColumns.OperatorName, r.record.OperatorName)
+	return true
+}
+
 // FUNCTION: データ登録
-func (r *OperatorRecord) persiste(ctx infra.AppCtx, db *sql.DB) {
+func (r *OperatorRecord) persiste(ctx infra.AppCtx, writer CleanWriter) {
 	// PROCESS: データ登録
 	rec := clean.Operator{
 		OperatorID:   r.record.OperatorID,
@@ -72,11 +125,13 @@ func (r *OperatorRecord) persiste(ctx infra.AppCtx, db *sql.DB) {
 		CreatedBy:    ctx.OperationUser,
 		UpdatedBy:    ctx.OperationUser,
 	}
-	err := rec.Insert(ctx.Ctx, db, boil.Infer())
+	err := infra.RetryInsert(ctx, func(c context.Context) error {
+		return writer.InsertOperator(c, rec)
+	})
 
 	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納)
 	if err != nil {
-		r.msg.bp.dbError(err)
+		r.msg.bp.dbError(ctx, err)
 	}
 }
 
@@ -91,16 +146,27 @@ func (r *OperatorRecord) checkOperatorName(OperatorNameSet map[string]struct{})
 }
 
 // FUNCTION: #1-02(MODIFY): 担当者IDが5桁ではない場合、末尾に「X」を埋めてクレンジングする。
-func (r *OperatorRecord) checkOperatorId() {
+// 個別指定値(担当者ID単位)が存在する場合は、個別指定値にクレンジングする。(5桁ではない、登録済みの担当者IDの場合は既定)
+func (r *OperatorRecord) checkOperatorId(ctx infra.AppCtx, operatorIdSet map[string]struct{}) {
 	const ID = "#1-02"
 	const LENGTH int = 5
 	const CHAR string = "X"
 	operatorId := r.record.OperatorID
 	if len(operatorId) < LENGTH {
+		r.msg.bp.approveStay(ID) //TODO: 承認確認中
+
+		// PROCESS: 個別指定値が存在する場合(5桁ではない、登録済みの場合は既定)
+		value, exist := ctx.Overrides.Lookup(ID, operatorId)
+		if _, duplicated := operatorIdSet[value]; exist && len(value) == LENGTH && !duplicated {
+			r.record.OperatorID = value
+			r.msg.bp.overridden().changed(legacy.OperatorColumns.OperatorID, operatorId, r.record.OperatorID, ID).addMessage(
+				fmt.Sprintf("operator_id(担当者ID) の桁数が5桁未満(%d桁)です。<br>【クレンジング】`%s`(個別指定値) にクレンジング。", len(operatorId), value), ID)
+			return
+		}
+
 		r.record.OperatorID = operatorId + strings.Repeat(CHAR, LENGTH-len(operatorId))
-		r.msg.bp.approveStay() //TODO: 承認確認中
-		r.msg.bp.modified().addMessage(
-			fmt.Sprintf("operator_id(担当者ID) の桁数が5桁未満(%d桁)です。<br>【クレンジング】末尾に`X`を追加", len(operatorId)), ID)
+		r.msg.bp.modified().changed(legacy.OperatorColumns.OperatorID, operatorId, r.record.OperatorID, ID).addMessage(
+			fmt.Sprintf("operator_id(担当者ID) の桁数が5桁未満(%d桁)です。<br>【クレンジング】末尾に`X`を追加(既定)%s", len(operatorId), invalidOverrideMsg(value, exist)), ID)
 	}
 }
 
@@ -123,19 +189,23 @@ func (cmd *OperatorsCmd) getTableInfo() TableInfo {
 }
 
 // FUNCTION: 入力データ量
-func (cmd *OperatorsCmd) entryCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := legacy.Operators().Count(ctx.Ctx, con)
+func (cmd *OperatorsCmd) entryCount(ctx infra.AppCtx, reader LegacyReader, subset *Subset) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return reader.Count(c, legacy.TableNames.Operators, subset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
 
 // FUNCTION: 処理対象レコードのフェッチ
-func (cmd *OperatorsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm.QueryMod) []Record {
-	records, err := legacy.Operators(qmArray...).All(ctx.Ctx, con)
+func (cmd *OperatorsCmd) fetchRecords(ctx infra.AppCtx, reader LegacyReader, subset *Subset, limit int, offset int) []Record {
+	records, err := infra.Retry(ctx, func(c context.Context) (legacy.OperatorSlice, error) {
+		return reader.FetchOperators(c, subset, limit, offset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 
 	results := make([]Record, len(records))
@@ -150,7 +220,7 @@ func (cmd *OperatorsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []q
 }
 
 // FUNCTION: 追加データ登録
-func (r *OperatorsCmd) extInsert(ctx infra.AppCtx, db *sql.DB, refData *RefData) {
+func (r *OperatorsCmd) extInsert(ctx infra.AppCtx, writer CleanWriter, refData *RefData) {
 	// INFO: ダミー担当者
 	rec := clean.Operator{
 		OperatorID:   "Z9999",
@@ -158,7 +228,10 @@ func (r *OperatorsCmd) extInsert(ctx infra.AppCtx, db *sql.DB, refData *RefData)
 		CreatedBy:    ctx.OperationUser,
 		UpdatedBy:    ctx.OperationUser,
 	}
-	rec.Insert(ctx.Ctx, db, boil.Infer())
+	infra.RetryInsert(ctx, func(c context.Context) error {
+		return writer.InsertOperator(c, rec)
+	})
+	refData.OperatorIdSet["Z9999"] = struct{}{}
 	refData.OperatorNameSet["N/A"] = struct{}{}
 }
 
@@ -176,7 +249,7 @@ func (cmd *OperatorsCmd) showDetails(ctx infra.AppCtx, tableName string) string
 		msg += fmt.Sprintf("  | %d | %s | … | %s | %s | %s |\n",
 			i+1,
 			piece.OperatorId,
-			piece.bp.status,
+			piece.bp.result(),
 			piece.bp.approve,
 			piece.bp.msg,
 		)
@@ -4,15 +4,13 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package cleansing
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
+	"strconv"
 
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
-	"github.com/volatiletech/sqlboiler/v4/boil"
-	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 // STRUCT: 詳細メッセージ
@@ -36,14 +34,46 @@ type ProductRecord struct {
 }
 
 // FUNCTION: 更新
-func (r *ProductRecord) checkAndPersist(ctx infra.AppCtx, db *sql.DB, refData *RefData) Piece {
+func (r *ProductRecord) checkAndPersist(ctx infra.AppCtx, writer CleanWriter, refData *RefData) Piece {
+
+	// PROCESS: 再登録データ(承認済みの隔離データ)の適用(再登録による変更を記録)
+	fetched := r.record
+	reinstated := r.applyReinstate(refData)
+	origin := r.record
+	r.msg.bp.diffChanged(QUARANTINE_TABLES[legacy.TableNames.Products], r.values(fetched), r.values(origin), "")
 
 	// PROCESS:TODO: check #2-01:
-	r.checkCostPrice()
+	if ctx.RuleEnabled("#2-01") {
+		r.checkCostPrice(ctx)
+	}
+
+	// PROCESS: 承認データ(レビューブックで承認済みのルール)の適用
+	r.msg.bp.applyApproval(refData.Approvals.lookup(legacy.TableNames.Products, r.legacyKey()))
+
+	// PROCESS: 再登録データの場合は除外しない
+	if reinstated {
+		r.msg.bp.reinstated()
+	}
+
+	// PROCESS: 移行元/クレンジング後の値(レビュー用)
+	r.msg.bp.setValues(r.values(fetched), r.values(r.record))
 
 	// PROCESS: REMOVE判定時は登録なし
 	if !r.msg.bp.isRemove() {
-		r.persiste(ctx, db)
+		r.persiste(ctx, writer)
+	}
+
+	// PROCESS: 中断時(SQLのタイムアウト/キャンセル)は変更履歴/隔離データを登録しない
+	if r.msg.bp.interrupted {
+		return *r.msg.bp
+	}
+
+	// PROCESS: MODIFY判定時(登録エラーを除く)は変更履歴を登録
+	changeLog(ctx, writer, legacy.TableNames.Products, r.legacyKey(), r.msg.bp)
+
+	// PROCESS: REMOVE判定時(登録エラーを含む)は隔離データとして登録
+	if r.msg.bp.isRemove() {
+		quarantine(ctx, writer, legacy.TableNames.Products, r.legacyKey(), r.values(origin), r.msg.bp)
 	}
 
 	// PROCESS: REMOVE/MODIFY判定時は詳細情報の出力あり
@@ -59,8 +89,29 @@ func (r *ProductRecord) checkAndPersist(ctx infra.AppCtx, db *sql.DB, refData *R
 	return *r.msg.bp
 }
 
+// FUNCTION: 移行元キー
+func (r *ProductRecord) legacyKey() string {
+	return r.msg.ProductName
+}
+
+// FUNCTION: カラム値(QUARANTINE_TABLESのカラム順)
+func (r *ProductRecord) values(rec legacy.Product) []any {
+	return []any{rec.ProductName, rec.CostPrice}
+}
+
+// FUNCTION: 再登録データの適用
+func (r *ProductRecord) applyReinstate(refData *RefData) bool {
+	values, exist := refData.Reinstate.lookup(legacy.TableNames.Products, r.legacyKey())
+	if !exist {
+		return false
+	}
+	r.record.ProductName = reinstateStr(values, legacy.ProductColumns.ProductName, r.record.ProductName)
+	r.record.CostPrice = reinstateInt(values, legacy.ProductColumns.CostPrice, r.record.CostPrice)
+	return true
+}
+
 // FUNCTION: データ登録
-func (r *ProductRecord) persiste(ctx infra.AppCtx, db *sql.DB) {
+func (r *ProductRecord) persiste(ctx infra.AppCtx, writer CleanWriter) {
 	// PROCESS: データ登録
 	rec := clean.Product{
 		ProductName: r.record.ProductName,
@@ -69,23 +120,37 @@ func (r *ProductRecord) persiste(ctx infra.AppCtx, db *sql.DB) {
 		UpdatedBy:   ctx.OperationUser,
 		// INFO: w_product_id はtrigger function
 	}
-	err := rec.Insert(ctx.Ctx, db, boil.Infer())
+	err := infra.RetryInsert(ctx, func(c context.Context) error {
+		return writer.InsertProduct(c, rec)
+	})
 
 	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納)
 	if err != nil {
-		r.msg.bp.dbError(err)
+		r.msg.bp.dbError(ctx, err)
 	}
 }
 
 // FUNCTION: #2-01(MODIFY): cost_priceが負の数字の場合は、0にクレンジングする。
-func (r *ProductRecord) checkCostPrice() {
+// 個別指定値(商品名単位)が存在する場合は、個別指定値にクレンジングする。
+func (r *ProductRecord) checkCostPrice(ctx infra.AppCtx) {
 	const ID = "#2-01"
+	const COST_PRICE int = 0
 	costPrice := r.record.CostPrice
 	if costPrice < 0 {
-		r.record.CostPrice = 0
-		r.msg.bp.approveStay() //TODO: 承認確認中
-		r.msg.bp.modified().addMessage(
-			fmt.Sprintf("cost_price(商品原価) が負の数です`%d`。<br>【クレンジング】`0`に変換", costPrice), "#2-01")
+		r.msg.bp.approveStay(ID) //TODO: 承認確認中
+
+		// PROCESS: 個別指定値が存在する場合(数値に変換できない場合は固定値)
+		value, exist := ctx.Overrides.Lookup(ID, r.record.ProductName)
+		if overridePrice, err := strconv.Atoi(value); exist && err == nil {
+			r.record.CostPrice = overridePrice
+			r.msg.bp.overridden().changed(legacy.ProductColumns.CostPrice, costPrice, r.record.CostPrice, ID).addMessage(
+				fmt.Sprintf("cost_price(商品原価) が負の数です`%d`。<br>【クレンジング】`%d`(個別指定値) に変換", costPrice, overridePrice), ID)
+			return
+		}
+
+		r.record.CostPrice = COST_PRICE
+		r.msg.bp.modified().changed(legacy.ProductColumns.CostPrice, costPrice, r.record.CostPrice, ID).addMessage(
+			fmt.Sprintf("cost_price(商品原価) が負の数です`%d`。<br>【クレンジング】`%d`(固定値) に変換%s", costPrice, COST_PRICE, invalidOverrideMsg(value, exist)), ID)
 	}
 }
 
@@ -108,21 +173,23 @@ func (cmd *ProductsCmd) getTableInfo() TableInfo {
 }
 
 // FUNCTION: 入力データ量
-func (cmd *ProductsCmd) entryCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := legacy.Products().Count(ctx.Ctx, con)
+func (cmd *ProductsCmd) entryCount(ctx infra.AppCtx, reader LegacyReader, subset *Subset) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return reader.Count(c, legacy.TableNames.Products, subset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
 
 // FUNCTION: 処理対象レコードのフェッチ
-func (cmd *ProductsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm.QueryMod) []Record {
-	//INFO: 商品名:昇順
-	qmArray = append(qmArray, qm.OrderBy("product_name ASC"))
-	records, err := legacy.Products(qmArray...).All(ctx.Ctx, con)
+func (cmd *ProductsCmd) fetchRecords(ctx infra.AppCtx, reader LegacyReader, subset *Subset, limit int, offset int) []Record {
+	records, err := infra.Retry(ctx, func(c context.Context) (legacy.ProductSlice, error) {
+		return reader.FetchProducts(c, subset, limit, offset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 
 	results := make([]Record, len(records))
@@ -137,7 +204,7 @@ func (cmd *ProductsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm
 }
 
 // FUNCTION: 追加データ登録
-func (r *ProductsCmd) extInsert(ctx infra.AppCtx, db *sql.DB, refData *RefData) {}
+func (r *ProductsCmd) extInsert(ctx infra.AppCtx, writer CleanWriter, refData *RefData) {}
 
 // FUNCTION: 詳細メッセージの出力
 func (cmd *ProductsCmd) showDetails(ctx infra.AppCtx, tableName string) string {
@@ -153,7 +220,7 @@ func (cmd *ProductsCmd) showDetails(ctx infra.AppCtx, tableName string) string {
 		msg += fmt.Sprintf("  | %d | %s | … | %s | %s | %s |\n",
 			i+1,
 			piece.ProductName,
-			piece.bp.status,
+			piece.bp.result(),
 			piece.bp.approve,
 			piece.bp.msg,
 		)
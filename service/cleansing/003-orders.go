@@ -4,16 +4,14 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package cleansing
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
+	"strconv"
 	"time"
 
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
-	"github.com/volatiletech/sqlboiler/v4/boil"
-	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 // STRUCT: 詳細メッセージ
@@ -37,17 +35,51 @@ type OrderRecord struct {
 }
 
 // FUNCTION: 更新
-func (r *OrderRecord) checkAndPersist(ctx infra.AppCtx, db *sql.DB, refData *RefData) Piece {
+func (r *OrderRecord) checkAndPersist(ctx infra.AppCtx, writer CleanWriter, refData *RefData) Piece {
+
+	// PROCESS: 再登録データ(承認済みの隔離データ)の適用(再登録による変更を記録)
+	fetched := r.record
+	reinstated := r.applyReinstate(refData)
+	origin := r.record
+	r.msg.bp.diffChanged(QUARANTINE_TABLES[legacy.TableNames.Orders], r.values(fetched), r.values(origin), "")
 
 	// PROCESS: check #3-01:
-	r.checkOrderDate(ctx)
+	if ctx.RuleEnabled("#3-01") {
+		r.checkOrderDate(ctx)
+	}
 
 	// PROCESS: check #3-02:
-	r.checkOrderPic(refData.OperatorNameSet)
+	if ctx.RuleEnabled("#3-02") {
+		r.checkOrderPic(ctx, refData)
+	}
+
+	// PROCESS: 承認データ(レビューブックで承認済みのルール)の適用
+	r.msg.bp.applyApproval(refData.Approvals.lookup(legacy.TableNames.Orders, r.legacyKey()))
+
+	// PROCESS: 再登録データの場合は除外しない
+	if reinstated {
+		r.msg.bp.reinstated()
+	}
+
+	// PROCESS: 移行元/クレンジング後の値(レビュー用)
+	r.msg.bp.setValues(r.values(fetched), r.values(r.record))
 
 	// PROCESS: REMOVE判定時は登録なし
 	if !r.msg.bp.isRemove() {
-		r.persiste(ctx, db)
+		r.persiste(ctx, writer)
+	}
+
+	// PROCESS: 中断時(SQLのタイムアウト/キャンセル)は変更履歴/隔離データを登録しない
+	if r.msg.bp.interrupted {
+		return *r.msg.bp
+	}
+
+	// PROCESS: MODIFY判定時(登録エラーを除く)は変更履歴を登録
+	changeLog(ctx, writer, legacy.TableNames.Orders, r.legacyKey(), r.msg.bp)
+
+	// PROCESS: REMOVE判定時(登録エラーを含む)は隔離データとして登録
+	if r.msg.bp.isRemove() {
+		quarantine(ctx, writer, legacy.TableNames.Orders, r.legacyKey(), r.values(origin), r.msg.bp)
 	}
 
 	// PROCESS: REMOVE/MODIFY判定時は詳細情報の出力あり
@@ -63,8 +95,31 @@ func (r *OrderRecord) checkAndPersist(ctx infra.AppCtx, db *sql.DB, refData *Ref
 	return *r.msg.bp
 }
 
+// FUNCTION: 移行元キー
+func (r *OrderRecord) legacyKey() string {
+	return strconv.Itoa(r.msg.OrderNo)
+}
+
+// FUNCTION: カラム値(QUARANTINE_TABLESのカラム順)
+func (r *OrderRecord) values(rec legacy.Order) []any {
+	return []any{rec.OrderNo, rec.OrderDate, rec.OrderPic, rec.CustomerName}
+}
+
+// FUNCTION: 再登録データの適用
+func (r *OrderRecord) applyReinstate(refData *RefData) bool {
+	values, exist := refData.Reinstate.lookup(legacy.TableNames.Orders, r.legacyKey())
+	if !exist {
+		return false
+	}
+	r.record.OrderNo = reinstateInt(values, legacy.OrderColumns.OrderNo, r.record.OrderNo)
+	r.record.OrderDate = reinstateStr(values, legacy.OrderColumns.OrderDate, r.record.OrderDate)
+	r.record.OrderPic = reinstateStr(values, legacy.OrderColumns.OrderPic, r.record.OrderPic)
+	r.record.CustomerName = reinstateStr(values, legacy.OrderColumns.CustomerName, r.record.CustomerName)
+	return true
+}
+
 // FUNCTION: データ登録
-func (r *OrderRecord) persiste(ctx infra.AppCtx, db *sql.DB) {
+func (r *OrderRecord) persiste(ctx infra.AppCtx, writer CleanWriter) {
 	// INFO: 日付型変換
 	orderDate, _ := time.Parse(ctx.DateLayout, r.record.OrderDate)
 
@@ -77,38 +132,71 @@ func (r *OrderRecord) persiste(ctx infra.AppCtx, db *sql.DB) {
 		CreatedBy:    ctx.OperationUser,
 		UpdatedBy:    ctx.OperationUser,
 	}
-	err := rec.Insert(ctx.Ctx, db, boil.Infer())
+	err := infra.RetryInsert(ctx, func(c context.Context) error {
+		return writer.InsertOrder(c, rec)
+	})
 
 	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納)
 	if err != nil {
-		r.msg.bp.dbError(err)
+		r.msg.bp.dbError(ctx, err)
 	}
 }
 
 // FUNCTION: #3-01(MODIFY): order_dateが日付のフォーマットに合致しない場合は、"20250101"にクレンジングする。
+// 個別指定値(受注番号単位)が存在する場合は、個別指定値にクレンジングする。
 func (r *OrderRecord) checkOrderDate(ctx infra.AppCtx) {
 	const ID = "#3-01"
 	const ORDER_DATE = "20250101"
 	orderDate := r.record.OrderDate
 	_, err := time.Parse(ctx.DateLayout, orderDate)
 	if err != nil {
+		// PROCESS: 個別指定値が存在する場合(日付フォーマットではない場合は固定値)
+		value, exist := ctx.Overrides.Lookup(ID, strconv.Itoa(r.record.OrderNo))
+		if _, err := time.Parse(ctx.DateLayout, value); exist && err == nil {
+			r.record.OrderDate = value
+			r.msg.bp.overridden().changed(legacy.OrderColumns.OrderDate, orderDate, r.record.OrderDate, ID).addMessage(
+				fmt.Sprintf("order_date(受注日付) が日付フォーマットではありません`%s`。<br>【クレンジング】`%s`(個別指定値) にクレンジング。", orderDate, value), ID)
+			return
+		}
+
 		r.record.OrderDate = ORDER_DATE
-		r.msg.bp.modified().addMessage(
-			fmt.Sprintf("order_date(受注日付) が日付フォーマットではありません`%s`。<br>【クレンジング】`%s`(固定値) にクレンジング。", orderDate, ORDER_DATE), ID)
+		r.msg.bp.modified().changed(legacy.OrderColumns.OrderDate, orderDate, r.record.OrderDate, ID).addMessage(
+			fmt.Sprintf("order_date(受注日付) が日付フォーマットではありません`%s`。<br>【クレンジング】`%s`(固定値) にクレンジング。%s", orderDate, ORDER_DATE, invalidOverrideMsg(value, exist)), ID)
 	}
 }
 
 // FUNCTION: #3-02(MODIFY): order_picが[担当者]に存在しない場合は、"N/A"にクレンジングする。
-func (r *OrderRecord) checkOrderPic(OperatorNameSet map[string]struct{}) {
+// 個別指定値(受注番号単位)が存在する場合は個別指定値に、承認済みのマッピング定義が存在する場合はマッピング先の値にクレンジングする。
+func (r *OrderRecord) checkOrderPic(ctx infra.AppCtx, refData *RefData) {
 	const ID = "#3-02"
 	const ORDER_PIC = "N/A"
 	orderPic := r.record.OrderPic
-	_, exist := OperatorNameSet[orderPic]
-	if !exist {
-		r.record.OrderPic = ORDER_PIC
-		r.msg.bp.modified().addMessage(
-			fmt.Sprintf("order_pic(受注担当者名) が[担当者]として存在しません`%s`。<br>【クレンジング】`%s`(固定値) にクレンジング。", orderPic, ORDER_PIC), ID)
+	_, exist := refData.OperatorNameSet[orderPic]
+	if exist {
+		return
+	}
+
+	// PROCESS: 個別指定値が存在する場合(担当者に存在しない場合はマッピング定義/固定値)
+	value, overridden := ctx.Overrides.Lookup(ID, strconv.Itoa(r.record.OrderNo))
+	if _, valid := refData.OperatorNameSet[value]; overridden && valid {
+		r.record.OrderPic = value
+		r.msg.bp.overridden().changed(legacy.OrderColumns.OrderPic, orderPic, r.record.OrderPic, ID).addMessage(
+			fmt.Sprintf("order_pic(受注担当者名) が[担当者]として存在しません`%s`。<br>【クレンジング】`%s`(個別指定値) にクレンジング。", orderPic, value), ID)
+		return
 	}
+
+	// PROCESS: マッピング定義(承認済み)が存在する場合はマッピング先に変換
+	entry, mapped := lookupMapping(refData.Mapping.OperatorName, orderPic)
+	if _, valid := refData.OperatorNameSet[entry.Canonical]; mapped && entry.Approved && valid {
+		r.record.OrderPic = entry.Canonical
+		r.msg.bp.modified().changed(legacy.OrderColumns.OrderPic, orderPic, r.record.OrderPic, ID).addMessage(
+			fmt.Sprintf("order_pic(受注担当者名) が[担当者]として存在しません`%s`。<br>【クレンジング】`%s`(マッピング定義) にクレンジング。%s", orderPic, entry.Canonical, invalidOverrideMsg(value, overridden)), ID)
+		return
+	}
+
+	r.record.OrderPic = ORDER_PIC
+	r.msg.bp.modified().changed(legacy.OrderColumns.OrderPic, orderPic, r.record.OrderPic, ID).addMessage(
+		fmt.Sprintf("order_pic(受注担当者名) が[担当者]として存在しません`%s`。<br>【クレンジング】`%s`(固定値) にクレンジング。%s%s", orderPic, ORDER_PIC, invalidOverrideMsg(value, overridden), mappingMsg(entry, mapped, suggest(orderPic, refData.OperatorNameSet, SUGGEST_LIMIT))), ID)
 }
 
 // STRUCT: コマンド
@@ -130,19 +218,23 @@ func (cmd *OrdersCmd) getTableInfo() TableInfo {
 }
 
 // FUNCTION: 入力データ量
-func (cmd *OrdersCmd) entryCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := legacy.Orders().Count(ctx.Ctx, con)
+func (cmd *OrdersCmd) entryCount(ctx infra.AppCtx, reader LegacyReader, subset *Subset) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return reader.Count(c, legacy.TableNames.Orders, subset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
 
 // FUNCTION: 処理対象レコードのフェッチ
-func (cmd *OrdersCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm.QueryMod) []Record {
-	records, err := legacy.Orders(qmArray...).All(ctx.Ctx, con)
+func (cmd *OrdersCmd) fetchRecords(ctx infra.AppCtx, reader LegacyReader, subset *Subset, limit int, offset int) []Record {
+	records, err := infra.Retry(ctx, func(c context.Context) (legacy.OrderSlice, error) {
+		return reader.FetchOrders(c, subset, limit, offset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 
 	results := make([]Record, len(records))
@@ -157,7 +249,7 @@ func (cmd *OrdersCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm.Q
 }
 
 // FUNCTION: 追加データ登録
-func (r *OrdersCmd) extInsert(ctx infra.AppCtx, db *sql.DB, refData *RefData) {}
+func (r *OrdersCmd) extInsert(ctx infra.AppCtx, writer CleanWriter, refData *RefData) {}
 
 // FUNCTION: 詳細メッセージの出力
 func (cmd *OrdersCmd) showDetails(ctx infra.AppCtx, tableName string) string {
@@ -173,7 +265,7 @@ func (cmd *OrdersCmd) showDetails(ctx infra.AppCtx, tableName string) string {
 		msg += fmt.Sprintf("  | %d | %d | … | %s | %s | %s |\n",
 			i+1,
 			piece.OrderNo,
-			piece.bp.status,
+			piece.bp.result(),
 			piece.bp.approve,
 			piece.bp.msg,
 		)
@@ -4,15 +4,12 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package cleansing
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
 
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
-	"github.com/volatiletech/sqlboiler/v4/boil"
-	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 // STRUCT: 詳細メッセージ
@@ -39,21 +36,57 @@ type OrderDetailRecord struct {
 }
 
 // FUNCTION: 更新
-func (r *OrderDetailRecord) checkAndPersist(ctx infra.AppCtx, db *sql.DB, refData *RefData) Piece {
+func (r *OrderDetailRecord) checkAndPersist(ctx infra.AppCtx, writer CleanWriter, refData *RefData) Piece {
+
+	// PROCESS: 再登録データ(承認済みの隔離データ)の適用(再登録による変更を記録)
+	fetched := r.record
+	reinstated := r.applyReinstate(refData)
+	origin := r.record
+	r.msg.bp.diffChanged(QUARANTINE_TABLES[legacy.TableNames.OrderDetails], r.values(fetched), r.values(origin), "")
 
 	// PROCESS: check #4-01:
-	r.checkShippingAndCanceledFlag()
+	if ctx.RuleEnabled("#4-01") {
+		r.checkShippingAndCanceledFlag()
+	}
 
 	// PROCESS:INFO: check #4-02:
-	r.checkOrderNo(refData.OrderNoSet)
+	if ctx.RuleEnabled("#4-02") {
+		r.checkOrderNo(refData.OrderNoSet)
+	}
 
 	// PROCESS: check #4-03:
-	// TODO: クレンジング処理未記載の状況際限のためコメントアウト
-	// r.checkProductName(refData.ProductNameSet)
+	// INFO: クレンジング処理未記載の状況再現のため既定で無効(DISABLED_RULESの既定値、空を指定した場合に有効)
+	if ctx.RuleEnabled("#4-03") {
+		r.checkProductName(refData)
+	}
+
+	// PROCESS: 承認データ(レビューブックで承認済みのルール)の適用
+	r.msg.bp.applyApproval(refData.Approvals.lookup(legacy.TableNames.OrderDetails, r.legacyKey()))
+
+	// PROCESS: 再登録データの場合は除外しない
+	if reinstated {
+		r.msg.bp.reinstated()
+	}
+
+	// PROCESS: 移行元/クレンジング後の値(レビュー用)
+	r.msg.bp.setValues(r.values(fetched), r.values(r.record))
 
 	// PROCESS: REMOVE判定時は登録なし
 	if !r.msg.bp.isRemove() {
-		r.persiste(ctx, db)
+		r.persiste(ctx, writer)
+	}
+
+	// PROCESS: 中断時(SQLのタイムアウト/キャンセル)は変更履歴/隔離データを登録しない
+	if r.msg.bp.interrupted {
+		return *r.msg.bp
+	}
+
+	// PROCESS: MODIFY判定時(登録エラーを除く)は変更履歴を登録
+	changeLog(ctx, writer, legacy.TableNames.OrderDetails, r.legacyKey(), r.msg.bp)
+
+	// PROCESS: REMOVE判定時(登録エラーを含む)は隔離データとして登録
+	if r.msg.bp.isRemove() {
+		quarantine(ctx, writer, legacy.TableNames.OrderDetails, r.legacyKey(), r.values(origin), r.msg.bp)
 	}
 
 	// PROCESS: REMOVE/MODIFY判定時は詳細情報の出力あり
@@ -64,8 +97,35 @@ func (r *OrderDetailRecord) checkAndPersist(ctx infra.AppCtx, db *sql.DB, refDat
 	return *r.msg.bp
 }
 
+// FUNCTION: 移行元キー
+func (r *OrderDetailRecord) legacyKey() string {
+	return fmt.Sprintf("%d-%d", r.msg.OrderNo, r.msg.OrderDetailNo)
+}
+
+// FUNCTION: カラム値(QUARANTINE_TABLESのカラム順)
+func (r *OrderDetailRecord) values(rec legacy.OrderDetail) []any {
+	return []any{rec.OrderNo, rec.OrderDetailNo, rec.ProductName, rec.ReceivingQuantity, rec.ShippingFlag, rec.CanceledFlag, rec.SellingPrice, rec.CostPrice}
+}
+
+// FUNCTION: 再登録データの適用
+func (r *OrderDetailRecord) applyReinstate(refData *RefData) bool {
+	values, exist := refData.Reinstate.lookup(legacy.TableNames.OrderDetails, r.legacyKey())
+	if !exist {
+		return false
+	}
+	r.record.OrderNo = reinstateInt(values, legacy.OrderDetailColumns.OrderNo, r.record.OrderNo)
+	r.record.OrderDetailNo = reinstateInt(values, legacy.OrderDetailColumns.OrderDetailNo, r.record.OrderDetailNo)
+	r.record.ProductName = reinstateStr(values, legacy.OrderDetailColumns.ProductName, r.record.ProductName)
+	r.record.ReceivingQuantity = reinstateInt(values, legacy.OrderDetailColumns.ReceivingQuantity, r.record.ReceivingQuantity)
+	r.record.ShippingFlag = reinstateBool(values, legacy.OrderDetailColumns.ShippingFlag, r.record.ShippingFlag)
+	r.record.CanceledFlag = reinstateBool(values, legacy.OrderDetailColumns.CanceledFlag, r.record.CanceledFlag)
+	r.record.SellingPrice = reinstateInt(values, legacy.OrderDetailColumns.SellingPrice, r.record.SellingPrice)
+	r.record.CostPrice = reinstateInt(values, legacy.OrderDetailColumns.CostPrice, r.record.CostPrice)
+	return true
+}
+
 // FUNCTION: データ登録
-func (r *OrderDetailRecord) persiste(ctx infra.AppCtx, db *sql.DB) {
+func (r *OrderDetailRecord) persiste(ctx infra.AppCtx, writer CleanWriter) {
 	// INFO: 受注番号の採番
 	wOrderNo := r.orderNoGen.generate(r.record.OrderNo, r.record.ProductName, r.record.SellingPrice, r.record.CostPrice)
 
@@ -83,11 +143,13 @@ func (r *OrderDetailRecord) persiste(ctx infra.AppCtx, db *sql.DB) {
 		CreatedBy:         ctx.OperationUser,
 		UpdatedBy:         ctx.OperationUser,
 	}
-	err := rec.Insert(ctx.Ctx, db, boil.Infer())
+	err := infra.RetryInsert(ctx, func(c context.Context) error {
+		return writer.InsertOrderDetail(c, rec)
+	})
 
 	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納)
 	if err != nil {
-		r.msg.bp.dbError(err)
+		r.msg.bp.dbError(ctx, err)
 	}
 }
 
@@ -105,19 +167,32 @@ func (r *OrderDetailRecord) checkOrderNo(OrderNoSet map[int]struct{}) {
 	const ID = "#4-02"
 	_, exist := OrderNoSet[r.record.OrderNo]
 	if !exist {
-		r.msg.bp.approveStay() //TODO: 承認確認中
+		r.msg.bp.approveStay(ID) //TODO: 承認確認中
 		r.msg.bp.removed().addMessage("order_no(受注番号) が[受注]に存在しません。【除外】", ID)
 	}
 }
 
 // FUNCTION: #4-03(REMOVE): product_nameが[商品]に存在しない場合、移行対象から除外する。
-func (r *OrderDetailRecord) checkProductName(ProductNameSet map[string]struct{}) {
+// 承認済みのマッピング定義が存在する場合は、マッピング先の値にクレンジングする。
+func (r *OrderDetailRecord) checkProductName(refData *RefData) {
 	const ID = "#4-03"
 	productName := r.record.ProductName
-	_, exist := ProductNameSet[productName]
-	if !exist {
-		r.msg.bp.removed().addMessage(fmt.Sprintf("product_name(商品名) が[商品]に存在しません`%s`。【除外】", productName), ID)
+	_, exist := refData.ProductNameSet[productName]
+	if exist {
+		return
 	}
+
+	// PROCESS: マッピング定義(承認済み)が存在する場合はマッピング先に変換
+	entry, mapped := lookupMapping(refData.Mapping.ProductName, productName)
+	if _, valid := refData.ProductNameSet[entry.Canonical]; mapped && entry.Approved && valid {
+		r.record.ProductName = entry.Canonical
+		r.msg.bp.modified().changed(legacy.OrderDetailColumns.ProductName, productName, r.record.ProductName, ID).addMessage(
+			fmt.Sprintf("product_name(商品名) が[商品]に存在しません`%s`。<br>【クレンジング】`%s`(マッピング定義) にクレンジング。", productName, entry.Canonical), ID)
+		return
+	}
+
+	r.msg.bp.removed().addMessage(
+		fmt.Sprintf("product_name(商品名) が[商品]に存在しません`%s`。【除外】%s", productName, mappingMsg(entry, mapped, suggest(productName, refData.ProductNameSet, SUGGEST_LIMIT))), ID)
 }
 
 // STRUCT: コマンド
@@ -140,19 +215,23 @@ func (cmd *OrderDetailsCmd) getTableInfo() TableInfo {
 }
 
 // FUNCTION: 入力データ量
-func (cmd *OrderDetailsCmd) entryCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := legacy.OrderDetails().Count(ctx.Ctx, con)
+func (cmd *OrderDetailsCmd) entryCount(ctx infra.AppCtx, reader LegacyReader, subset *Subset) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return reader.Count(c, legacy.TableNames.OrderDetails, subset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
 
 // FUNCTION: 処理対象レコードのフェッチ
-func (cmd *OrderDetailsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm.QueryMod) []Record {
-	records, err := legacy.OrderDetails(qmArray...).All(ctx.Ctx, con)
+func (cmd *OrderDetailsCmd) fetchRecords(ctx infra.AppCtx, reader LegacyReader, subset *Subset, limit int, offset int) []Record {
+	records, err := infra.Retry(ctx, func(c context.Context) (legacy.OrderDetailSlice, error) {
+		return reader.FetchOrderDetails(c, subset, limit, offset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 
 	results := make([]Record, len(records))
@@ -168,7 +247,7 @@ func (cmd *OrderDetailsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray
 }
 
 // FUNCTION: 追加データ登録
-func (r *OrderDetailsCmd) extInsert(ctx infra.AppCtx, db *sql.DB, refData *RefData) {}
+func (r *OrderDetailsCmd) extInsert(ctx infra.AppCtx, writer CleanWriter, refData *RefData) {}
 
 // FUNCTION: 詳細メッセージの出力
 func (cmd *OrderDetailsCmd) showDetails(ctx infra.AppCtx, tableName string) string {
@@ -185,7 +264,7 @@ func (cmd *OrderDetailsCmd) showDetails(ctx infra.AppCtx, tableName string) stri
 			i+1,
 			piece.OrderNo,
 			piece.OrderDetailNo,
-			piece.bp.status,
+			piece.bp.result(),
 			piece.bp.approve,
 			piece.bp.msg,
 		)
@@ -0,0 +1,162 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/xuri/excelize/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// TITLE: 承認データ(レビューブックで承認された指摘)
+
+// STRUCT: 承認データ(テーブル名 → 移行元キー → 承認内容)
+type ApprovalStore map[string]map[string]Approval
+
+// STRUCT: 承認内容(承認したルールID/承認者/コメント)
+type Approval struct {
+	Rules      []string `yaml:"rules"`
+	ApprovedBy string   `yaml:"approved_by,omitempty"`
+	Comment    string   `yaml:"comment,omitempty"`
+}
+
+// FUNCTION: 承認内容の表示
+func (a Approval) label() string {
+	msg := strings.Join(a.Rules, ",")
+	if a.ApprovedBy != "" {
+		msg += fmt.Sprintf(" by %s", a.ApprovedBy)
+	}
+	if a.Comment != "" {
+		msg += fmt.Sprintf("(%s)", a.Comment)
+	}
+	return msg
+}
+
+// FUNCTION: 承認内容の追加(承認済みのルールIDに追加、承認者/コメントは入力がある場合のみ更新)
+func (a Approval) merge(approval Approval) Approval {
+	rules := append([]string{}, a.Rules...)
+	for _, id := range approval.Rules {
+		if id = strings.TrimSpace(id); id != "" && !slices.Contains(rules, id) {
+			rules = append(rules, id)
+		}
+	}
+	a.Rules = rules
+	if approval.ApprovedBy != "" {
+		a.ApprovedBy = approval.ApprovedBy
+	}
+	if approval.Comment != "" {
+		a.Comment = approval.Comment
+	}
+	return a
+}
+
+// FUNCTION: 承認データの読込み(ファイルが存在しない場合は空)
+func LoadApprovalStore(filePath string) (ApprovalStore, error) {
+	store := ApprovalStore{}
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read approval file: %s", err.Error())
+	}
+
+	if err := yaml.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("cannot parse approval file[%s]: %s", filePath, err.Error())
+	}
+	return store, nil
+}
+
+// FUNCTION: 承認データの検索
+func (s ApprovalStore) lookup(table string, legacyKey string) (Approval, bool) {
+	rows, exist := s[table]
+	if !exist {
+		return Approval{}, false
+	}
+	approval, exist := rows[legacyKey]
+	return approval, exist
+}
+
+// FUNCTION: 承認データの保存
+func (s ApprovalStore) save(filePath string) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return infra.WriteText(filePath, string(data))
+}
+
+// FUNCTION: 承認の取込み(レビューブックのAPPROVE列が`OK`の行を承認データに追加する)
+// INFO: 承認対象は行の承認待ちルール(pending_rule_ids)、承認待ちのない行は対象外。承認済みのルールは残す
+func ImportApprovals(bookFile string, filePath string) (map[string]int, error) {
+	store, err := LoadApprovalStore(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := excelize.OpenFile(bookFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open review book: %s", err.Error())
+	}
+	defer book.Close()
+
+	counts := map[string]int{}
+	for _, table := range REVIEW_TABLES {
+		if !slices.Contains(book.GetSheetList(), table) {
+			continue
+		}
+		rows, err := book.GetRows(table)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read review sheet[%s]: %s", table, err.Error())
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		// PROCESS: ヘッダーから列位置を特定
+		col := func(name string) int { return slices.Index(rows[0], name) }
+		keyCol, pendingCol, approveCol := col(REVIEW_KEY), col(REVIEW_PENDING), col(REVIEW_APPROVE)
+		if keyCol < 0 || pendingCol < 0 || approveCol < 0 {
+			return nil, fmt.Errorf("cannot find review columns in sheet[%s]", table)
+		}
+		cell := func(row []string, i int) string {
+			if i < 0 || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		for _, row := range rows[1:] {
+			if !strings.EqualFold(cell(row, approveCol), APPROVE_OK) {
+				continue
+			}
+			pending := cell(row, pendingCol)
+			if pending == "" {
+				continue
+			}
+
+			if _, exist := store[table]; !exist {
+				store[table] = map[string]Approval{}
+			}
+			key := cell(row, keyCol)
+			store[table][key] = store[table][key].merge(Approval{
+				Rules:      strings.Split(pending, ","),
+				ApprovedBy: cell(row, col(REVIEW_APPROVED_BY)),
+				Comment:    cell(row, col(REVIEW_COMMENT)),
+			})
+			counts[table]++
+		}
+	}
+
+	if err := store.save(filePath); err != nil {
+		return nil, err
+	}
+	log.Printf("approval data saved [%s]\n", filePath)
+	return counts, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+	"github.com/xuri/excelize/v2"
+)
+
+// TITLE: 承認データ(レビューブックの出力/承認の取込み)
+
+// STRUCT: レビューブックの承認入力(テーブル/移行元キー/承認者)
+type approveInput struct {
+	table      string
+	legacyKey  string
+	approvedBy string
+}
+
+// FUNCTION: レビューブックの出力→承認の入力→承認の取込み(承認済みのルールは次回の取込みでも残す)
+func TestImportApprovals(t *testing.T) {
+	finding := func(table string, legacyKey string, pending ...string) Finding {
+		return Finding{Table: table, LegacyKey: legacyKey, Result: string(MODIFY), RuleIds: pending, Pending: pending}
+	}
+	tests := []struct {
+		name   string
+		rounds [][]Finding      //クレンジング毎の指摘
+		inputs [][]approveInput //クレンジング毎の承認入力
+		want   ApprovalStore
+		counts []map[string]int
+	}{
+		{
+			name:   "approve pending rules",
+			rounds: [][]Finding{{finding(legacy.TableNames.Operators, "AB1", "#1-02"), finding(legacy.TableNames.Orders, "1001", "#3-01", "#3-02")}},
+			inputs: [][]approveInput{{{legacy.TableNames.Orders, "1001", "suzuki"}}},
+			want: ApprovalStore{
+				legacy.TableNames.Orders: {"1001": {Rules: []string{"#3-01", "#3-02"}, ApprovedBy: "suzuki"}},
+			},
+			counts: []map[string]int{{legacy.TableNames.Orders: 1}},
+		},
+		{
+			name: "merge with earlier approvals of the same record",
+			rounds: [][]Finding{
+				{finding(legacy.TableNames.Orders, "1001", "#3-01")},
+				{finding(legacy.TableNames.Orders, "1001", "#3-02")},
+			},
+			inputs: [][]approveInput{
+				{{legacy.TableNames.Orders, "1001", "suzuki"}},
+				{{legacy.TableNames.Orders, "1001", ""}},
+			},
+			want: ApprovalStore{
+				legacy.TableNames.Orders: {"1001": {Rules: []string{"#3-01", "#3-02"}, ApprovedBy: "suzuki"}},
+			},
+			counts: []map[string]int{{legacy.TableNames.Orders: 1}, {legacy.TableNames.Orders: 1}},
+		},
+		{
+			name:   "row without pending rules",
+			rounds: [][]Finding{{finding(legacy.TableNames.OrderDetails, "1001-1")}},
+			inputs: [][]approveInput{{{legacy.TableNames.OrderDetails, "1001-1", "suzuki"}}},
+			want:   ApprovalStore{},
+			counts: []map[string]int{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			approvalFile := filepath.Join(dir, "approvals.yaml")
+			for i, findings := range tt.rounds {
+				bookFile := filepath.Join(dir, "cleansing-review.xlsx")
+				if err := (Report{Findings: findings}).WriteReviewBook(bookFile); err != nil {
+					t.Fatal(err)
+				}
+				for _, input := range tt.inputs[i] {
+					approve(t, bookFile, input)
+				}
+				counts, err := ImportApprovals(bookFile, approvalFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !maps.Equal(counts, tt.counts[i]) {
+					t.Errorf("round %d counts: got %v, want %v", i+1, counts, tt.counts[i])
+				}
+			}
+
+			// PROCESS: 保存した承認データ
+			store, err := LoadApprovalStore(approvalFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for table, rows := range tt.want {
+				for key, want := range rows {
+					got, exist := store.lookup(table, key)
+					if !exist || !slices.Equal(got.Rules, want.Rules) || got.ApprovedBy != want.ApprovedBy || got.Comment != want.Comment {
+						t.Errorf("%s[%s]: got %+v, want %+v", table, key, got, want)
+					}
+				}
+			}
+			if len(store) != len(tt.want) {
+				t.Errorf("tables: got %v, want %v", store, tt.want)
+			}
+		})
+	}
+}
+
+// FUNCTION: レビューブックの承認入力(APPROVE列に`OK`、approved_by列に承認者)
+func approve(t *testing.T, bookFile string, input approveInput) {
+	t.Helper()
+	book, err := excelize.OpenFile(bookFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer book.Close()
+
+	rows, err := book.GetRows(input.table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyCol, approveCol, approvedByCol := slices.Index(rows[0], REVIEW_KEY), slices.Index(rows[0], REVIEW_APPROVE), slices.Index(rows[0], REVIEW_APPROVED_BY)
+	for i, row := range rows[1:] {
+		if row[keyCol] != input.legacyKey {
+			continue
+		}
+		if err := book.SetCellValue(input.table, cellName(approveCol+1, i+2), APPROVE_OK); err != nil {
+			t.Fatal(err)
+		}
+		if err := book.SetCellValue(input.table, cellName(approvedByCol+1, i+2), input.approvedBy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := book.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+)
+
+// TITLE: 変更履歴(MODIFY判定レコードのカラム毎の変更)
+
+// STRUCT: 変更履歴テーブル
+const CHANGE_LOG_TABLE = "change_log"
+
+// STRUCT: 再登録による変更のルールID(表示用)
+const REINSTATE_RULE = "REINSTATE"
+
+// STRUCT: カラム毎の変更(ルールIDが空の場合は再登録データによる変更)
+type Change struct {
+	Column   string `json:"column"`
+	Original string `json:"original"`
+	Cleansed string `json:"cleansed"`
+	RuleId   string `json:"rule_id"`
+}
+
+// FUNCTION: ルールIDの表示
+func (c Change) RuleLabel() string {
+	if c.RuleId == "" {
+		return REINSTATE_RULE
+	}
+	return c.RuleId
+}
+
+// FUNCTION: 変更の記録(値が同じ場合は記録しない)
+func (p *Piece) changed(column string, original any, cleansed any, id string) *Piece {
+	change := Change{Column: column, Original: fmt.Sprint(original), Cleansed: fmt.Sprint(cleansed), RuleId: id}
+	if change.Original != change.Cleansed {
+		p.changes = append(p.changes, change)
+	}
+	return p
+}
+
+// FUNCTION: 変更の記録(カラム値の差分、QUARANTINE_TABLESのカラム順)
+func (p *Piece) diffChanged(columns []string, original []any, cleansed []any, id string) *Piece {
+	for i, column := range columns {
+		if i < len(original) && i < len(cleansed) {
+			p.changed(column, original[i], cleansed[i], id)
+		}
+	}
+	return p
+}
+
+// FUNCTION: 登録した変更履歴(MODIFY判定時のみ)
+func (p *Piece) changeLog() []Change {
+	if p.status != MODIFY {
+		return []Change{}
+	}
+	return append([]Change{}, p.changes...)
+}
+
+// FUNCTION: 変更履歴の登録
+func changeLog(ctx infra.AppCtx, writer CleanWriter, table string, legacyKey string, p *Piece) {
+	changes := p.changeLog()
+	if len(changes) == 0 {
+		return
+	}
+	row := ChangeLogRow{
+		Table:         table,
+		LegacyKey:     legacyKey,
+		Changes:       changes,
+		OperationUser: ctx.OperationUser,
+	}
+
+	// PROCESS: 登録に失敗した場合は、DB確認の対象
+	// INFO: 変更履歴IDは採番のため、リトライすると重複登録となる場合がある(リトライしない)
+	stmtCtx, cancel := ctx.WithStatementTimeout()
+	defer cancel()
+	if err := writer.LogChanges(stmtCtx.Ctx, row); err != nil {
+		p.dbCheck(ctx, err, "変更履歴の登録に失敗しました。")
+	}
+}
+
+// STRUCT: 変更履歴の集計(テーブル/カラム/ルール毎)
+type ChangeStat struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	RuleId string `json:"rule_id"`
+	Count  int    `json:"count"`
+}
+
+// FUNCTION: 変更履歴の集計(指摘の出現順)
+func (r Report) ChangeStats() []ChangeStat {
+	stats := []ChangeStat{}
+	for _, finding := range r.Findings {
+		for _, change := range finding.Changes {
+			i := slices.IndexFunc(stats, func(stat ChangeStat) bool {
+				return stat.Table == finding.Table && stat.Column == change.Column && stat.RuleId == change.RuleLabel()
+			})
+			if i < 0 {
+				stats = append(stats, ChangeStat{Table: finding.Table, Column: change.Column, RuleId: change.RuleLabel()})
+				i = len(stats) - 1
+			}
+			stats[i].Count++
+		}
+	}
+	return stats
+}
+
+// FUNCTION: 集計の表示
+func showChangeStats(ctx infra.AppCtx, stats []ChangeStat) string {
+	msg := "\n## Cleansing Change Log\n\n"
+	if len(stats) == 0 {
+		msg += "  no changes.\n"
+		return msg
+	}
+	msg += fmt.Sprintf("  all changes are registered in `%s.%s`.\n\n", CLEAN_SCHEMA, CHANGE_LOG_TABLE)
+	msg += "  | TABLE | COLUMN | RULE | CHANGES |\n"
+	msg += "  |---|---|---|--:|\n"
+	for _, stat := range stats {
+		msg += fmt.Sprintf("  | %s | %s | %s | %s |\n",
+			stat.Table,
+			stat.Column,
+			stat.RuleId,
+			ctx.Printer.Sprintf("%d", stat.Count),
+		)
+	}
+	return msg
+}
@@ -4,7 +4,10 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package cleansing
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"slices"
 
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 )
@@ -25,11 +28,22 @@ const APPROVED Approve = "✅"
 const STAY Approve = ""
 const NOT_FINDED Approve = "🔰<br>CHECK!"
 
+// STRUCT: 個別指定値の適用あり
+const OVERRIDE string = "<br>(OVERRIDE)"
+
 // STRUCT: クレンジング後のメッセージを管理
 type Piece struct {
-	status  Status
-	approve Approve
-	msg     string
+	status      Status
+	approve     Approve
+	msg         string
+	override    bool
+	ruleIds     []string
+	pendingIds  []string
+	messages    []string
+	original    []any
+	cleansed    []any
+	changes     []Change
+	interrupted bool
 }
 
 // FUNCTION:
@@ -61,18 +75,93 @@ func (p *Piece) modified() *Piece {
 	return p
 }
 
-// FUNCTION: DBエラー
-func (p *Piece) dbError(err error) {
+// FUNCTION: クレンジング(個別指定値を適用)
+func (p *Piece) overridden() *Piece {
+	p.override = true
+	return p.modified()
+}
+
+// FUNCTION: 結果の表示(個別指定値を適用した場合は併記する)
+func (p *Piece) result() string {
+	if p.override {
+		return string(p.status) + OVERRIDE
+	}
+	return string(p.status)
+}
+
+// FUNCTION: 再登録(隔離データの承認済み)の場合は、除外せずに登録する
+func (p *Piece) reinstated() *Piece {
+	if p.status == REMOVE {
+		p.status = MODIFY
+	}
+	p.modified()
+	p.approve = APPROVED
+	p.pendingIds = nil
+	return p.addMessage("【再登録】承認済みの隔離データの値で登録", "")
+}
+
+// FUNCTION: SQLのタイムアウト/キャンセルの場合は中断(指摘として扱わない)
+func (p *Piece) interruptIf(ctx infra.AppCtx, err error) bool {
+	if ctx.InterruptIf(err) {
+		p.interrupted = true
+	}
+	return p.interrupted
+}
+
+// FUNCTION: DBエラー(SQLのタイムアウト/キャンセルの場合は中断)
+func (p *Piece) dbError(ctx infra.AppCtx, err error) {
+	if p.interruptIf(ctx, err) {
+		return
+	}
 	p.removed()
 	p.approve = NOT_FINDED
 	p.addMessage(fmt.Sprintf("<span style=\"color:red;\">%v</span>", err), "")
 }
 
-// FUNCTION: 承認待ち
-func (p *Piece) approveStay() *Piece {
+// FUNCTION: 登録後のDBエラー(変更履歴/隔離データ、SQLのタイムアウト/キャンセルの場合は中断)
+// INFO: レコードの登録結果は変わらないため判定はそのままとし、DB確認の対象(🔰CHECK!)とする
+func (p *Piece) dbCheck(ctx infra.AppCtx, err error, msg string) {
+	if p.interruptIf(ctx, err) {
+		return
+	}
+	p.approve = NOT_FINDED
+	p.addMessage(fmt.Sprintf("<span style=\"color:red;\">%s%v</span>", msg, err), "")
+}
+
+// FUNCTION: 承認待ち(承認待ちのルールIDを記録する)
+func (p *Piece) approveStay(id string) *Piece {
 	if p.approve == APPROVED {
 		p.approve = STAY
 	}
+	p.pendingIds = append(p.pendingIds, id)
+	return p
+}
+
+// FUNCTION: 承認(レビューブックで承認済みのルールを承認待ちから除く)
+func (p *Piece) applyApproval(approval Approval, exist bool) *Piece {
+	if !exist || len(p.pendingIds) == 0 {
+		return p
+	}
+	pendingIds := []string{}
+	for _, id := range p.pendingIds {
+		if !slices.Contains(approval.Rules, id) {
+			pendingIds = append(pendingIds, id)
+		}
+	}
+	if len(pendingIds) == len(p.pendingIds) {
+		return p
+	}
+	p.pendingIds = pendingIds
+	if len(pendingIds) == 0 && p.approve == STAY {
+		p.approve = APPROVED
+	}
+	return p.addMessage(fmt.Sprintf("【承認】%s", approval.label()), "")
+}
+
+// FUNCTION: 移行元/クレンジング後の値(QUARANTINE_TABLESのカラム順)
+func (p *Piece) setValues(original []any, cleansed []any) *Piece {
+	p.original = original
+	p.cleansed = cleansed
 	return p
 }
 
@@ -87,22 +176,44 @@ func (p *Piece) addMessage(msg string, id string) *Piece {
 		_id = fmt.Sprintf("[%s]", id)
 	}
 	p.msg += fmt.Sprintf("%s● %s %s", br, _id, msg)
+
+	// INFO: 隔離データ用(ルールID/メッセージ)
+	if id != "" {
+		p.ruleIds = append(p.ruleIds, id)
+	}
+	p.messages = append(p.messages, fmt.Sprintf("%s %s", _id, msg))
 	return p
 }
 
+// FUNCTION: 個別指定値が不正な場合のメッセージ
+func invalidOverrideMsg(value string, exist bool) string {
+	if !exist {
+		return ""
+	}
+	return fmt.Sprintf("<br>【個別指定値(不正のため未適用)】`%s`", value)
+}
+
 // STRUCT: リファレンスデータ
 type RefData struct {
+	OperatorIdSet   map[string]struct{} //担当者ID
 	OperatorNameSet map[string]struct{} //担当者名
 	ProductNameSet  map[string]struct{} //商品名
 	OrderNoSet      map[int]struct{}    //受注番号
+	Mapping         *NameMapping        //マッピング定義
+	Reinstate       ReinstateStore      //再登録データ
+	Approvals       ApprovalStore       //承認データ
 }
 
 // FUNCTION: リファレンスデータの作成
-func NewRefData() *RefData {
+func NewRefData(mapping *NameMapping, reinstate ReinstateStore, approvals ApprovalStore) *RefData {
 	return &RefData{
+		OperatorIdSet:   map[string]struct{}{},
 		OperatorNameSet: map[string]struct{}{},
 		ProductNameSet:  map[string]struct{}{},
 		OrderNoSet:      map[int]struct{}{},
+		Mapping:         mapping,
+		Reinstate:       reinstate,
+		Approvals:       approvals,
 	}
 }
 
@@ -110,30 +221,81 @@ func NewRefData() *RefData {
 type Controller struct {
 	num     int
 	ctx     infra.AppCtx
-	conns   infra.DbConnection
+	reader  LegacyReader
+	writer  CleanWriter
 	refData *RefData
+	subset  *Subset
+	report  *Report
 }
 
-// FUNCTION:
-func New(conns infra.DbConnection) *Controller {
+// FUNCTION: (subsetがnilの場合は全件)
+func New(run context.Context, config infra.Config, conns infra.DbConnection, subset *Subset) *Controller {
+	// PROCESS: マッピングファイルの読込み
+	mapping, err := LoadNameMapping(config.Base.MappingFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// PROCESS: 個別指定値の読込み
+	overrides, err := infra.LoadOverrides(config.Base.OverrideDir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// PROCESS: 再登録データの読込み
+	reinstate, err := LoadReinstateStore(config.ReinstateFile())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// PROCESS: 承認データの読込み
+	approvals, err := LoadApprovalStore(config.ApprovalFile())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx := infra.NewCtx(run, config.Run, config.Run.CleansingStatementTimeout, overrides)
+	return NewController(ctx, NewLegacyReader(conns.LegacyDB), NewCleanWriter(conns.WorkDB), NewRefData(mapping, reinstate, approvals), subset)
+}
+
+// FUNCTION: リポジトリを指定して生成(インメモリリポジトリによる検証用)
+func NewController(ctx infra.AppCtx, reader LegacyReader, writer CleanWriter, refData *RefData, subset *Subset) *Controller {
 	return &Controller{
 		num:     0,
-		ctx:     infra.NewCtx(),
-		conns:   conns,
-		refData: NewRefData(),
+		ctx:     ctx,
+		reader:  reader,
+		writer:  writer,
+		refData: refData,
+		subset:  subset,
+		report:  &Report{Tables: []TableResult{}, Rules: newRuleStats(ctx), Findings: []Finding{}},
 	}
 }
 
 // FUNCTION: インボーカーの生成
 func (c *Controller) CreateInvocer(cmd Command) *Invoker {
 	c.num++
-	return NewInvoker(c.num, c.ctx, c.conns, c.refData, cmd)
+	return NewInvoker(c.num, c.ctx, c.reader, c.writer, c.refData, c.subset, c.report, cmd)
+}
+
+// FUNCTION: ルール毎の集計メッセージ
+func (c *Controller) RuleSummary() string {
+	return showRuleStats(c.ctx, c.report.Rules)
+}
+
+// FUNCTION: 変更履歴の集計メッセージ
+func (c *Controller) ChangeSummary() string {
+	return showChangeStats(c.ctx, c.report.ChangeStats())
+}
+
+// FUNCTION: クレンジング結果(レポート出力用)
+func (c *Controller) Report() *Report {
+	return c.report
 }
 
 // FUNCTION: ヘッダーメッセージ
 func (c *Controller) Head() string {
 	msg := "\n## Legacy Data Check and Cleansing\n\n"
-	msg += "  | # | TABLE | ENTRY | ELAPSED | … | UNCHANGE | MODIFY | REMOVE | … | ACCEPT | RATE |\n"
-	msg += "  |--:|---|--:|--:|---|--:|--:|--:|---|--:|--:|\n"
+	msg += "  | # | TABLE | ENTRY | ELAPSED | RETRY | … | UNCHANGE | MODIFY | REMOVE | … | ACCEPT | RATE |\n"
+	msg += "  |--:|---|--:|--:|--:|---|--:|--:|--:|---|--:|--:|\n"
 	return msg
 }
@@ -0,0 +1,230 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+)
+
+// TITLE: クレンジング(インメモリリポジトリ)
+
+// STRUCT: テーブル毎の判定件数(UNCHANGE/MODIFY/REMOVE)
+type judgeCount struct {
+	unchange int
+	modify   int
+	remove   int
+}
+
+// FUNCTION: ルール毎の判定件数と登録レコード
+func TestController(t *testing.T) {
+	tests := []struct {
+		name         string
+		reader       MemoryLegacyReader
+		counts       [4]judgeCount //担当者/商品/受注/受注明細
+		operators    []string
+		products     []string
+		orders       []string
+		orderDetails []string
+	}{
+		{
+			name: "unchange",
+			reader: MemoryLegacyReader{
+				Operators:    []legacy.Operator{{OperatorID: "AB001", OperatorName: "山田太郎"}},
+				Products:     []legacy.Product{{ProductName: "ボールペン", CostPrice: 100}},
+				Orders:       []legacy.Order{{OrderNo: 1001, OrderDate: "20250110", OrderPic: "山田太郎", CustomerName: "A商事"}},
+				OrderDetails: []legacy.OrderDetail{{OrderNo: 1001, OrderDetailNo: 1, ProductName: "ボールペン", ReceivingQuantity: 2, SellingPrice: 150, CostPrice: 100}},
+			},
+			counts:       [4]judgeCount{{1, 0, 0}, {1, 0, 0}, {1, 0, 0}, {1, 0, 0}},
+			operators:    []string{"AB001:山田太郎", "Z9999:N/A"},
+			products:     []string{"ボールペン:100"},
+			orders:       []string{"1001:2025-01-10:山田太郎"},
+			orderDetails: []string{"1001-1:ボールペン:2"},
+		},
+		{
+			name: "#1-01 duplicate operator_name, #1-02 short operator_id",
+			reader: MemoryLegacyReader{
+				Operators: []legacy.Operator{
+					{OperatorID: "AB1", OperatorName: "山田太郎"},
+					{OperatorID: "CD002", OperatorName: "山田太郎"},
+				},
+			},
+			counts:    [4]judgeCount{{0, 1, 1}, {}, {}, {}},
+			operators: []string{"AB1XX:山田太郎", "Z9999:N/A"},
+		},
+		{
+			name: "#2-01 negative cost_price",
+			reader: MemoryLegacyReader{
+				Products: []legacy.Product{
+					{ProductName: "ボールペン", CostPrice: -10},
+					{ProductName: "消しゴム", CostPrice: 50},
+				},
+			},
+			counts:    [4]judgeCount{{}, {1, 1, 0}, {}, {}},
+			operators: []string{"Z9999:N/A"},
+			products:  []string{"ボールペン:0", "消しゴム:50"},
+		},
+		{
+			name: "#3-01 invalid order_date, #3-02 unknown order_pic",
+			reader: MemoryLegacyReader{
+				Operators: []legacy.Operator{{OperatorID: "AB001", OperatorName: "山田太郎"}},
+				Orders: []legacy.Order{
+					{OrderNo: 1001, OrderDate: "2025/01/10", OrderPic: "山田太郎", CustomerName: "A商事"},
+					{OrderNo: 1002, OrderDate: "20250111", OrderPic: "佐藤花子", CustomerName: "B商会"},
+				},
+			},
+			counts:    [4]judgeCount{{1, 0, 0}, {}, {0, 2, 0}, {}},
+			operators: []string{"AB001:山田太郎", "Z9999:N/A"},
+			orders:    []string{"1001:2025-01-01:山田太郎", "1002:2025-01-11:N/A"},
+		},
+		{
+			name: "#4-01 shipped and canceled, #4-02 unknown order_no, #4-03 unknown product_name",
+			reader: MemoryLegacyReader{
+				Operators: []legacy.Operator{{OperatorID: "AB001", OperatorName: "山田太郎"}},
+				Products:  []legacy.Product{{ProductName: "ボールペン", CostPrice: 100}},
+				Orders:    []legacy.Order{{OrderNo: 1001, OrderDate: "20250110", OrderPic: "山田太郎", CustomerName: "A商事"}},
+				OrderDetails: []legacy.OrderDetail{
+					{OrderNo: 1001, OrderDetailNo: 1, ProductName: "ボールペン", ReceivingQuantity: 2, ShippingFlag: true, CanceledFlag: true, SellingPrice: 150, CostPrice: 100},
+					{OrderNo: 1001, OrderDetailNo: 2, ProductName: "消しゴム", ReceivingQuantity: 3, SellingPrice: 80, CostPrice: 50},
+					{OrderNo: 1001, OrderDetailNo: 3, ProductName: "ボールペン", ReceivingQuantity: 4, SellingPrice: 150, CostPrice: 100},
+					{OrderNo: 1002, OrderDetailNo: 1, ProductName: "ボールペン", ReceivingQuantity: 5, SellingPrice: 150, CostPrice: 100},
+				},
+			},
+			counts:       [4]judgeCount{{1, 0, 0}, {1, 0, 0}, {1, 0, 0}, {1, 0, 3}},
+			operators:    []string{"AB001:山田太郎", "Z9999:N/A"},
+			products:     []string{"ボールペン:100"},
+			orders:       []string{"1001:2025-01-10:山田太郎"},
+			orderDetails: []string{"1001-3:ボールペン:4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := infra.NewCtx(context.Background(), infra.RunConfig{FetchLimit: 2, DateLayout: "20060102"}, 0, infra.Overrides{})
+			writer := NewMemoryCleanWriter()
+			controller := NewController(ctx, &tt.reader, writer, NewRefData(&NameMapping{}, ReinstateStore{}, ApprovalStore{}), nil)
+			controller.CreateInvocer(NewOperatorsCmd()).Execute()
+			controller.CreateInvocer(NewProductsCmd()).Execute()
+			controller.CreateInvocer(NewOrdersCmd()).Execute()
+			controller.CreateInvocer(NewOrderDetailsCmd()).Execute()
+
+			// PROCESS: 判定件数
+			tables := controller.Report().Tables
+			if len(tables) != len(tt.counts) {
+				t.Fatalf("tables: got %d, want %d", len(tables), len(tt.counts))
+			}
+			for i, want := range tt.counts {
+				got := judgeCount{tables[i].Unchange, tables[i].Modify, tables[i].Remove}
+				if got != want {
+					t.Errorf("%s: got %+v, want %+v", tables[i].Table, got, want)
+				}
+			}
+
+			// PROCESS: 登録レコード
+			assertRows(t, clean.TableNames.Operators, writer.Operators, tt.operators, func(r clean.Operator) string {
+				return fmt.Sprintf("%s:%s", r.OperatorID, r.OperatorName)
+			})
+			assertRows(t, clean.TableNames.Products, writer.Products, tt.products, func(r clean.Product) string {
+				return fmt.Sprintf("%s:%d", r.ProductName, r.CostPrice)
+			})
+			assertRows(t, clean.TableNames.Orders, writer.Orders, tt.orders, func(r clean.Order) string {
+				return fmt.Sprintf("%d:%s:%s", r.OrderNo, r.OrderDate.Format("2006-01-02"), r.OrderPic)
+			})
+			assertRows(t, clean.TableNames.OrderDetails, writer.OrderDetails, tt.orderDetails, func(r clean.OrderDetail) string {
+				return fmt.Sprintf("%d-%d:%s:%d", r.OrderNo, r.OrderDetailNo, r.ProductName, r.ReceivingQuantity)
+			})
+		})
+	}
+}
+
+// FUNCTION: 登録レコードの比較(順不同)
+func assertRows[T any](t *testing.T, table string, rows []T, want []string, key func(T) string) {
+	t.Helper()
+	got := []string{}
+	for _, row := range rows {
+		got = append(got, key(row))
+	}
+	slices.Sort(got)
+	want = slices.Sorted(slices.Values(want))
+	if !slices.Equal(got, want) {
+		t.Errorf("%s: got %v, want %v", table, got, want)
+	}
+}
+
+// STRUCT: 変更履歴/隔離データの登録に失敗するクレンジング結果
+type failingWriter struct {
+	*MemoryCleanWriter
+	failLog        bool
+	failQuarantine bool
+}
+
+// FUNCTION: 隔離データ
+func (w *failingWriter) Quarantine(ctx context.Context, table string, row QuarantineRow) error {
+	if w.failQuarantine {
+		return fmt.Errorf("quarantine failed: %s", row.LegacyKey)
+	}
+	return w.MemoryCleanWriter.Quarantine(ctx, table, row)
+}
+
+// FUNCTION: 変更履歴
+func (w *failingWriter) LogChanges(ctx context.Context, row ChangeLogRow) error {
+	if w.failLog {
+		return fmt.Errorf("change log failed: %s", row.LegacyKey)
+	}
+	return w.MemoryCleanWriter.LogChanges(ctx, row)
+}
+
+// FUNCTION: 変更履歴/隔離データの登録エラーはDB確認の対象(判定/登録レコードは変えない)
+func TestControllerWriteErrors(t *testing.T) {
+	reader := MemoryLegacyReader{
+		Operators: []legacy.Operator{
+			{OperatorID: "AB1", OperatorName: "山田太郎"},
+			{OperatorID: "CD002", OperatorName: "山田太郎"},
+		},
+	}
+	tests := []struct {
+		name           string
+		failLog        bool
+		failQuarantine bool
+		dbCheck        int
+		changeLogs     int
+		quarantined    int
+	}{
+		{name: "no error", dbCheck: 0, changeLogs: 1, quarantined: 1},
+		{name: "change log failed", failLog: true, dbCheck: 1, changeLogs: 0, quarantined: 1},
+		{name: "quarantine failed", failQuarantine: true, dbCheck: 1, changeLogs: 1, quarantined: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := infra.NewCtx(context.Background(), infra.RunConfig{FetchLimit: 2, DateLayout: "20060102"}, 0, infra.Overrides{})
+			writer := &failingWriter{MemoryCleanWriter: NewMemoryCleanWriter(), failLog: tt.failLog, failQuarantine: tt.failQuarantine}
+			controller := NewController(ctx, &reader, writer, NewRefData(&NameMapping{}, ReinstateStore{}, ApprovalStore{}), nil)
+			controller.CreateInvocer(NewOperatorsCmd()).Execute()
+
+			table := controller.Report().Tables[0]
+			if got := (judgeCount{table.Unchange, table.Modify, table.Remove}); got != (judgeCount{0, 1, 1}) {
+				t.Errorf("counts: got %+v", got)
+			}
+			if table.DbCheck != tt.dbCheck {
+				t.Errorf("db check: got %d, want %d", table.DbCheck, tt.dbCheck)
+			}
+			if got := len(writer.ChangeLogs[legacy.TableNames.Operators]); got != tt.changeLogs {
+				t.Errorf("change logs: got %d, want %d", got, tt.changeLogs)
+			}
+			if got := len(writer.Quarantined[legacy.TableNames.Operators]); got != tt.quarantined {
+				t.Errorf("quarantined: got %d, want %d", got, tt.quarantined)
+			}
+			assertRows(t, clean.TableNames.Operators, writer.Operators, []string{"AB1XX:山田太郎", "Z9999:N/A"}, func(r clean.Operator) string {
+				return fmt.Sprintf("%s:%s", r.OperatorID, r.OperatorName)
+			})
+		})
+	}
+}
@@ -4,15 +4,13 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package cleansing
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/cheggaaa/pb/v3"
 	"github.com/teru-0529/data-transfer-sandbox/infra"
-	"github.com/volatiletech/sqlboiler/v4/queries"
-	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 // TITLE: クレンジングインボーカー
@@ -20,7 +18,8 @@ import (
 // STRUCT: レコードインターフェース
 
 type LegacyRecord interface {
-	checkAndPersist(ctx infra.AppCtx, db *sql.DB, refData *RefData) Piece
+	checkAndPersist(ctx infra.AppCtx, writer CleanWriter, refData *RefData) Piece
+	legacyKey() string
 }
 
 // STRUCT: レコード(ラッパー)
@@ -29,36 +28,43 @@ type Record struct {
 }
 
 // FUNCTION:
-func (r *Record) save(ctx infra.AppCtx, db *sql.DB, refData *RefData) Piece {
-	return r.rec.checkAndPersist(ctx, db, refData)
+func (r *Record) save(ctx infra.AppCtx, writer CleanWriter, refData *RefData) Piece {
+	return r.rec.checkAndPersist(ctx, writer, refData)
 }
 
 // STRUCT: コマンドインターフェース
 type Command interface {
 	getTableInfo() TableInfo
-	entryCount(ctx infra.AppCtx, con *sql.DB) int
-	fetchRecords(ctx infra.AppCtx, db *sql.DB, qmArray []qm.QueryMod) []Record
+	entryCount(ctx infra.AppCtx, reader LegacyReader, subset *Subset) int
+	fetchRecords(ctx infra.AppCtx, reader LegacyReader, subset *Subset, limit int, offset int) []Record
 	showDetails(ctx infra.AppCtx, tableName string) string
-	extInsert(ctx infra.AppCtx, con *sql.DB, refData *RefData)
+	extInsert(ctx infra.AppCtx, writer CleanWriter, refData *RefData)
 }
 
 // STRUCT: インボーカー
 type Invoker struct {
 	num     int
 	ctx     infra.AppCtx
-	conns   infra.DbConnection
+	reader  LegacyReader
+	writer  CleanWriter
 	cmd     Command
 	refData *RefData
+	subset  *Subset
+	report  *Report
 }
 
 // FUNCTION:
-func NewInvoker(num int, ctx infra.AppCtx, conns infra.DbConnection, refData *RefData, cmd Command) *Invoker {
+// INFO: テーブル毎の結果件数/レコード毎の指摘はreportに追加する
+func NewInvoker(num int, ctx infra.AppCtx, reader LegacyReader, writer CleanWriter, refData *RefData, subset *Subset, report *Report, cmd Command) *Invoker {
 	return &Invoker{
 		num:     num,
 		ctx:     ctx,
-		conns:   conns,
+		reader:  reader,
+		writer:  writer,
 		cmd:     cmd,
 		refData: refData,
+		subset:  subset,
+		report:  report,
 	}
 }
 
@@ -68,58 +74,102 @@ func (inv *Invoker) Execute() (string, string) {
 
 	// PROCESS: テーブル名称取得
 	table := inv.cmd.getTableInfo()
+
+	// PROCESS: 中断済みの場合は処理しない
+	if inv.ctx.Interrupted() {
+		log.Printf("[%s] table cleansing skipped (interrupted)", table.tableEn)
+		result := ResultCount{Interrupted: true}
+		inv.report.Tables = append(inv.report.Tables, result.tableResult(inv.num, table, 0, 0))
+		return inv.showRecord(table, result, 0), ""
+	}
 	log.Printf("[%s] table cleansing ...", table.tableEn)
 
+	// PROCESS: リトライ回数(テーブル単位)
+	inv.ctx.Retries = infra.NewRetryCounter()
+
 	// PROCESS: 入力データ量
-	count := inv.cmd.entryCount(inv.ctx, inv.conns.LegacyDB)
+	count := inv.cmd.entryCount(inv.ctx, inv.reader, inv.subset)
 
 	// PROCESS: 移行先のtruncate
-	_, err := queries.Raw(table.truncateSql()).ExecContext(inv.ctx.Ctx, inv.conns.WorkDB)
+	err := infra.RetryExec(inv.ctx, func(c context.Context) error {
+		return inv.writer.Truncate(c, table.tableEn)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		inv.ctx.Fatal(err)
 	}
 
 	// PROCESS: データ取得/登録
-	result := inv.iterate(count)
+	result := inv.iterate(table, count)
 
-	// PROCESS: 追加データ登録
-	inv.cmd.extInsert(inv.ctx, inv.conns.WorkDB, inv.refData)
+	// PROCESS: 追加データ登録(中断時は登録しない)
+	if !result.Interrupted {
+		inv.cmd.extInsert(inv.ctx, inv.writer, inv.refData)
+	}
 
 	// PROCESS: 後処理
 	duration := time.Since(s).Seconds()
 	log.Printf("cleansing completed … %3.2fs\n", duration)
+	inv.report.Tables = append(inv.report.Tables, result.tableResult(inv.num, table, duration, inv.ctx.Retries.Count()))
 	return inv.showRecord(table, result, duration), inv.cmd.showDetails(inv.ctx, table.Name())
 }
 
 // FUNCTION: データ取得/登録
-func (inv *Invoker) iterate(count int) ResultCount {
+func (inv *Invoker) iterate(table TableInfo, count int) ResultCount {
 	result := ResultCount{EntryCount: count}
 	bar := pb.Default.Start(count)
 	bar.SetMaxWidth(80)
 
 	// PROCESS: SQLによる取得を分割する
 	for lap := 0; lap < inv.ctx.LapNumber(count); lap++ {
-		qmArray := []qm.QueryMod{qm.Limit(inv.ctx.Limit), qm.Offset(lap * inv.ctx.Limit)}
-
-		records := inv.cmd.fetchRecords(inv.ctx, inv.conns.LegacyDB, qmArray)
+		// PROCESS: 中断時は以降の取得を行わない
+		if inv.ctx.Interrupted() {
+			result.Interrupted = true
+			break
+		}
+		// INFO: サブセット指定時は抽出条件を付与する
+		records := inv.cmd.fetchRecords(inv.ctx, inv.reader, inv.subset, inv.ctx.Limit, lap*inv.ctx.Limit)
 
 		for _, record := range records {
+			// PROCESS: 中断時は以降の登録を行わない
+			if inv.ctx.Interrupted() {
+				result.Interrupted = true
+				break
+			}
+
 			// PROCESS: レコード毎のデータ登録
-			result.add(record.save(inv.ctx, inv.conns.WorkDB, inv.refData))
+			piece := record.save(inv.ctx, inv.writer, inv.refData)
+
+			// PROCESS: SQLのタイムアウト/キャンセル時は結果に含めない
+			if piece.interrupted {
+				result.Interrupted = true
+				break
+			}
+			result.add(piece)
+
+			// PROCESS: REMOVE/MODIFY判定時はレコード毎の指摘を追加
+			if piece.isWarn() {
+				inv.report.addFinding(piece.finding(table.tableEn, record.rec.legacyKey()))
+			}
 			bar.Increment()
 		}
 	}
 	bar.Finish()
+
+	// INFO: 最終ページの取得中に中断した場合
+	if inv.ctx.Interrupted() {
+		result.Interrupted = true
+	}
 	return result
 }
 
 // FUNCTION: メッセージの出力
 func (inv *Invoker) showRecord(t TableInfo, r ResultCount, duration float64) string {
-	return fmt.Sprintf("  | %d. | %s | %s | %s | … | %s | %s | %s%s | … | %s | %3.1f%% |\n",
+	return fmt.Sprintf("  | %d. | %s | %s | %s | %s | … | %s | %s | %s%s | … | %s | %3.1f%% |\n",
 		inv.num,
-		t.Name(),
+		t.Name()+r.interruptedStr(),
 		inv.ctx.Printer.Sprintf("%d", r.EntryCount),
 		inv.ctx.Printer.Sprintf("%3.2fs", duration),
+		inv.ctx.Printer.Sprintf("%d", inv.ctx.Retries.Count()),
 		inv.ctx.Printer.Sprintf("%d", r.UnchangeCount),
 		inv.ctx.Printer.Sprintf("%d", r.ModifyCount),
 		inv.ctx.Printer.Sprintf("%d", r.RemoveCount),
@@ -141,11 +191,6 @@ func (t TableInfo) Name() string {
 	return fmt.Sprintf("%s(%s)", t.tableEn, t.tableJp)
 }
 
-// FUNCTION: truuncate文
-func (t TableInfo) truncateSql() string {
-	return fmt.Sprintf("TRUNCATE clean.%s CASCADE;", t.tableEn)
-}
-
 // STRUCT: 結果件数
 type ResultCount struct {
 	EntryCount    int
@@ -153,6 +198,7 @@ type ResultCount struct {
 	ModifyCount   int
 	RemoveCount   int
 	DbCheckCount  int
+	Interrupted   bool
 }
 
 // FUNCTION: クレンジング結果の登録
@@ -192,3 +238,12 @@ func (r ResultCount) dbCheckCountStr(ctx infra.AppCtx) string {
 		return ""
 	}
 }
+
+// FUNCTION: 中断時の表示
+func (r ResultCount) interruptedStr() string {
+	if r.Interrupted {
+		return "<span style=\"color:red;\">(⏸INTERRUPTED)</span>"
+	} else {
+		return ""
+	}
+}
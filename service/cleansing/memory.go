@@ -0,0 +1,244 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+)
+
+// TITLE: インメモリリポジトリ(DBを使用しないルールの検証用)
+
+// INFO: インターフェースの実装確認
+var _ LegacyReader = (*MemoryLegacyReader)(nil)
+var _ CleanWriter = (*MemoryCleanWriter)(nil)
+
+// STRUCT: 移行元データ(インメモリ)
+type MemoryLegacyReader struct {
+	Operators    []legacy.Operator
+	Products     []legacy.Product
+	Orders       []legacy.Order
+	OrderDetails []legacy.OrderDetail
+}
+
+// FUNCTION: 件数
+func (r *MemoryLegacyReader) Count(ctx context.Context, table string, subset *Subset) (int64, error) {
+	switch table {
+	case legacy.TableNames.Operators:
+		return int64(len(filterRows(r.Operators, subset, operatorInSubset))), nil
+	case legacy.TableNames.Products:
+		return int64(len(filterRows(r.Products, subset, productInSubset))), nil
+	case legacy.TableNames.Orders:
+		return int64(len(filterRows(r.Orders, subset, orderInSubset))), nil
+	case legacy.TableNames.OrderDetails:
+		return int64(len(filterRows(r.OrderDetails, subset, orderDetailInSubset))), nil
+	}
+	return 0, fmt.Errorf("unknown legacy table: %s", table)
+}
+
+// FUNCTION: 担当者(担当者ID:昇順)
+func (r *MemoryLegacyReader) FetchOperators(ctx context.Context, subset *Subset, limit int, offset int) (legacy.OperatorSlice, error) {
+	rows := filterRows(r.Operators, subset, operatorInSubset)
+	slices.SortStableFunc(rows, func(a, b legacy.Operator) int { return strings.Compare(a.OperatorID, b.OperatorID) })
+	return page(rows, limit, offset), nil
+}
+
+// FUNCTION: 商品(商品名:昇順)
+func (r *MemoryLegacyReader) FetchProducts(ctx context.Context, subset *Subset, limit int, offset int) (legacy.ProductSlice, error) {
+	rows := filterRows(r.Products, subset, productInSubset)
+	slices.SortStableFunc(rows, func(a, b legacy.Product) int { return strings.Compare(a.ProductName, b.ProductName) })
+	return page(rows, limit, offset), nil
+}
+
+// FUNCTION: 受注(受注番号:昇順)
+func (r *MemoryLegacyReader) FetchOrders(ctx context.Context, subset *Subset, limit int, offset int) (legacy.OrderSlice, error) {
+	rows := filterRows(r.Orders, subset, orderInSubset)
+	slices.SortStableFunc(rows, func(a, b legacy.Order) int { return cmp.Compare(a.OrderNo, b.OrderNo) })
+	return page(rows, limit, offset), nil
+}
+
+// FUNCTION: 受注明細(受注番号、受注明細番号:昇順)
+func (r *MemoryLegacyReader) FetchOrderDetails(ctx context.Context, subset *Subset, limit int, offset int) (legacy.OrderDetailSlice, error) {
+	rows := filterRows(r.OrderDetails, subset, orderDetailInSubset)
+	slices.SortStableFunc(rows, func(a, b legacy.OrderDetail) int {
+		return cmp.Or(cmp.Compare(a.OrderNo, b.OrderNo), cmp.Compare(a.OrderDetailNo, b.OrderDetailNo))
+	})
+	return page(rows, limit, offset), nil
+}
+
+// FUNCTION: サブセットの抽出条件(Subset.modsと同じ条件)
+func operatorInSubset(s *Subset, r legacy.Operator) bool {
+	return slices.Contains(s.OperatorIDs, r.OperatorID)
+}
+func productInSubset(s *Subset, r legacy.Product) bool {
+	return slices.Contains(s.ProductNames, r.ProductName)
+}
+func orderInSubset(s *Subset, r legacy.Order) bool {
+	return slices.Contains(s.OrderNos, r.OrderNo)
+}
+func orderDetailInSubset(s *Subset, r legacy.OrderDetail) bool {
+	return slices.Contains(s.OrderNos, r.OrderNo)
+}
+
+// FUNCTION: 抽出(subsetがnilの場合は全件)
+func filterRows[T any](rows []T, subset *Subset, match func(*Subset, T) bool) []T {
+	results := []T{}
+	for _, row := range rows {
+		if subset == nil || match(subset, row) {
+			results = append(results, row)
+		}
+	}
+	return results
+}
+
+// FUNCTION: ページング(レコードはコピーを返す)
+func page[T any](rows []T, limit int, offset int) []*T {
+	results := []*T{}
+	for i := offset; i < len(rows) && i < offset+limit; i++ {
+		row := rows[i]
+		results = append(results, &row)
+	}
+	return results
+}
+
+// STRUCT: クレンジング結果(インメモリ)
+// INFO: 主キー/一意制約/外部キー、トリガー(w_product_id採番、受注明細の数量/金額)はWorkDBと同じ動作とする
+type MemoryCleanWriter struct {
+	Operators    []clean.Operator
+	Products     []clean.Product
+	Orders       []clean.Order
+	OrderDetails []clean.OrderDetail
+	Quarantined  map[string][]QuarantineRow
+	ChangeLogs   map[string][]ChangeLogRow
+	productSeq   int
+}
+
+// FUNCTION:
+func NewMemoryCleanWriter() *MemoryCleanWriter {
+	return &MemoryCleanWriter{Quarantined: map[string][]QuarantineRow{}, ChangeLogs: map[string][]ChangeLogRow{}}
+}
+
+// FUNCTION: truncate(隔離データ/変更履歴を含む、参照元テーブルも削除する)
+func (w *MemoryCleanWriter) Truncate(ctx context.Context, table string) error {
+	switch table {
+	case clean.TableNames.Operators:
+		w.Operators = nil
+		w.Orders, w.OrderDetails = nil, nil
+	case clean.TableNames.Products:
+		w.Products, w.productSeq = nil, 0
+		w.OrderDetails = nil
+	case clean.TableNames.Orders:
+		w.Orders, w.OrderDetails = nil, nil
+	case clean.TableNames.OrderDetails:
+		w.OrderDetails = nil
+	default:
+		return fmt.Errorf("unknown clean table: %s", table)
+	}
+	delete(w.Quarantined, table)
+	delete(w.ChangeLogs, table)
+	return nil
+}
+
+// FUNCTION: 担当者
+func (w *MemoryCleanWriter) InsertOperator(ctx context.Context, rec clean.Operator) error {
+	for _, row := range w.Operators {
+		if row.OperatorID == rec.OperatorID {
+			return duplicateKeyError("operators_pkey", rec.OperatorID)
+		}
+		if row.OperatorName == rec.OperatorName {
+			return duplicateKeyError("operators_unique_1", rec.OperatorName)
+		}
+	}
+	w.Operators = append(w.Operators, rec)
+	return nil
+}
+
+// FUNCTION: 商品(w_product_idはトリガーで採番)
+func (w *MemoryCleanWriter) InsertProduct(ctx context.Context, rec clean.Product) error {
+	w.productSeq++
+	rec.WProductID = fmt.Sprintf("P%04d", w.productSeq)
+	for _, row := range w.Products {
+		if row.ProductName == rec.ProductName {
+			return duplicateKeyError("products_pkey", rec.ProductName)
+		}
+	}
+	w.Products = append(w.Products, rec)
+	return nil
+}
+
+// FUNCTION: 受注
+func (w *MemoryCleanWriter) InsertOrder(ctx context.Context, rec clean.Order) error {
+	for _, row := range w.Orders {
+		if row.OrderNo == rec.OrderNo {
+			return duplicateKeyError("orders_pkey", rec.OrderNo)
+		}
+	}
+	if !slices.ContainsFunc(w.Operators, func(row clean.Operator) bool { return row.OperatorName == rec.OrderPic }) {
+		return foreignKeyError("orders_foreignKey_1", rec.OrderPic)
+	}
+	w.Orders = append(w.Orders, rec)
+	return nil
+}
+
+// FUNCTION: 受注明細(数量/金額はトリガーで設定)
+func (w *MemoryCleanWriter) InsertOrderDetail(ctx context.Context, rec clean.OrderDetail) error {
+	for _, row := range w.OrderDetails {
+		if row.OrderNo == rec.OrderNo && row.OrderDetailNo == rec.OrderDetailNo {
+			return duplicateKeyError("order_details_pkey", fmt.Sprintf("%d, %d", rec.OrderNo, rec.OrderDetailNo))
+		}
+	}
+	if !slices.ContainsFunc(w.Orders, func(row clean.Order) bool { return row.OrderNo == rec.OrderNo }) {
+		return foreignKeyError("order_details_foreignKey_1", rec.OrderNo)
+	}
+	if !slices.ContainsFunc(w.Products, func(row clean.Product) bool { return row.ProductName == rec.ProductName }) {
+		return foreignKeyError("order_details_foreignKey_2", rec.ProductName)
+	}
+
+	rec.WShippingQuantity, rec.WCancelQuantity, rec.WRemainingQuantity = 0, 0, 0
+	if rec.ShippingFlag {
+		rec.WShippingQuantity = rec.ReceivingQuantity
+	} else if rec.CancelFlag {
+		rec.WCancelQuantity = rec.ReceivingQuantity
+	} else {
+		rec.WRemainingQuantity = rec.ReceivingQuantity
+	}
+	rec.WTotalOrderPrice = rec.ReceivingQuantity * rec.SellingPrice
+	rec.WRemainingOrderPrice = rec.WRemainingQuantity * rec.SellingPrice
+	w.OrderDetails = append(w.OrderDetails, rec)
+	return nil
+}
+
+// FUNCTION: 隔離データ
+func (w *MemoryCleanWriter) Quarantine(ctx context.Context, table string, row QuarantineRow) error {
+	if _, exist := QUARANTINE_TABLES[table]; !exist {
+		return fmt.Errorf("unknown quarantine table: %s", table)
+	}
+	w.Quarantined[table] = append(w.Quarantined[table], row)
+	return nil
+}
+
+// FUNCTION: 変更履歴
+func (w *MemoryCleanWriter) LogChanges(ctx context.Context, row ChangeLogRow) error {
+	if _, exist := QUARANTINE_TABLES[row.Table]; !exist {
+		return fmt.Errorf("unknown change log table: %s", row.Table)
+	}
+	w.ChangeLogs[row.Table] = append(w.ChangeLogs[row.Table], row)
+	return nil
+}
+
+// FUNCTION: 一意制約違反(PostgreSQLのメッセージに合わせる)
+func duplicateKeyError(constraint string, key any) error {
+	return fmt.Errorf("duplicate key value violates unique constraint \"%s\" (%v)", constraint, key)
+}
+
+// FUNCTION: 外部キー制約違反(PostgreSQLのメッセージに合わせる)
+func foreignKeyError(constraint string, key any) error {
+	return fmt.Errorf("insert violates foreign key constraint \"%s\" (%v)", constraint, key)
+}
@@ -0,0 +1,208 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+	"gopkg.in/yaml.v3"
+)
+
+// TITLE: 隔離データ(REMOVE判定レコード)
+
+// STRUCT: 隔離テーブル(移行元テーブルと同一のカラム)
+var QUARANTINE_TABLES = map[string][]string{
+	legacy.TableNames.Operators: {
+		legacy.OperatorColumns.OperatorID,
+		legacy.OperatorColumns.OperatorName,
+	},
+	legacy.TableNames.Products: {
+		legacy.ProductColumns.ProductName,
+		legacy.ProductColumns.CostPrice,
+	},
+	legacy.TableNames.Orders: {
+		legacy.OrderColumns.OrderNo,
+		legacy.OrderColumns.OrderDate,
+		legacy.OrderColumns.OrderPic,
+		legacy.OrderColumns.CustomerName,
+	},
+	legacy.TableNames.OrderDetails: {
+		legacy.OrderDetailColumns.OrderNo,
+		legacy.OrderDetailColumns.OrderDetailNo,
+		legacy.OrderDetailColumns.ProductName,
+		legacy.OrderDetailColumns.ReceivingQuantity,
+		legacy.OrderDetailColumns.ShippingFlag,
+		legacy.OrderDetailColumns.CanceledFlag,
+		legacy.OrderDetailColumns.SellingPrice,
+		legacy.OrderDetailColumns.CostPrice,
+	},
+}
+
+// FUNCTION: 隔離データの登録
+func quarantine(ctx infra.AppCtx, writer CleanWriter, table string, legacyKey string, values []any, p *Piece) {
+	row := QuarantineRow{
+		LegacyKey:     legacyKey,
+		RuleIds:       strings.Join(p.ruleIds, ","),
+		Messages:      plainText(p.messages),
+		OperationUser: ctx.OperationUser,
+		Values:        values,
+	}
+
+	// PROCESS: 登録に失敗した場合は、DB確認の対象
+	// INFO: 隔離IDは採番のため、リトライすると重複登録となる場合がある(リトライしない)
+	stmtCtx, cancel := ctx.WithStatementTimeout()
+	defer cancel()
+	if err := writer.Quarantine(stmtCtx.Ctx, table, row); err != nil {
+		p.dbCheck(ctx, err, "隔離データの登録に失敗しました。")
+	}
+}
+
+// FUNCTION: メッセージのテキスト化(HTMLタグを除去)
+func plainText(messages []string) string {
+	tag := regexp.MustCompile(`<[^>]+>`)
+	strs := make([]string, len(messages))
+	for i, msg := range messages {
+		strs[i] = strings.TrimSpace(tag.ReplaceAllString(msg, " "))
+	}
+	return strings.Join(strs, "\n")
+}
+
+// STRUCT: 再登録データ(テーブル名 → 移行元キー → カラム名 → 値)
+type ReinstateStore map[string]map[string]map[string]string
+
+// FUNCTION: 再登録データの読込み(ファイルが存在しない場合は空)
+func LoadReinstateStore(filePath string) (ReinstateStore, error) {
+	store := ReinstateStore{}
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read reinstate file: %s", err.Error())
+	}
+
+	if err := yaml.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("cannot parse reinstate file[%s]: %s", filePath, err.Error())
+	}
+	return store, nil
+}
+
+// FUNCTION: 再登録データの検索
+func (s ReinstateStore) lookup(table string, legacyKey string) (map[string]string, bool) {
+	rows, exist := s[table]
+	if !exist {
+		return nil, false
+	}
+	values, exist := rows[legacyKey]
+	return values, exist
+}
+
+// FUNCTION: 再登録データの保存(一時ファイルからの置き換え)
+func (s ReinstateStore) save(filePath string) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return infra.WriteTextAtomic(filePath, string(data))
+}
+
+// FUNCTION: 再登録(承認済み`reinstate=true`の隔離データを再登録データに移動する)
+func Reinstate(ctx context.Context, db *sql.DB, filePath string) (map[string]int, error) {
+	store, err := LoadReinstateStore(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	counts := map[string]int{}
+	for table, columns := range QUARANTINE_TABLES {
+		// PROCESS: 承認済みの隔離データの取得
+		query := fmt.Sprintf("SELECT legacy_key, %s FROM quarantine.%s WHERE reinstate ORDER BY quarantine_id;", strings.Join(columns, ", "), table)
+		rows, err := tx.QueryContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var legacyKey string
+			values := make([]any, len(columns))
+			strs := make([]*string, len(columns))
+			for i := range columns {
+				values[i] = &strs[i]
+			}
+			if err := rows.Scan(append([]any{&legacyKey}, values...)...); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			if _, exist := store[table]; !exist {
+				store[table] = map[string]map[string]string{}
+			}
+			store[table][legacyKey] = map[string]string{}
+			for i, column := range columns {
+				if strs[i] != nil {
+					store[table][legacyKey][column] = *strs[i]
+				}
+			}
+			counts[table]++
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
+
+		// PROCESS: 隔離データから削除
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM quarantine.%s WHERE reinstate;", table)); err != nil {
+			return nil, err
+		}
+	}
+
+	// PROCESS: 再登録データを保存してからコミット
+	// INFO: 保存に失敗した場合は隔離データを削除しない。コミットに失敗した場合は次回の再登録で同じキーを上書きする
+	if err := store.save(filePath); err != nil {
+		return nil, fmt.Errorf("cannot save reinstate file[%s]: %s", filePath, err.Error())
+	}
+	log.Printf("reinstate data saved [%s]\n", filePath)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("cannot delete reinstated quarantine records (reinstate file already saved): %s", err.Error())
+	}
+	return counts, nil
+}
+
+// FUNCTION: 再登録データの値(文字列)
+func reinstateStr(values map[string]string, column string, value string) string {
+	if v, exist := values[column]; exist {
+		return v
+	}
+	return value
+}
+
+// FUNCTION: 再登録データの値(数値)
+func reinstateInt(values map[string]string, column string, value int) int {
+	if v, err := strconv.Atoi(values[column]); err == nil {
+		return v
+	}
+	return value
+}
+
+// FUNCTION: 再登録データの値(真偽値)
+func reinstateBool(values map[string]string, column string, value bool) bool {
+	if v, err := strconv.ParseBool(values[column]); err == nil {
+		return v
+	}
+	return value
+}
@@ -0,0 +1,186 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"fmt"
+	"slices"
+)
+
+// TITLE: クレンジング結果(レポート出力用の構造化データ)
+
+// STRUCT: クレンジング結果
+type Report struct {
+	Tables   []TableResult `json:"tables"`
+	Rules    []RuleStat    `json:"rules"`
+	Findings []Finding     `json:"findings"`
+}
+
+// STRUCT: テーブル毎の結果件数
+type TableResult struct {
+	Num         int     `json:"num"`
+	Table       string  `json:"table"`
+	TableJp     string  `json:"table_jp"`
+	Entry       int     `json:"entry"`
+	Elapsed     float64 `json:"elapsed"`
+	Retry       int     `json:"retry"`
+	Unchange    int     `json:"unchange"`
+	Modify      int     `json:"modify"`
+	Remove      int     `json:"remove"`
+	DbCheck     int     `json:"db_check"`
+	Accept      int     `json:"accept"`
+	AcceptRate  float64 `json:"accept_rate"`
+	Interrupted bool    `json:"interrupted"`
+}
+
+// STRUCT: レコード毎の指摘(MODIFY/REMOVE判定のレコード)
+type Finding struct {
+	Table     string         `json:"table"`
+	LegacyKey string         `json:"legacy_key"`
+	Result    string         `json:"result"`
+	Override  bool           `json:"override"`
+	Approve   string         `json:"approve"`
+	RuleIds   []string       `json:"rule_ids"`
+	Pending   []string       `json:"pending_rule_ids"`
+	Messages  []string       `json:"messages"`
+	Values    []FindingValue `json:"values"`
+	Changes   []Change       `json:"changes"`
+}
+
+// STRUCT: 指摘レコードのカラム値(移行元/クレンジング後)
+type FindingValue struct {
+	Column   string `json:"column"`
+	Original string `json:"original"`
+	Cleansed string `json:"cleansed"`
+}
+
+// FUNCTION: 値の変更あり
+func (v FindingValue) Changed() bool {
+	return v.Original != v.Cleansed
+}
+
+// FUNCTION: 指摘の追加(ルール毎の集計に加算、承認待ちのルールはpendingとする)
+func (r *Report) addFinding(finding Finding) {
+	r.Findings = append(r.Findings, finding)
+	for _, id := range uniqueIds(finding.RuleIds) {
+		i := slices.IndexFunc(r.Rules, func(stat RuleStat) bool { return stat.Id == id })
+		if i < 0 {
+			continue
+		}
+		r.Rules[i].Hit++
+		if slices.Contains(finding.Pending, id) {
+			r.Rules[i].Pending++
+		} else {
+			r.Rules[i].Approved++
+		}
+	}
+}
+
+// FUNCTION: ルール毎の指摘(RULE_IDSの順、ルールIDのない指摘は最後に空文字のキーで返す)
+func (r Report) FindingsByRule() ([]string, map[string][]Finding) {
+	groups := map[string][]Finding{}
+	for _, finding := range r.Findings {
+		if len(finding.RuleIds) == 0 {
+			groups[""] = append(groups[""], finding)
+			continue
+		}
+		for _, id := range uniqueIds(finding.RuleIds) {
+			groups[id] = append(groups[id], finding)
+		}
+	}
+
+	keys := []string{}
+	for _, id := range RULE_IDS {
+		if _, exist := groups[id]; exist {
+			keys = append(keys, id)
+		}
+	}
+	if _, exist := groups[""]; exist {
+		keys = append(keys, "")
+	}
+	return keys, groups
+}
+
+// FUNCTION: 重複を除いたルールID(出現順)
+func uniqueIds(ids []string) []string {
+	results := []string{}
+	for _, id := range ids {
+		if !slices.Contains(results, id) {
+			results = append(results, id)
+		}
+	}
+	return results
+}
+
+// FUNCTION: テーブル毎の結果件数
+func (r ResultCount) tableResult(num int, t TableInfo, duration float64, retry int) TableResult {
+	return TableResult{
+		Num:         num,
+		Table:       t.tableEn,
+		TableJp:     t.tableJp,
+		Entry:       r.EntryCount,
+		Elapsed:     duration,
+		Retry:       retry,
+		Unchange:    r.UnchangeCount,
+		Modify:      r.ModifyCount,
+		Remove:      r.RemoveCount,
+		DbCheck:     r.DbCheckCount,
+		Accept:      r.AcceptCount(),
+		AcceptRate:  r.AcceptRate(),
+		Interrupted: r.Interrupted,
+	}
+}
+
+// FUNCTION: レコード毎の指摘(メッセージはHTMLタグを除去する)
+func (p Piece) finding(table string, legacyKey string) Finding {
+	messages := make([]string, len(p.messages))
+	for i, msg := range p.messages {
+		messages[i] = plainText([]string{msg})
+	}
+	values := []FindingValue{}
+	for i, column := range QUARANTINE_TABLES[table] {
+		if i >= len(p.original) || i >= len(p.cleansed) {
+			break
+		}
+		values = append(values, FindingValue{
+			Column:   column,
+			Original: fmt.Sprint(p.original[i]),
+			Cleansed: fmt.Sprint(p.cleansed[i]),
+		})
+	}
+	return Finding{
+		Table:     table,
+		LegacyKey: legacyKey,
+		Result:    p.status.label(),
+		Override:  p.override,
+		Approve:   p.approve.label(),
+		RuleIds:   append([]string{}, p.ruleIds...),
+		Pending:   append([]string{}, p.pendingIds...),
+		Messages:  messages,
+		Values:    values,
+		Changes:   p.changeLog(),
+	}
+}
+
+// FUNCTION: 結果の表示名(記号/HTMLを含まない)
+func (s Status) label() string {
+	switch s {
+	case MODIFY:
+		return "MODIFY"
+	case REMOVE:
+		return "REMOVE"
+	}
+	return "UNCHANGE"
+}
+
+// FUNCTION: 承認状況の表示名(記号/HTMLを含まない)
+func (a Approve) label() string {
+	switch a {
+	case APPROVED:
+		return "APPROVED"
+	case NOT_FINDED:
+		return "CHECK"
+	}
+	return "PENDING"
+}
@@ -0,0 +1,215 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// TITLE: リポジトリ(移行元データの読込み/クレンジング結果の登録)
+
+// STRUCT: 移行元データの読込み(subsetがnilの場合は全件)
+type LegacyReader interface {
+	Count(ctx context.Context, table string, subset *Subset) (int64, error)
+	FetchOperators(ctx context.Context, subset *Subset, limit int, offset int) (legacy.OperatorSlice, error)
+	FetchProducts(ctx context.Context, subset *Subset, limit int, offset int) (legacy.ProductSlice, error)
+	FetchOrders(ctx context.Context, subset *Subset, limit int, offset int) (legacy.OrderSlice, error)
+	FetchOrderDetails(ctx context.Context, subset *Subset, limit int, offset int) (legacy.OrderDetailSlice, error)
+}
+
+// STRUCT: クレンジング結果の登録(clean/quarantineスキーマ)
+type CleanWriter interface {
+	Truncate(ctx context.Context, table string) error
+	InsertOperator(ctx context.Context, rec clean.Operator) error
+	InsertProduct(ctx context.Context, rec clean.Product) error
+	InsertOrder(ctx context.Context, rec clean.Order) error
+	InsertOrderDetail(ctx context.Context, rec clean.OrderDetail) error
+	Quarantine(ctx context.Context, table string, row QuarantineRow) error
+	LogChanges(ctx context.Context, row ChangeLogRow) error
+}
+
+// STRUCT: 隔離データ(values は移行元テーブルのカラム順、QUARANTINE_TABLES)
+type QuarantineRow struct {
+	LegacyKey     string
+	RuleIds       string
+	Messages      string
+	OperationUser null.String
+	Values        []any
+}
+
+// STRUCT: 変更履歴(レコード単位、changesはカラム毎の変更)
+type ChangeLogRow struct {
+	Table         string
+	LegacyKey     string
+	Changes       []Change
+	OperationUser null.String
+}
+
+// STRUCT: 移行元データの読込み(LegacyDB)
+type sqlLegacyReader struct {
+	db *sql.DB
+}
+
+// FUNCTION:
+func NewLegacyReader(db *sql.DB) LegacyReader {
+	return &sqlLegacyReader{db: db}
+}
+
+// FUNCTION: 件数
+func (r *sqlLegacyReader) Count(ctx context.Context, table string, subset *Subset) (int64, error) {
+	mods := subset.mods(table)
+	switch table {
+	case legacy.TableNames.Operators:
+		return legacy.Operators(mods...).Count(ctx, r.db)
+	case legacy.TableNames.Products:
+		return legacy.Products(mods...).Count(ctx, r.db)
+	case legacy.TableNames.Orders:
+		return legacy.Orders(mods...).Count(ctx, r.db)
+	case legacy.TableNames.OrderDetails:
+		return legacy.OrderDetails(mods...).Count(ctx, r.db)
+	}
+	return 0, fmt.Errorf("unknown legacy table: %s", table)
+}
+
+// FUNCTION: 担当者
+func (r *sqlLegacyReader) FetchOperators(ctx context.Context, subset *Subset, limit int, offset int) (legacy.OperatorSlice, error) {
+	//INFO: 担当者ID:昇順
+	mods := append(pageMods(subset, legacy.TableNames.Operators, limit, offset), qm.OrderBy("operator_id ASC"))
+	return legacy.Operators(mods...).All(ctx, r.db)
+}
+
+// FUNCTION: 商品
+func (r *sqlLegacyReader) FetchProducts(ctx context.Context, subset *Subset, limit int, offset int) (legacy.ProductSlice, error) {
+	//INFO: 商品名:昇順
+	mods := append(pageMods(subset, legacy.TableNames.Products, limit, offset), qm.OrderBy("product_name ASC"))
+	return legacy.Products(mods...).All(ctx, r.db)
+}
+
+// FUNCTION: 受注
+func (r *sqlLegacyReader) FetchOrders(ctx context.Context, subset *Subset, limit int, offset int) (legacy.OrderSlice, error) {
+	//INFO: 受注番号:昇順
+	mods := append(pageMods(subset, legacy.TableNames.Orders, limit, offset), qm.OrderBy("order_no ASC"))
+	return legacy.Orders(mods...).All(ctx, r.db)
+}
+
+// FUNCTION: 受注明細
+func (r *sqlLegacyReader) FetchOrderDetails(ctx context.Context, subset *Subset, limit int, offset int) (legacy.OrderDetailSlice, error) {
+	//INFO: 受注番号、受注明細番号:昇順
+	mods := append(pageMods(subset, legacy.TableNames.OrderDetails, limit, offset), qm.OrderBy("order_no ASC, order_detail_no ASC"))
+	return legacy.OrderDetails(mods...).All(ctx, r.db)
+}
+
+// FUNCTION: 抽出条件(サブセット指定時は抽出条件を付与する)
+func pageMods(subset *Subset, table string, limit int, offset int) []qm.QueryMod {
+	return append(subset.mods(table), qm.Limit(limit), qm.Offset(offset))
+}
+
+// STRUCT: クレンジング結果の登録(WorkDB)
+type sqlCleanWriter struct {
+	db *sql.DB
+}
+
+// FUNCTION:
+func NewCleanWriter(db *sql.DB) CleanWriter {
+	return &sqlCleanWriter{db: db}
+}
+
+// FUNCTION: truncate(隔離データ/変更履歴を含む、w_product_id等のシーケンスは初期化する)
+// INFO: 変更履歴IDのシーケンスは、変更履歴が全件削除された場合のみ初期化する
+func (w *sqlCleanWriter) Truncate(ctx context.Context, table string) error {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("TRUNCATE clean.%s RESTART IDENTITY CASCADE;", table)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("TRUNCATE quarantine.%s RESTART IDENTITY;", table)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s.%s WHERE table_name = $1;", CLEAN_SCHEMA, CHANGE_LOG_TABLE), table); err != nil {
+		return err
+	}
+	changeLog := fmt.Sprintf("%s.%s", CLEAN_SCHEMA, CHANGE_LOG_TABLE)
+	if _, err := tx.ExecContext(ctx,
+		fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'change_log_id'), 1, false) WHERE NOT EXISTS (SELECT 1 FROM %s);", changeLog, changeLog),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FUNCTION: 担当者
+func (w *sqlCleanWriter) InsertOperator(ctx context.Context, rec clean.Operator) error {
+	return rec.Insert(ctx, w.db, boil.Infer())
+}
+
+// FUNCTION: 商品
+func (w *sqlCleanWriter) InsertProduct(ctx context.Context, rec clean.Product) error {
+	return rec.Insert(ctx, w.db, boil.Infer())
+}
+
+// FUNCTION: 受注
+func (w *sqlCleanWriter) InsertOrder(ctx context.Context, rec clean.Order) error {
+	return rec.Insert(ctx, w.db, boil.Infer())
+}
+
+// FUNCTION: 受注明細
+func (w *sqlCleanWriter) InsertOrderDetail(ctx context.Context, rec clean.OrderDetail) error {
+	return rec.Insert(ctx, w.db, boil.Infer())
+}
+
+// FUNCTION: 隔離データ
+func (w *sqlCleanWriter) Quarantine(ctx context.Context, table string, row QuarantineRow) error {
+	columns := append([]string{"legacy_key", "rule_ids", "messages", "created_by", "updated_by"}, QUARANTINE_TABLES[table]...)
+	args := append([]any{row.LegacyKey, row.RuleIds, row.Messages, row.OperationUser, row.OperationUser}, row.Values...)
+
+	placeholders := make([]string, len(args))
+	for i := range args {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	sql := fmt.Sprintf("INSERT INTO quarantine.%s (%s) VALUES (%s);",
+		table,
+		strings.Join(columns, ", "),
+		strings.Join(placeholders, ", "),
+	)
+	_, err := queries.Raw(sql, args...).ExecContext(ctx, w.db)
+	return err
+}
+
+// FUNCTION: 変更履歴
+func (w *sqlCleanWriter) LogChanges(ctx context.Context, row ChangeLogRow) error {
+	columns := []string{"table_name", "legacy_key", "column_name", "original_value", "cleansed_value", "rule_id", "created_by", "updated_by"}
+
+	values := make([]string, len(row.Changes))
+	args := []any{}
+	for i, change := range row.Changes {
+		placeholders := make([]string, len(columns))
+		for j := range columns {
+			placeholders[j] = fmt.Sprintf("$%d", len(args)+j+1)
+		}
+		values[i] = fmt.Sprintf("(%s)", strings.Join(placeholders, ", "))
+		args = append(args, row.Table, row.LegacyKey, change.Column, change.Original, change.Cleansed, null.NewString(change.RuleId, change.RuleId != ""), row.OperationUser, row.OperationUser)
+	}
+	sql := fmt.Sprintf("INSERT INTO %s.%s (%s) VALUES %s;",
+		CLEAN_SCHEMA,
+		CHANGE_LOG_TABLE,
+		strings.Join(columns, ", "),
+		strings.Join(values, ", "),
+	)
+	_, err := queries.Raw(sql, args...).ExecContext(ctx, w.db)
+	return err
+}
@@ -0,0 +1,258 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+	"github.com/xuri/excelize/v2"
+)
+
+// TITLE: レビューブック(業務担当者による指摘確認用のExcel)
+
+// STRUCT: レビュー対象テーブル(シート順)
+var REVIEW_TABLES = []string{
+	legacy.TableNames.Operators,
+	legacy.TableNames.Products,
+	legacy.TableNames.Orders,
+	legacy.TableNames.OrderDetails,
+}
+
+// STRUCT: レビューシートの列名
+const REVIEW_KEY = "legacy_key"
+const REVIEW_PENDING = "pending_rule_ids"
+const REVIEW_APPROVE = "APPROVE"
+const REVIEW_APPROVED_BY = "approved_by"
+const REVIEW_COMMENT = "comment"
+
+// STRUCT: 承認の入力値
+const APPROVE_OK = "OK"
+
+// STRUCT: ルールシート名
+const RULES_SHEET = "rules"
+
+// STRUCT: 変更履歴シート名
+const CHANGES_SHEET = "change_log"
+
+// FUNCTION: レビューブックの出力(ルール毎の集計シート、テーブル毎の指摘シート、変更履歴シート)
+func (r Report) WriteReviewBook(filePath string) error {
+	book := excelize.NewFile()
+	defer book.Close()
+
+	styles, err := newReviewStyles(book)
+	if err != nil {
+		return fmt.Errorf("cannot create review book style: %s", err.Error())
+	}
+
+	// PROCESS: ルール毎の集計シート
+	if err := book.SetSheetName("Sheet1", RULES_SHEET); err != nil {
+		return fmt.Errorf("cannot create review sheet[%s]: %s", RULES_SHEET, err.Error())
+	}
+	if err := r.writeRulesSheet(book, styles); err != nil {
+		return fmt.Errorf("cannot write review sheet[%s]: %s", RULES_SHEET, err.Error())
+	}
+
+	// PROCESS: テーブル毎の指摘シート
+	for _, table := range REVIEW_TABLES {
+		if _, err := book.NewSheet(table); err != nil {
+			return fmt.Errorf("cannot create review sheet[%s]: %s", table, err.Error())
+		}
+		if err := r.writeTableSheet(book, styles, table); err != nil {
+			return fmt.Errorf("cannot write review sheet[%s]: %s", table, err.Error())
+		}
+	}
+
+	// PROCESS: 変更履歴シート
+	if _, err := book.NewSheet(CHANGES_SHEET); err != nil {
+		return fmt.Errorf("cannot create review sheet[%s]: %s", CHANGES_SHEET, err.Error())
+	}
+	if err := r.writeChangesSheet(book, styles); err != nil {
+		return fmt.Errorf("cannot write review sheet[%s]: %s", CHANGES_SHEET, err.Error())
+	}
+
+	// PROCESS: フォルダが存在しない場合作成する
+	if err := os.MkdirAll(filepath.Dir(filePath), 0777); err != nil {
+		return fmt.Errorf("cannot create directory: %s", err.Error())
+	}
+	if err := book.SaveAs(filePath); err != nil {
+		return fmt.Errorf("cannot save review book: %s", err.Error())
+	}
+	return nil
+}
+
+// FUNCTION: ルール毎の集計シート
+func (r Report) writeRulesSheet(book *excelize.File, styles reviewStyles) error {
+	header := []any{"RULE", "TABLE", "DESCRIPTION", "SEVERITY", "HIT", "APPROVED", "PENDING", "LINK"}
+	if err := book.SetSheetRow(RULES_SHEET, "A1", &header); err != nil {
+		return err
+	}
+	for i, stat := range r.Rules {
+		hit := any(stat.Hit)
+		if stat.Disabled {
+			hit = "(disabled)"
+		}
+		row := []any{stat.Id, stat.Table, stat.Description, stat.Severity, hit, stat.Approved, stat.Pending, stat.Link}
+		if err := book.SetSheetRow(RULES_SHEET, cellName(1, i+2), &row); err != nil {
+			return err
+		}
+	}
+	return decorateSheet(book, styles, RULES_SHEET, len(header), len(r.Rules), []float64{10, 16, 48, 10, 10, 10, 10, 60})
+}
+
+// FUNCTION: 変更履歴シート(MODIFY判定レコードのカラム毎の変更)
+func (r Report) writeChangesSheet(book *excelize.File, styles reviewStyles) error {
+	header := []any{"#", "table", REVIEW_KEY, "column", "original", "cleansed", "rule_id"}
+	if err := book.SetSheetRow(CHANGES_SHEET, "A1", &header); err != nil {
+		return err
+	}
+	count := 0
+	for _, finding := range r.Findings {
+		for _, change := range finding.Changes {
+			count++
+			row := []any{count, finding.Table, finding.LegacyKey, change.Column, change.Original, change.Cleansed, change.RuleLabel()}
+			if err := book.SetSheetRow(CHANGES_SHEET, cellName(1, count+1), &row); err != nil {
+				return err
+			}
+		}
+	}
+	return decorateSheet(book, styles, CHANGES_SHEET, len(header), count, []float64{6, 16, 16, 20, 20, 20, 12})
+}
+
+// FUNCTION: テーブル毎の指摘シート
+// INFO: 移行元/クレンジング後の値を並べ、値が変更されたセルは強調表示する。APPROVE/approved_by/commentは入力欄
+func (r Report) writeTableSheet(book *excelize.File, styles reviewStyles, table string) error {
+	columns := QUARANTINE_TABLES[table]
+
+	// PROCESS: ヘッダー
+	header := []any{"#", REVIEW_KEY, "rule_ids", REVIEW_PENDING, "result", "approved", "message"}
+	widths := []float64{6, 16, 12, 16, 10, 12, 60}
+	for _, column := range columns {
+		header = append(header, column+"(before)", column+"(after)")
+		widths = append(widths, 16, 16)
+	}
+	inputCol := len(header) + 1
+	header = append(header, REVIEW_APPROVE, REVIEW_APPROVED_BY, REVIEW_COMMENT)
+	widths = append(widths, 10, 16, 40)
+	if err := book.SetSheetRow(table, "A1", &header); err != nil {
+		return err
+	}
+
+	// PROCESS: 指摘
+	count := 0
+	changed := []string{}
+	for _, finding := range r.Findings {
+		if finding.Table != table {
+			continue
+		}
+		count++
+		row := []any{
+			count,
+			finding.LegacyKey,
+			strings.Join(uniqueIds(finding.RuleIds), ","),
+			strings.Join(uniqueIds(finding.Pending), ","),
+			finding.Result,
+			finding.Approve,
+			strings.Join(finding.Messages, "\n"),
+		}
+		for _, value := range finding.Values {
+			row = append(row, value.Original, value.Cleansed)
+		}
+		if err := book.SetSheetRow(table, cellName(1, count+1), &row); err != nil {
+			return err
+		}
+
+		for i, value := range finding.Values {
+			if value.Changed() {
+				changed = append(changed, cellName(9+i*2, count+1))
+			}
+		}
+	}
+
+	if err := decorateSheet(book, styles, table, len(header), count, widths); err != nil {
+		return err
+	}
+
+	// PROCESS: 変更されたセル(クレンジング後)の強調表示
+	for _, cell := range changed {
+		if err := book.SetCellStyle(table, cell, cell, styles.changed); err != nil {
+			return err
+		}
+	}
+
+	// PROCESS: 入力欄(ヘッダー/承認のドロップダウン)
+	if err := book.SetCellStyle(table, cellName(inputCol, 1), cellName(inputCol+2, 1), styles.input); err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	dv := excelize.NewDataValidation(true)
+	dv.Sqref = fmt.Sprintf("%s:%s", cellName(inputCol, 2), cellName(inputCol, count+1))
+	if err := dv.SetDropList([]string{APPROVE_OK}); err != nil {
+		return err
+	}
+	return book.AddDataValidation(table, dv)
+}
+
+// STRUCT: レビューブックのスタイル
+type reviewStyles struct {
+	header  int
+	input   int
+	changed int
+	wrap    int
+}
+
+// FUNCTION: スタイルの作成
+func newReviewStyles(book *excelize.File) (reviewStyles, error) {
+	fill := func(color string) excelize.Fill {
+		return excelize.Fill{Type: "pattern", Color: []string{color}, Pattern: 1}
+	}
+	var styles reviewStyles
+	var err error
+	if styles.header, err = book.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}, Fill: fill("#D9D9D9")}); err != nil {
+		return styles, err
+	}
+	if styles.input, err = book.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}, Fill: fill("#DDEBF7")}); err != nil {
+		return styles, err
+	}
+	if styles.changed, err = book.NewStyle(&excelize.Style{Fill: fill("#FFF2CC"), Alignment: &excelize.Alignment{Vertical: "top"}}); err != nil {
+		return styles, err
+	}
+	if styles.wrap, err = book.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{Vertical: "top", WrapText: true}}); err != nil {
+		return styles, err
+	}
+	return styles, nil
+}
+
+// FUNCTION: シートの共通装飾(ヘッダー/列幅/ウィンドウ枠の固定/オートフィルター)
+func decorateSheet(book *excelize.File, styles reviewStyles, sheet string, cols int, rows int, widths []float64) error {
+	if rows > 0 {
+		if err := book.SetCellStyle(sheet, "A2", cellName(cols, rows+1), styles.wrap); err != nil {
+			return err
+		}
+	}
+	if err := book.SetCellStyle(sheet, "A1", cellName(cols, 1), styles.header); err != nil {
+		return err
+	}
+	for i, width := range widths {
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		if err := book.SetColWidth(sheet, col, col, width); err != nil {
+			return err
+		}
+	}
+	if err := book.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	return book.AutoFilter(sheet, fmt.Sprintf("A1:%s", cellName(cols, rows+1)), nil)
+}
+
+// FUNCTION: セル名
+func cellName(col int, row int) string {
+	name, _ := excelize.CoordinatesToCellName(col, row)
+	return name
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+)
+
+// TITLE: クレンジングルール(docs/cleansing-spec.mdのConstraints)
+
+// STRUCT: クレンジング仕様書
+const SPEC_URL = "https://github.com/teru-0529/data-transfer-sandbox/blob/main/docs/cleansing-spec.md"
+
+// STRUCT: クレンジングルール(severityは仕様書の対応方針)
+type Rule struct {
+	Id          string
+	Table       string
+	Description string
+	Severity    string
+	Section     string
+}
+
+// FUNCTION: 仕様書のリンク(テーブル毎の節)
+func (r Rule) SpecLink() string {
+	return fmt.Sprintf("%s#%s", SPEC_URL, url.PathEscape(r.Section))
+}
+
+// STRUCT: クレンジングルール一覧
+var RULES = []Rule{
+	{"#1-01", legacy.TableNames.Operators, "担当者名が一意ではない", "REMOVE", "1-担当者operators"},
+	{"#1-02", legacy.TableNames.Operators, "担当者IDが5桁に満たない", "MODIFY", "1-担当者operators"},
+	{"#2-01", legacy.TableNames.Products, "商品原価がマイナス", "MODIFY", "2-商品products"},
+	{"#3-01", legacy.TableNames.Orders, "受注日付が日付型ではない", "MODIFY", "3-受注orders"},
+	{"#3-02", legacy.TableNames.Orders, "受注担当者名が「担当者」に存在しない", "MODIFY", "3-受注orders"},
+	{"#4-01", legacy.TableNames.OrderDetails, "出荷済フラグ/キャンセルフラグが両方ともTrue", "REMOVE", "4-受注明細order_details"},
+	{"#4-02", legacy.TableNames.OrderDetails, "受注番号が「受注」に存在しない", "REMOVE", "4-受注明細order_details"},
+	{"#4-03", legacy.TableNames.OrderDetails, "商品名が「商品」に存在しない", "REMOVE", "4-受注明細order_details"},
+}
+
+// STRUCT: クレンジングルール(DISABLED_RULESで無効化できる)
+var RULE_IDS = ruleIds()
+
+// FUNCTION:
+func ruleIds() []string {
+	ids := make([]string, len(RULES))
+	for i, rule := range RULES {
+		ids[i] = rule.Id
+	}
+	return ids
+}
+
+// STRUCT: ルール毎の集計
+type RuleStat struct {
+	Id          string `json:"rule_id"`
+	Table       string `json:"table"`
+	Description string `json:"description"`
+	Severity    string `json:"severity"`
+	Link        string `json:"link"`
+	Disabled    bool   `json:"disabled"`
+	Hit         int    `json:"hit"`
+	Approved    int    `json:"approved"`
+	Pending     int    `json:"pending"`
+}
+
+// FUNCTION: ルール毎の集計(件数0)
+func newRuleStats(ctx infra.AppCtx) []RuleStat {
+	stats := make([]RuleStat, len(RULES))
+	for i, rule := range RULES {
+		stats[i] = RuleStat{
+			Id:          rule.Id,
+			Table:       rule.Table,
+			Description: rule.Description,
+			Severity:    rule.Severity,
+			Link:        rule.SpecLink(),
+			Disabled:    !ctx.RuleEnabled(rule.Id),
+		}
+	}
+	return stats
+}
+
+// FUNCTION: 集計の表示
+func showRuleStats(ctx infra.AppCtx, stats []RuleStat) string {
+	msg := "\n## Cleansing Rule Summary\n\n"
+	msg += "  | RULE | TABLE | DESCRIPTION | SEVERITY | HIT | APPROVED | PENDING |\n"
+	msg += "  |---|---|---|:-:|--:|--:|--:|\n"
+	for _, stat := range stats {
+		hit := ctx.Printer.Sprintf("%d", stat.Hit)
+		if stat.Disabled {
+			hit = "(disabled)"
+		}
+		msg += fmt.Sprintf("  | [%s](%s) | %s | %s | %s | %s | %s | %s |\n",
+			stat.Id,
+			stat.Link,
+			stat.Table,
+			stat.Description,
+			stat.Severity,
+			hit,
+			ctx.Printer.Sprintf("%d", stat.Approved),
+			ctx.Printer.Sprintf("%d", stat.Pending),
+		)
+	}
+	return msg
+}
@@ -0,0 +1,135 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+)
+
+// TITLE: スキーマ差分の検証(LegacyDB/cleanスキーマとモデルの比較)
+
+// STRUCT: cleanスキーマ
+const CLEAN_SCHEMA = "clean"
+
+// STRUCT: モデル定義
+type modelDef struct {
+	table  string
+	fields []infra.ModelField
+}
+
+// STRUCT: 移行元のモデル定義
+var LEGACY_MODELS = []modelDef{
+	{legacy.TableNames.Operators, infra.ModelFields(legacy.Operator{})},
+	{legacy.TableNames.Products, infra.ModelFields(legacy.Product{})},
+	{legacy.TableNames.Orders, infra.ModelFields(legacy.Order{})},
+	{legacy.TableNames.OrderDetails, infra.ModelFields(legacy.OrderDetail{})},
+}
+
+// STRUCT: cleanスキーマのモデル定義
+var CLEAN_MODELS = []modelDef{
+	{clean.TableNames.Operators, infra.ModelFields(clean.Operator{})},
+	{clean.TableNames.Products, infra.ModelFields(clean.Product{})},
+	{clean.TableNames.Orders, infra.ModelFields(clean.Order{})},
+	{clean.TableNames.OrderDetails, infra.ModelFields(clean.OrderDetail{})},
+}
+
+// STRUCT: 検証結果
+type SchemaCheck struct {
+	LegacyDatabase string
+	Legacy         []infra.ColumnDrift
+	Clean          []infra.ColumnDrift
+}
+
+// FUNCTION: スキーマ差分の検証
+func CheckSchema(ctx context.Context, config infra.Config, conns infra.DbConnection) (SchemaCheck, error) {
+	check := SchemaCheck{LegacyDatabase: config.LegacyDB.Database}
+
+	// PROCESS: LegacyDBと移行元モデルの比較
+	legacySchema, err := infra.FetchSchema(ctx, conns.LegacyDB, "mysql", config.LegacyDB.Database)
+	if err != nil {
+		return check, err
+	}
+	for _, model := range LEGACY_MODELS {
+		check.Legacy = append(check.Legacy, infra.CompareModel(model.table, model.fields, legacySchema))
+	}
+
+	// PROCESS: cleanスキーマ(DDL)とcleanモデルの比較
+	cleanSchema, err := infra.FetchSchema(ctx, conns.WorkDB, "postgres", CLEAN_SCHEMA)
+	if err != nil {
+		return check, err
+	}
+	for _, model := range CLEAN_MODELS {
+		check.Clean = append(check.Clean, infra.CompareModel(model.table, model.fields, cleanSchema))
+	}
+	return check, nil
+}
+
+// FUNCTION: クレンジングを中止すべき差分
+func (c SchemaCheck) Breaking() []string {
+	reasons := []string{}
+	for _, group := range []struct {
+		schema string
+		drifts []infra.ColumnDrift
+	}{
+		{c.LegacyDatabase, c.Legacy},
+		{CLEAN_SCHEMA, c.Clean},
+	} {
+		for _, drift := range group.drifts {
+			if len(drift.Missing) > 0 {
+				reasons = append(reasons, fmt.Sprintf("%s.%s: missing columns %v", group.schema, drift.Table, drift.Missing))
+			}
+			for _, mismatch := range drift.Mismatch {
+				reasons = append(reasons, fmt.Sprintf("%s.%s.%s", group.schema, drift.Table, mismatch))
+			}
+		}
+	}
+	return reasons
+}
+
+// FUNCTION: 検証結果のメッセージ
+func (c SchemaCheck) Report() string {
+	msg := "\n## Schema Drift Check\n"
+	msg += fmt.Sprintf("\n### LegacyDB(%s) vs legacy models\n", c.LegacyDatabase)
+	msg += driftTable(c.LegacyDatabase, c.Legacy)
+	msg += "\n### WorkDB(clean) vs clean models\n"
+	msg += driftTable(CLEAN_SCHEMA, c.Clean)
+
+	// PROCESS: 中止理由
+	if reasons := c.Breaking(); len(reasons) > 0 {
+		msg += "\n<span style=\"color:red;\">**BREAKING DRIFT**</span>\n\n"
+		for _, reason := range reasons {
+			msg += fmt.Sprintf("- %s\n", reason)
+		}
+	}
+	return msg
+}
+
+// FUNCTION: 差分の表
+func driftTable(schema string, drifts []infra.ColumnDrift) string {
+	msg := "\n  | TABLE | MISSING COLUMNS | EXTRA COLUMNS | TYPE/NULLABLE | RESULT |\n"
+	msg += "  |---|---|---|---|:-:|\n"
+	for _, drift := range drifts {
+		result := "⭕"
+		if drift.IsBreaking() {
+			result = "⛔"
+		} else if drift.HasDrift() {
+			result = "⚠"
+		}
+		msg += fmt.Sprintf("  | %s.%s | %s | %s | %s | %s |\n",
+			schema,
+			drift.Table,
+			strings.Join(drift.Missing, "<br>"),
+			strings.Join(drift.Extra, "<br>"),
+			strings.Join(drift.Mismatch, "<br>"),
+			result,
+		)
+	}
+	return msg
+}
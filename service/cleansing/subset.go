@@ -0,0 +1,189 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// TITLE: サブセット抽出(受注を起点に参照整合性を保った移行元データの抽出)
+
+// STRUCT: サブセットの抽出条件(指定した条件はすべて満たす受注を起点とする)
+type SubsetSpec struct {
+	From     string //受注日(開始、DATE_LAYOUT形式)
+	To       string //受注日(終了、DATE_LAYOUT形式)
+	OrderNos []int  //受注番号
+	Limit    int    //起点とする受注の最大件数(受注番号順、0の場合は無制限)
+}
+
+// STRUCT: サブセット(抽出対象のキー)
+type Subset struct {
+	Spec         SubsetSpec
+	OrderNos     []int    //受注/受注明細(受注番号)
+	ProductNames []string //商品(受注明細の商品名)
+	OperatorIDs  []string //担当者(受注担当者名、商品担当者の個別指定値)
+}
+
+// FUNCTION: 抽出条件の指定あり
+func (spec SubsetSpec) Enabled() bool {
+	return spec.From != "" || spec.To != "" || len(spec.OrderNos) > 0 || spec.Limit > 0
+}
+
+// FUNCTION: サブセットの解決(マッピング定義/個別指定値で置換される値も参照先に含める)
+func ResolveSubset(ctx context.Context, config infra.Config, db *sql.DB, spec SubsetSpec) (*Subset, error) {
+	mapping, err := LoadNameMapping(config.Base.MappingFile)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := infra.LoadOverrides(config.Base.OverrideDir)
+	if err != nil {
+		return nil, err
+	}
+	subset := &Subset{Spec: spec, OrderNos: []int{}, ProductNames: []string{}, OperatorIDs: []string{}}
+
+	// PROCESS: 起点となる受注
+	orders, err := legacy.Orders(append(subset.mods(legacy.TableNames.Orders), qm.OrderBy("order_no ASC"))...).All(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch subset orders: %s", err.Error())
+	}
+	operatorNames := []string{}
+	for _, order := range orders {
+		subset.OrderNos = append(subset.OrderNos, order.OrderNo)
+		operatorNames = appendName(operatorNames, mapping.OperatorName, order.OrderPic)
+		if value, exist := overrides.Lookup("#3-02", strconv.Itoa(order.OrderNo)); exist {
+			operatorNames = appendUnique(operatorNames, value)
+		}
+	}
+
+	// PROCESS: 受注明細が参照する商品
+	details, err := legacy.OrderDetails(subset.mods(legacy.TableNames.OrderDetails)...).All(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch subset order_details: %s", err.Error())
+	}
+	for _, detail := range details {
+		subset.ProductNames = appendName(subset.ProductNames, mapping.ProductName, detail.ProductName)
+	}
+
+	// PROCESS: 受注担当者名、商品担当者(個別指定値)が参照する担当者
+	operators, err := legacy.Operators(whereIn("operator_name", operatorNames)).All(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch subset operators: %s", err.Error())
+	}
+	for _, operator := range operators {
+		subset.OperatorIDs = appendUnique(subset.OperatorIDs, operator.OperatorID)
+	}
+	for _, name := range subset.ProductNames {
+		if value, exist := overrides.Lookup("product_pic", name); exist {
+			subset.OperatorIDs = appendUnique(subset.OperatorIDs, value)
+		}
+	}
+	return subset, nil
+}
+
+// FUNCTION: テーブル毎の抽出条件(サブセット未指定の場合は条件なし)
+// INFO: 受注/受注明細は受注番号を列挙せず起点条件のサブクエリで絞り込む(プレースホルダ数の上限65535を超えないため)
+func (s *Subset) mods(table string) []qm.QueryMod {
+	if s == nil {
+		return []qm.QueryMod{}
+	}
+	switch table {
+	case legacy.TableNames.Operators:
+		return []qm.QueryMod{whereIn("operator_id", s.OperatorIDs)}
+	case legacy.TableNames.Products:
+		return []qm.QueryMod{whereIn("product_name", s.ProductNames)}
+	case legacy.TableNames.Orders, legacy.TableNames.OrderDetails:
+		query, args := s.Spec.seedQuery()
+		return []qm.QueryMod{qm.Where(fmt.Sprintf("order_no IN (SELECT order_no FROM (%s) AS seed)", query), args...)}
+	}
+	return []qm.QueryMod{}
+}
+
+// FUNCTION: 起点となる受注の受注番号を抽出するSQL
+// INFO: MariaDBはIN句のサブクエリでLIMITを使えないため、呼び出し側で導出テーブルとして囲む
+func (spec SubsetSpec) seedQuery() (string, []any) {
+	conditions := []string{}
+	args := []any{}
+	if spec.From != "" {
+		conditions = append(conditions, "order_date >= ?")
+		args = append(args, spec.From)
+	}
+	if spec.To != "" {
+		conditions = append(conditions, "order_date <= ?")
+		args = append(args, spec.To)
+	}
+	if len(spec.OrderNos) > 0 {
+		conditions = append(conditions, fmt.Sprintf("order_no IN (%s)", strings.TrimSuffix(strings.Repeat("?,", len(spec.OrderNos)), ",")))
+		for _, no := range spec.OrderNos {
+			args = append(args, no)
+		}
+	}
+	query := fmt.Sprintf("SELECT order_no FROM %s", legacy.TableNames.Orders)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY order_no ASC"
+	if spec.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", spec.Limit)
+	}
+	return query, args
+}
+
+// FUNCTION: サブセットのメッセージ
+func (s *Subset) Report() string {
+	if s == nil {
+		return ""
+	}
+	orderNos := make([]string, len(s.Spec.OrderNos))
+	for i, no := range s.Spec.OrderNos {
+		orderNos[i] = strconv.Itoa(no)
+	}
+	msg := "\n## Subset\n\n"
+	msg += fmt.Sprintf("- **order date**: %s - %s\n", s.Spec.From, s.Spec.To)
+	msg += fmt.Sprintf("- **order no**: %s\n", strings.Join(orderNos, ", "))
+	msg += fmt.Sprintf("- **limit**: %d\n\n", s.Spec.Limit)
+	msg += "  | TABLE | KEY | COUNT |\n"
+	msg += "  |---|---|--:|\n"
+	msg += fmt.Sprintf("  | %s/%s | order_no | %d |\n", legacy.TableNames.Orders, legacy.TableNames.OrderDetails, len(s.OrderNos))
+	msg += fmt.Sprintf("  | %s | product_name | %d |\n", legacy.TableNames.Products, len(s.ProductNames))
+	msg += fmt.Sprintf("  | %s | operator_id | %d |\n", legacy.TableNames.Operators, len(s.OperatorIDs))
+	return msg
+}
+
+// FUNCTION: IN句(値がない場合は該当なし、商品/担当者などマスタの件数に収まるキーに使用する)
+func whereIn[T any](column string, values []T) qm.QueryMod {
+	if len(values) == 0 {
+		return qm.Where("1 = 0")
+	}
+	args := make([]any, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return qm.WhereIn(fmt.Sprintf("%s IN ?", column), args...)
+}
+
+// FUNCTION: 値と承認済みのマッピング先を追加
+func appendName(names []string, entries []MappingEntry, value string) []string {
+	names = appendUnique(names, value)
+	if entry, mapped := lookupMapping(entries, value); mapped && entry.Approved {
+		names = appendUnique(names, entry.Canonical)
+	}
+	return names
+}
+
+// FUNCTION: 重複なしで追加
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
@@ -0,0 +1,56 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// TITLE: サブセット抽出
+
+// FUNCTION: 受注/受注明細の抽出条件(解決済みの受注番号の件数によらず起点条件のサブクエリになる)
+func TestSubsetModsOrders(t *testing.T) {
+	orderNos := make([]int, 70000)
+	for i := range orderNos {
+		orderNos[i] = i + 1
+	}
+	tests := []struct {
+		name  string
+		spec  SubsetSpec
+		query string
+		args  []any
+	}{
+		{
+			name:  "date range",
+			spec:  SubsetSpec{From: "20250101", To: "20250131"},
+			query: "(SELECT order_no FROM orders WHERE order_date >= ? AND order_date <= ? ORDER BY order_no ASC)",
+			args:  []any{"20250101", "20250131"},
+		},
+		{
+			name:  "order no and limit",
+			spec:  SubsetSpec{OrderNos: []int{1001, 1002}, Limit: 10},
+			query: "(SELECT order_no FROM orders WHERE order_no IN (?,?) ORDER BY order_no ASC LIMIT 10)",
+			args:  []any{1001, 1002},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subset := &Subset{Spec: tt.spec, OrderNos: orderNos}
+			for _, table := range []string{legacy.TableNames.Orders, legacy.TableNames.OrderDetails} {
+				query, args := queries.BuildQuery(legacy.OrderDetails(subset.mods(table)...).Query)
+				if !strings.Contains(query, "order_no IN (SELECT order_no FROM "+tt.query+" AS seed)") {
+					t.Errorf("%s: query = %s", table, query)
+				}
+				if !slices.Equal(args, tt.args) {
+					t.Errorf("%s: args = %v, want %v", table, args, tt.args)
+				}
+			}
+		})
+	}
+}
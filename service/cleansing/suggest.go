@@ -0,0 +1,147 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package cleansing
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+	"gopkg.in/yaml.v3"
+)
+
+// TITLE: 候補提示(あいまい検索)
+
+// STRUCT: 候補の最大提示件数
+const SUGGEST_LIMIT int = 3
+
+// STRUCT: 候補
+type Suggestion struct {
+	Value    string
+	Distance int
+}
+
+// FUNCTION: 候補の表示
+func (s Suggestion) str() string {
+	return fmt.Sprintf("`%s`(距離:%d)", s.Value, s.Distance)
+}
+
+// FUNCTION: 比較用の正規化(全角/半角の統一、空白除去、小文字化)
+func normalize(str string) string {
+	str = norm.NFKC.String(str)
+	str = strings.Join(strings.Fields(str), "")
+	return strings.ToLower(str)
+}
+
+// FUNCTION: 編集距離(レーベンシュタイン距離)
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// FUNCTION: 候補の抽出(正規化後の編集距離が文字数の半分以下のものを、距離の昇順で最大limit件)
+func suggest(value string, candidates map[string]struct{}, limit int) []Suggestion {
+	target := normalize(value)
+	threshold := max(1, len([]rune(target))/2)
+
+	results := []Suggestion{}
+	for candidate := range candidates {
+		distance := editDistance(target, normalize(candidate))
+		if distance <= threshold {
+			results = append(results, Suggestion{Value: candidate, Distance: distance})
+		}
+	}
+
+	// INFO: 距離:昇順、値:昇順
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Distance != results[j].Distance {
+			return results[i].Distance < results[j].Distance
+		}
+		return results[i].Value < results[j].Value
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// FUNCTION: 候補メッセージ
+func suggestionMsg(suggestions []Suggestion) string {
+	if len(suggestions) == 0 {
+		return "<br>【候補】なし"
+	}
+	strs := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		strs[i] = s.str()
+	}
+	return fmt.Sprintf("<br>【候補】%s", strings.Join(strs, ", "))
+}
+
+// STRUCT: マッピング定義(移行元の値→正規の値)
+type MappingEntry struct {
+	Legacy    string `yaml:"legacy"`
+	Canonical string `yaml:"canonical"`
+	Approved  bool   `yaml:"approved"`
+}
+
+// STRUCT: マッピングファイル
+type NameMapping struct {
+	OperatorName []MappingEntry `yaml:"operator_name"` //担当者名
+	ProductName  []MappingEntry `yaml:"product_name"`  //商品名
+}
+
+// FUNCTION: マッピングファイルの読込み(ファイルが存在しない場合は空のマッピング)
+func LoadNameMapping(filePath string) (*NameMapping, error) {
+	mapping := &NameMapping{}
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return mapping, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read mapping file: %s", err.Error())
+	}
+
+	if err := yaml.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("cannot parse mapping file[%s]: %s", filePath, err.Error())
+	}
+	return mapping, nil
+}
+
+// FUNCTION: マッピングの検索
+func lookupMapping(entries []MappingEntry, legacy string) (MappingEntry, bool) {
+	for _, entry := range entries {
+		if entry.Legacy == legacy {
+			return entry, true
+		}
+	}
+	return MappingEntry{}, false
+}
+
+// FUNCTION: マッピング定義/候補メッセージ(未承認のマッピング定義がある場合は併記する)
+func mappingMsg(entry MappingEntry, mapped bool, suggestions []Suggestion) string {
+	msg := suggestionMsg(suggestions)
+	if mapped && !entry.Approved {
+		msg += fmt.Sprintf("<br>【マッピング定義(未承認)】`%s`", entry.Canonical)
+	} else if mapped {
+		msg += fmt.Sprintf("<br>【マッピング定義(マッピング先が存在しません)】`%s`", entry.Canonical)
+	}
+	return msg
+}
@@ -0,0 +1,40 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service/cleansing"
+)
+
+// TITLE: 設定の検証
+
+// FUNCTION: 設定値の検証(設定値の整合性、マッピングファイル、ダンプ定義ファイル/マスキングのソルト、クレンジングルール)
+func ValidateConfig(config infra.Config) []error {
+	errs := config.Validate()
+
+	// PROCESS: マッピングファイル
+	if _, err := cleansing.LoadNameMapping(config.Base.MappingFile); err != nil {
+		errs = append(errs, fmt.Errorf("MAPPING_FILE: %v", err))
+	}
+
+	// PROCESS: ダンプ定義ファイル(マスキングルールを含む場合はソルトが必須)
+	if dumps, err := infra.LoadDumpProfiles(config.Base.DumpFile); err != nil {
+		errs = append(errs, fmt.Errorf("DUMP_FILE: %v", err))
+	} else if err := infra.ValidateMaskingSalt(dumps, config.Base.MaskingSalt); err != nil {
+		errs = append(errs, err)
+	}
+
+	// PROCESS: 無効化するクレンジングルールの存在チェック(前後の空白は無視する)
+	for _, id := range config.Run.DisabledRules {
+		if id = strings.TrimSpace(id); id != "" && !slices.Contains(cleansing.RULE_IDS, id) {
+			errs = append(errs, fmt.Errorf("DISABLED_RULES contains unknown rule id: `%s`", id))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,70 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package service
+
+import (
+	"path"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+)
+
+// TITLE: 設定の検証
+
+// FUNCTION: 無効化するクレンジングルールの検証(実行時と同じく前後の空白/空のIDは無視する)
+func TestValidateConfigDisabledRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		want  []string
+	}{
+		{name: "valid", rules: []string{"#1-01", "#3-02"}, want: []string{}},
+		{name: "spaces after comma", rules: []string{"#1-01", " #3-02"}, want: []string{}},
+		{name: "empty id", rules: []string{"#1-01", " ", ""}, want: []string{}},
+		{name: "unknown rule id", rules: []string{"#1-01", " #9-99 "}, want: []string{"DISABLED_RULES contains unknown rule id: `#9-99`"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := infra.Config{Run: infra.RunConfig{DisabledRules: tt.rules}}
+			got := []string{}
+			for _, err := range ValidateConfig(config) {
+				if strings.HasPrefix(err.Error(), "DISABLED_RULES") {
+					got = append(got, err.Error())
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// FUNCTION: マスキングのソルトの検証(マスキングルールを含むダンプ定義がある場合は必須)
+func TestValidateConfigMaskingSalt(t *testing.T) {
+	tests := []struct {
+		name string
+		salt string
+		want []string
+	}{
+		{name: "salt is set", salt: "secret", want: []string{}},
+		{name: "salt is empty", salt: "", want: []string{"MASKING_SALT is required for dumps with masks: `dml-local`"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// INFO: ダンプ定義ファイルが存在しない場合は既定のダンプ定義(dml-localにマスキングルールあり)
+			config := infra.Config{Base: infra.BaseConfig{DumpFile: path.Join(t.TempDir(), "dump-profiles.yaml"), MaskingSalt: tt.salt}}
+			got := []string{}
+			for _, err := range ValidateConfig(config) {
+				if strings.HasPrefix(err.Error(), "MASKING_SALT") {
+					got = append(got, err.Error())
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package generate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
+)
+
+// TITLE: 合成データ生成(移行元DBのテスト/デモ用データ、クレンジングルール毎に不正データを混入する)
+
+// STRUCT: 混入する不正データ(クレンジングルールID、ルール以外の検証観点)
+var INJECTIONS = []struct {
+	Id   string
+	Desc string
+}{
+	{"#1-01", "duplicate operator_name"},
+	{"#1-02", "operator_id shorter than 5 characters"},
+	{"#2-01", "negative cost_price"},
+	{"#3-01", "invalid order_date string"},
+	{"#3-02", "order_pic not in operators"},
+	{"#4-01", "shipping_flag and canceled_flag are both true"},
+	{"#4-02", "order_details without orders (orphan)"},
+	{"#4-03", "product_name not in products"},
+	{"split", "same product at different selling prices within one order"},
+}
+
+// STRUCT: 不正な受注日付
+var INVALID_DATES = []string{"20241301", "20240230", "2024/01/15", "N/A", ""}
+
+// STRUCT: INSERT文の1文あたりの最大行数
+const BATCH_SIZE = 1000
+
+// STRUCT: 生成条件
+type Spec struct {
+	Operators  int                //担当者数(9999以下)
+	Products   int                //商品数(9999以下)
+	Orders     int                //受注数(受注番号は99999以下)
+	MaxDetails int                //受注あたりの最大明細数
+	Seed       uint64             //乱数シード(同じシードは同じデータ)
+	ErrorRate  float64            //不正データの混入率(既定値)
+	RuleRates  map[string]float64 //不正データの混入率(ルール毎)
+	DateLayout string             //受注日付のフォーマット
+}
+
+// STRUCT: 生成データ
+type Dataset struct {
+	Spec         Spec
+	Operators    []legacy.Operator
+	Products     []legacy.Product
+	Orders       []legacy.Order
+	OrderDetails []legacy.OrderDetail
+	Injected     map[string]int //混入した不正データ数
+}
+
+// FUNCTION: 生成条件の検証
+func (spec Spec) Validate() error {
+	switch {
+	case spec.Operators < 1 || spec.Products < 1 || spec.Orders < 1 || spec.MaxDetails < 1:
+		return fmt.Errorf("operators/products/orders/max-details must be greater than 0")
+	case spec.Operators > 9999 || spec.Products > 9999:
+		return fmt.Errorf("operators/products must be 9999 or less")
+	case spec.Orders > 99999:
+		return fmt.Errorf("orders must be 99999 or less: %d", spec.Orders)
+	case spec.ErrorRate < 0 || spec.ErrorRate > 1:
+		return fmt.Errorf("error rate must be 0-1: %v", spec.ErrorRate)
+	}
+	for id, rate := range spec.RuleRates {
+		if !injectionExists(id) {
+			return fmt.Errorf("unknown rule id for error rate: `%s`", id)
+		}
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("error rate of %s must be 0-1: %v", id, rate)
+		}
+	}
+	return nil
+}
+
+// FUNCTION: 混入対象のルールIDの存在判定
+func injectionExists(id string) bool {
+	for _, injection := range INJECTIONS {
+		if injection.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// FUNCTION: 混入率(ルール毎の指定がない場合は既定値)
+func (spec Spec) rate(id string) float64 {
+	if rate, exist := spec.RuleRates[id]; exist {
+		return rate
+	}
+	return spec.ErrorRate
+}
+
+// STRUCT: ジェネレータ
+type generator struct {
+	spec     Spec
+	rnd      *rand.Rand
+	injected map[string]int
+}
+
+// FUNCTION: 不正データの混入判定(混入する場合は件数を加算)
+func (g *generator) inject(id string) bool {
+	if g.rnd.Float64() >= g.spec.rate(id) {
+		return false
+	}
+	g.injected[id]++
+	return true
+}
+
+// FUNCTION: データ生成
+func Generate(spec Spec) Dataset {
+	g := &generator{spec: spec, rnd: rand.New(rand.NewPCG(spec.Seed, spec.Seed)), injected: map[string]int{}}
+	data := Dataset{Spec: spec, Injected: g.injected}
+
+	// PROCESS: 担当者(#1-01:名前の重複、#1-02:5桁未満のID)
+	for i := 1; i <= spec.Operators; i++ {
+		operator := legacy.Operator{OperatorID: fmt.Sprintf("O%04d", i), OperatorName: fmt.Sprintf("担当者%04d", i)}
+		if g.inject("#1-02") {
+			operator.OperatorID = strconv.Itoa(i % 10000)
+		}
+		if i > 1 && g.inject("#1-01") {
+			operator.OperatorName = data.Operators[g.rnd.IntN(len(data.Operators))].OperatorName
+		}
+		data.Operators = append(data.Operators, operator)
+	}
+
+	// PROCESS: 商品(#2-01:マイナスの原価)
+	for i := 1; i <= spec.Products; i++ {
+		product := legacy.Product{ProductName: fmt.Sprintf("商品%04d", i), CostPrice: (g.rnd.IntN(100) + 1) * 100}
+		if g.inject("#2-01") {
+			product.CostPrice = -product.CostPrice
+		}
+		data.Products = append(data.Products, product)
+	}
+
+	// PROCESS: 受注(#3-01:不正な日付、#3-02:存在しない担当者)/受注明細
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for no := 1; no <= spec.Orders; no++ {
+		order := legacy.Order{
+			OrderNo:      no,
+			OrderDate:    start.AddDate(0, 0, g.rnd.IntN(366)).Format(spec.DateLayout),
+			OrderPic:     data.Operators[g.rnd.IntN(len(data.Operators))].OperatorName,
+			CustomerName: fmt.Sprintf("得意先%03d", g.rnd.IntN(500)+1),
+		}
+		if g.inject("#3-01") {
+			order.OrderDate = INVALID_DATES[g.rnd.IntN(len(INVALID_DATES))]
+		}
+		if g.inject("#3-02") {
+			order.OrderPic = fmt.Sprintf("退職者%03d", g.rnd.IntN(100)+1)
+		}
+		data.Orders = append(data.Orders, order)
+		data.OrderDetails = append(data.OrderDetails, g.details(no, data.Products)...)
+	}
+
+	// PROCESS: 受注明細(#4-02:受注が存在しない明細、受注数×混入率の受注番号分)
+	orphans := min(int(float64(spec.Orders)*spec.rate("#4-02")), 99999-spec.Orders)
+	for no := spec.Orders + 1; no <= spec.Orders+orphans; no++ {
+		details := g.details(no, data.Products)
+		g.injected["#4-02"] += len(details)
+		data.OrderDetails = append(data.OrderDetails, details...)
+	}
+	return data
+}
+
+// FUNCTION: 受注明細の生成(#4-01:出荷済かつキャンセル、#4-03:存在しない商品、split:同一商品の異なる販売単価)
+func (g *generator) details(orderNo int, products []legacy.Product) []legacy.OrderDetail {
+	details := []legacy.OrderDetail{}
+	count := g.rnd.IntN(g.spec.MaxDetails) + 1
+	for i := 1; i <= count; i++ {
+		product := products[g.rnd.IntN(len(products))]
+		cost := max(product.CostPrice, 0)
+		detail := legacy.OrderDetail{
+			OrderNo:           orderNo,
+			OrderDetailNo:     len(details) + 1,
+			ProductName:       product.ProductName,
+			ReceivingQuantity: g.rnd.IntN(20) + 1,
+			ShippingFlag:      g.rnd.IntN(2) == 0,
+			CanceledFlag:      g.rnd.IntN(20) == 0,
+			SellingPrice:      cost * 13 / 10,
+			CostPrice:         cost,
+		}
+		// INFO: 混入しない場合は出荷済かつキャンセルにしない(混入率0で#4-01に該当させない)
+		if g.inject("#4-01") {
+			detail.ShippingFlag, detail.CanceledFlag = true, true
+		} else if detail.ShippingFlag {
+			detail.CanceledFlag = false
+		}
+		if g.inject("#4-03") {
+			detail.ProductName = fmt.Sprintf("廃番商品%03d", g.rnd.IntN(100)+1)
+		}
+		details = append(details, detail)
+
+		// INFO: 同一受注内に同じ商品を異なる販売単価で追加する(受注番号の分割対象)
+		if g.inject("split") {
+			split := detail
+			split.OrderDetailNo = len(details) + 1
+			split.SellingPrice = detail.SellingPrice + (g.rnd.IntN(10)+1)*10
+			details = append(details, split)
+
+			// INFO: 複製元の不正データは複製した明細でも件数に含める
+			if split.ShippingFlag && split.CanceledFlag {
+				g.injected["#4-01"]++
+			}
+			if split.ProductName != product.ProductName {
+				g.injected["#4-03"]++
+			}
+		}
+	}
+	return details
+}
+
+// FUNCTION: SQL文(MariaDB、TRUNCATE + 一括INSERT)
+func (data Dataset) Statements() []string {
+	statements := []string{}
+	for _, table := range []string{legacy.TableNames.OrderDetails, legacy.TableNames.Orders, legacy.TableNames.Products, legacy.TableNames.Operators} {
+		statements = append(statements, fmt.Sprintf("TRUNCATE TABLE %s;", table))
+	}
+
+	operators := make([][]string, len(data.Operators))
+	for i, r := range data.Operators {
+		operators[i] = []string{sqlStr(r.OperatorID), sqlStr(r.OperatorName)}
+	}
+	statements = append(statements, insertStatements(legacy.TableNames.Operators, infra.ModelColumns(legacy.OperatorColumns), operators)...)
+
+	products := make([][]string, len(data.Products))
+	for i, r := range data.Products {
+		products[i] = []string{sqlStr(r.ProductName), strconv.Itoa(r.CostPrice)}
+	}
+	statements = append(statements, insertStatements(legacy.TableNames.Products, infra.ModelColumns(legacy.ProductColumns), products)...)
+
+	orders := make([][]string, len(data.Orders))
+	for i, r := range data.Orders {
+		orders[i] = []string{strconv.Itoa(r.OrderNo), sqlStr(r.OrderDate), sqlStr(r.OrderPic), sqlStr(r.CustomerName)}
+	}
+	statements = append(statements, insertStatements(legacy.TableNames.Orders, infra.ModelColumns(legacy.OrderColumns), orders)...)
+
+	details := make([][]string, len(data.OrderDetails))
+	for i, r := range data.OrderDetails {
+		details[i] = []string{
+			strconv.Itoa(r.OrderNo),
+			strconv.Itoa(r.OrderDetailNo),
+			sqlStr(r.ProductName),
+			strconv.Itoa(r.ReceivingQuantity),
+			strconv.FormatBool(r.ShippingFlag),
+			strconv.FormatBool(r.CanceledFlag),
+			strconv.Itoa(r.SellingPrice),
+			strconv.Itoa(r.CostPrice),
+		}
+	}
+	statements = append(statements, insertStatements(legacy.TableNames.OrderDetails, infra.ModelColumns(legacy.OrderDetailColumns), details)...)
+	return statements
+}
+
+// FUNCTION: 一括INSERT文(BATCH_SIZE行単位)
+func insertStatements(table string, columns []string, rows [][]string) []string {
+	statements := []string{}
+	for start := 0; start < len(rows); start += BATCH_SIZE {
+		values := []string{}
+		for _, row := range rows[start:min(start+BATCH_SIZE, len(rows))] {
+			values = append(values, fmt.Sprintf("(%s)", strings.Join(row, ", ")))
+		}
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) VALUES\n%s;", table, strings.Join(columns, ", "), strings.Join(values, ",\n")))
+	}
+	return statements
+}
+
+// FUNCTION: 文字列リテラル(MariaDBのエスケープ)
+func sqlStr(str string) string {
+	str = strings.ReplaceAll(str, `\`, `\\`)
+	return "'" + strings.ReplaceAll(str, "'", "''") + "'"
+}
+
+// FUNCTION: SQLファイルの出力
+func (data Dataset) WriteSQL(filePath string) error {
+	return infra.WriteText(filePath, strings.Join(data.Statements(), "\n\n")+"\n")
+}
+
+// FUNCTION: 移行元DBへの登録(既存データはTRUNCATEする)
+func (data Dataset) Insert(ctx context.Context, db *sql.DB) error {
+	for _, statement := range data.Statements() {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("cannot insert generated data: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// FUNCTION: 生成結果のメッセージ
+func (data Dataset) Report() string {
+	msg := "\n## Generated Legacy Data\n\n"
+	msg += fmt.Sprintf("- **seed**: %d\n", data.Spec.Seed)
+	msg += fmt.Sprintf("- **error rate**: %v\n\n", data.Spec.ErrorRate)
+	msg += "  | TABLE | COUNT |\n"
+	msg += "  |---|--:|\n"
+	msg += fmt.Sprintf("  | %s | %d |\n", legacy.TableNames.Operators, len(data.Operators))
+	msg += fmt.Sprintf("  | %s | %d |\n", legacy.TableNames.Products, len(data.Products))
+	msg += fmt.Sprintf("  | %s | %d |\n", legacy.TableNames.Orders, len(data.Orders))
+	msg += fmt.Sprintf("  | %s | %d |\n", legacy.TableNames.OrderDetails, len(data.OrderDetails))
+	msg += "\n  | RULE | INJECTED ERROR | RATE | COUNT |\n"
+	msg += "  |---|---|--:|--:|\n"
+	for _, injection := range INJECTIONS {
+		msg += fmt.Sprintf("  | %s | %s | %v | %d |\n", injection.Id, injection.Desc, data.Spec.rate(injection.Id), data.Injected[injection.Id])
+	}
+	return msg
+}
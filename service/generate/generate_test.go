@@ -0,0 +1,53 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package generate
+
+import (
+	"testing"
+)
+
+// TITLE: 合成データ生成
+
+// FUNCTION: 受注明細の不正データ件数(混入率0の場合は該当しない、混入した件数と該当する明細数が一致する)
+func TestGenerateOrderDetails(t *testing.T) {
+	tests := []struct {
+		name      string
+		ruleRates map[string]float64
+	}{
+		{name: "rate 0", ruleRates: map[string]float64{"#4-01": 0, "#4-03": 0}},
+		{name: "default rate", ruleRates: map[string]float64{}},
+		{name: "rate 0.5", ruleRates: map[string]float64{"#4-01": 0.5, "#4-03": 0.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := Spec{Operators: 10, Products: 20, Orders: 2000, MaxDetails: 5, Seed: 42, ErrorRate: 0.1, RuleRates: tt.ruleRates, DateLayout: "20060102"}
+			if err := spec.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			data := Generate(spec)
+
+			products := map[string]struct{}{}
+			for _, product := range data.Products {
+				products[product.ProductName] = struct{}{}
+			}
+			hits := map[string]int{}
+			for _, detail := range data.OrderDetails {
+				if detail.ShippingFlag && detail.CanceledFlag {
+					hits["#4-01"]++
+				}
+				if _, exist := products[detail.ProductName]; !exist {
+					hits["#4-03"]++
+				}
+			}
+			for _, id := range []string{"#4-01", "#4-03"} {
+				if hits[id] != data.Injected[id] {
+					t.Errorf("%s: got %d rows, injected %d", id, hits[id], data.Injected[id])
+				}
+				if rate, exist := tt.ruleRates[id]; exist && rate == 0 && hits[id] != 0 {
+					t.Errorf("%s: got %d rows, want 0", id, hits[id])
+				}
+			}
+		})
+	}
+}
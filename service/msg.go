@@ -7,9 +7,10 @@ package service
 
 // STRUCT: レスポンスメッセージ
 type Message struct {
-	header string
-	main   string
-	detail string
+	header   string
+	main     string
+	detail   string
+	sections []string
 }
 
 // FUNCTION: 新規作成
@@ -22,6 +23,14 @@ func (m *Message) addHead(header string) {
 	m.header += header
 }
 
+// FUNCTION: ヘッダーメッセージの追加(構造化していないセクションとしてレポートにも出力する)
+func (m *Message) addSection(section string) {
+	m.addHead(section)
+	if section != "" {
+		m.sections = append(m.sections, section)
+	}
+}
+
 // FUNCTION: メインメッセージの追加
 func (m *Message) add(main, detail string) {
 	m.main += main
@@ -42,3 +51,17 @@ func (m *Message) str() string {
 	msg += "\n-----\n"
 	return msg
 }
+
+// FUNCTION: レポートの作成(タイトル/項目は呼出し元で設定する)
+// INFO: メッセージが空の場合(スキーマ差分の検証前のエラー等)は本文を出力しない
+func (m *Message) report() Report {
+	report := Report{
+		Items:    []ReportItem{},
+		Sections: append([]string{}, m.sections...),
+		Details:  m.detail,
+	}
+	if m.header != "" || m.main != "" || m.detail != "" {
+		report.Body = m.str()
+	}
+	return report
+}
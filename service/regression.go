@@ -0,0 +1,257 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service/cleansing"
+	"github.com/teru-0529/data-transfer-sandbox/service/generate"
+	"github.com/teru-0529/data-transfer-sandbox/service/transfer"
+)
+
+// TITLE: 回帰テスト(固定の移行元データでクレンジング/移行を実行し、結果をゴールデンファイルと比較)
+
+// STRUCT: 回帰テストの移行元データ(合成データ、シード固定)
+var REGRESSION_SPEC = generate.Spec{
+	Operators:  20,
+	Products:   30,
+	Orders:     200,
+	MaxDetails: 4,
+	Seed:       1,
+	ErrorRate:  0.05,
+}
+
+// STRUCT: スナップショットの対象外カラム(実行毎に変わる値、変更履歴IDは登録順の採番)
+var SNAPSHOT_EXCLUDES = []string{"created_at", "updated_at", "change_log_id"}
+
+// STRUCT: ゴールデンファイルのディレクトリ(ディレクトリ毎に全体を更新する)
+var GOLDEN_DIRS = []string{"clean", "product", "report"}
+
+// STRUCT: ゴールデンファイルの格納先(DBによる回帰テスト: regressコマンド、インメモリによる回帰テスト: go test)
+const DB_GOLDEN_DIR = "db"
+const MEMORY_GOLDEN_DIR = "memory"
+
+// STRUCT: レポートの正規化(処理時間、リトライ回数)
+var REPORT_NORMALIZERS = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`[\d,]+\.\d+s`), "-"},
+	{regexp.MustCompile(`\| - \| [\d,]+ \|`), "| - | - |"},
+}
+
+// STRUCT: スナップショット(ゴールデンファイルの相対パス → 内容)
+type Snapshot map[string]string
+
+// FUNCTION: 回帰テストの実行(移行元DBを合成データで置き換え、クレンジング/移行の結果を取得)
+func TakeSnapshot(run context.Context, config infra.Config, conns infra.DbConnection) (Snapshot, error) {
+	snapshot := Snapshot{}
+
+	// PROCESS: 移行元データの投入
+	spec := REGRESSION_SPEC
+	spec.DateLayout = config.Run.DateLayout
+	data := generate.Generate(spec)
+	if err := data.Insert(run, conns.LegacyDB); err != nil {
+		return nil, err
+	}
+	snapshot["report/generate.md"] = data.Report()
+
+	// PROCESS: クレンジング/移行
+	cleansingReport, err := Cleansing(run, config, conns, false, cleansing.SubsetSpec{})
+	if err != nil {
+		return nil, err
+	}
+	snapshot["report/cleansing.md"] = normalizeReport(cleansingReport.Body)
+	transferReport, err := Transfer(run, config, conns, false)
+	if err != nil {
+		return nil, err
+	}
+	snapshot["report/transfer.md"] = normalizeReport(transferReport.Body)
+	splitsJson, err := json.MarshalIndent(transferReport.Transfer.Splits, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	snapshot["report/order-splits.json"] = string(splitsJson) + "\n"
+	if run.Err() != nil {
+		return nil, fmt.Errorf("regression interrupted: %v", run.Err())
+	}
+
+	// PROCESS: cleanスキーマ(w_orders/w_order_detailsビューを含む)、移行先スキーマの内容
+	for _, target := range []struct {
+		dir    string
+		db     *sql.DB
+		schema string
+	}{
+		{"clean", conns.WorkDB, "clean"},
+		{"product", conns.ProductDB, transfer.SCHEMA},
+	} {
+		tables, err := infra.Relations(run, target.db, target.schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range tables {
+			content, err := infra.SnapshotTable(run, target.db, target.schema, table, SNAPSHOT_EXCLUDES)
+			if err != nil {
+				return nil, err
+			}
+			snapshot[fmt.Sprintf("%s/%s.tsv", target.dir, table)] = content
+		}
+	}
+
+	// PROCESS: 件数(JSON)
+	counts := RowCounts{}
+	for _, target := range []struct {
+		db      *sql.DB
+		schemas []string
+	}{
+		{conns.WorkDB, WORK_SCHEMAS},
+		{conns.ProductDB, PRODUCT_SCHEMAS},
+	} {
+		result, err := CountRows(run, target.db, target.schemas)
+		if err != nil {
+			return nil, err
+		}
+		for name, count := range result {
+			counts[name] = count
+		}
+	}
+	countsJson, err := json.MarshalIndent(counts, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	snapshot["report/row-counts.json"] = string(countsJson) + "\n"
+	return snapshot, nil
+}
+
+// FUNCTION: レポートの正規化
+func normalizeReport(msg string) string {
+	for _, normalizer := range REPORT_NORMALIZERS {
+		msg = normalizer.pattern.ReplaceAllString(msg, normalizer.replace)
+	}
+	return msg
+}
+
+// FUNCTION: ゴールデンファイルの出力(対象ディレクトリは削除して再作成)
+func (s Snapshot) Save(dir string) error {
+	for _, sub := range GOLDEN_DIRS {
+		if err := os.RemoveAll(path.Join(dir, sub)); err != nil {
+			return fmt.Errorf("cannot remove golden directory: %s", err.Error())
+		}
+	}
+	for _, name := range s.names() {
+		if err := infra.WriteText(path.Join(dir, name), s[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FUNCTION: ゴールデンファイルとの比較(差分がある場合はエラー)
+func (s Snapshot) Compare(dir string) (string, error) {
+	msg := "\n## Golden File Comparison\n\n"
+	msg += "  | # | FILE | RESULT | DIFF |\n"
+	msg += "  |--:|---|:-:|---|\n"
+
+	// PROCESS: ゴールデンファイルのみに存在するファイルも差分とする
+	names := s.names()
+	goldens, err := goldenFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(goldens) == 0 {
+		return msg, fmt.Errorf("regression failed: no golden files in %s (use --update and commit them)", dir)
+	}
+	for _, name := range goldens {
+		if _, exist := s[name]; !exist {
+			names = append(names, name)
+		}
+	}
+
+	ngCount := 0
+	for i, name := range names {
+		result, diff := "⭕", ""
+		golden, err := os.ReadFile(path.Join(dir, name))
+		actual, exist := s[name]
+		switch {
+		case !exist:
+			result, diff = "❌", "not generated (stale golden file)"
+		case err != nil:
+			result, diff = "❌", "golden file not found"
+		case string(golden) != actual:
+			result, diff = "❌", firstDiff(string(golden), actual)
+		}
+		if result != "⭕" {
+			ngCount++
+		}
+		msg += fmt.Sprintf("  | %d. | %s | %s | %s |\n", i+1, name, result, diff)
+	}
+
+	if ngCount > 0 {
+		return msg, fmt.Errorf("regression failed: %d file(s) differ from golden (use --update and review `git diff`)", ngCount)
+	}
+	return msg, nil
+}
+
+// FUNCTION: ファイル名の一覧(ソート済み)
+func (s Snapshot) names() []string {
+	names := []string{}
+	for name := range s {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// FUNCTION: ゴールデンファイルの一覧(ディレクトリからの相対パス)
+func goldenFiles(dir string) ([]string, error) {
+	names := []string{}
+	for _, sub := range GOLDEN_DIRS {
+		entries, err := os.ReadDir(path.Join(dir, sub))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot read golden directory: %s", err.Error())
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names = append(names, filepath.ToSlash(path.Join(sub, entry.Name())))
+			}
+		}
+	}
+	return names, nil
+}
+
+// FUNCTION: 最初の差分行(`行番号: golden → actual`)
+func firstDiff(golden string, actual string) string {
+	goldenLines, actualLines := strings.Split(golden, "\n"), strings.Split(actual, "\n")
+	for i := 0; i < max(len(goldenLines), len(actualLines)); i++ {
+		var g, a string
+		if i < len(goldenLines) {
+			g = goldenLines[i]
+		}
+		if i < len(actualLines) {
+			a = actualLines[i]
+		}
+		if g != a {
+			return fmt.Sprintf("line %d: `%s` → `%s` (lines: %d → %d)", i+1, mdCell(g), mdCell(a), len(goldenLines), len(actualLines))
+		}
+	}
+	return ""
+}
+
+// FUNCTION: 表のセルに出力する値(`|`、タブのエスケープ)
+func mdCell(str string) string {
+	return strings.NewReplacer("|", `\|`, "\t", " ", "`", "'").Replace(str)
+}
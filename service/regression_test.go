@@ -0,0 +1,201 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package service
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service/cleansing"
+	"github.com/teru-0529/data-transfer-sandbox/service/generate"
+	"github.com/teru-0529/data-transfer-sandbox/service/transfer"
+)
+
+// TITLE: 回帰テスト(インメモリリポジトリ)
+
+// STRUCT: ゴールデンファイルの更新(`go test ./service -run TestRegression -update`)
+var updateGolden = flag.Bool("update", false, "rewrite golden files with current results. (review with `git diff`)")
+
+// STRUCT: ゴールデンファイルのディレクトリ(マッピング定義/個別指定値を含む)
+const GOLDEN_DIR = "../testdata/golden"
+
+// FUNCTION: 固定シードの合成データでクレンジング/移行を実行し、ゴールデンファイルと比較する
+func TestRegression(t *testing.T) {
+	snapshot, err := takeMemorySnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := path.Join(GOLDEN_DIR, MEMORY_GOLDEN_DIR)
+	if *updateGolden {
+		if err := snapshot.Save(dir); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("golden files updated [%s] … %d files", dir, len(snapshot))
+		return
+	}
+	if msg, err := snapshot.Compare(dir); err != nil {
+		t.Fatalf("%v\n%s", err, msg)
+	}
+}
+
+// FUNCTION: スナップショットの取得(インメモリリポジトリ)
+func takeMemorySnapshot() (Snapshot, error) {
+	snapshot := Snapshot{}
+
+	// PROCESS: 移行元データ
+	spec := REGRESSION_SPEC
+	spec.DateLayout = "20060102"
+	data := generate.Generate(spec)
+	snapshot["report/generate.md"] = data.Report()
+
+	// PROCESS: マッピング定義/個別指定値(全ルールを有効にする)
+	mapping, err := cleansing.LoadNameMapping(path.Join(GOLDEN_DIR, "name-mapping.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := infra.LoadOverrides(path.Join(GOLDEN_DIR, "overrides"))
+	if err != nil {
+		return nil, err
+	}
+	ctx := infra.NewCtx(context.Background(), infra.RunConfig{FetchLimit: 100, DateLayout: spec.DateLayout}, 0, overrides)
+
+	// PROCESS: クレンジング
+	reader := &cleansing.MemoryLegacyReader{
+		Operators:    data.Operators,
+		Products:     data.Products,
+		Orders:       data.Orders,
+		OrderDetails: data.OrderDetails,
+	}
+	cleanWriter := cleansing.NewMemoryCleanWriter()
+	refData := cleansing.NewRefData(mapping, cleansing.ReinstateStore{}, cleansing.ApprovalStore{})
+	cleansingReport := cleanse(NewMessage(), cleansing.NewController(ctx, reader, cleanWriter, refData, nil))
+	snapshot["report/cleansing.md"] = normalizeReport(cleansingReport.Body)
+
+	// PROCESS: 移行(受注/受注明細はcleanスキーマの集約結果)
+	source := &transfer.MemoryCleanReader{
+		Operators:    cleanWriter.Operators,
+		Products:     cleanWriter.Products,
+		Orders:       cleanWriter.Orders,
+		OrderDetails: cleanWriter.OrderDetails,
+	}
+	productWriter := &transfer.MemoryProductWriter{}
+	controller, err := transfer.NewController(ctx, source, productWriter)
+	if err != nil {
+		return nil, err
+	}
+	transferReport := transferAll(NewMessage(), controller)
+	snapshot["report/transfer.md"] = normalizeReport(transferReport.Body)
+	splitsJson, err := json.MarshalIndent(transferReport.Transfer.Splits, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	snapshot["report/order-splits.json"] = string(splitsJson) + "\n"
+
+	// PROCESS: cleanスキーマ(隔離データ/変更履歴、w_orders/w_order_detailsビューと同じ集約結果を含む)、移行先スキーマの内容
+	snapshot["clean/operators.tsv"] = modelRows(cleanWriter.Operators)
+	snapshot["clean/products.tsv"] = modelRows(cleanWriter.Products)
+	snapshot["clean/orders.tsv"] = modelRows(cleanWriter.Orders)
+	snapshot["clean/order_details.tsv"] = modelRows(cleanWriter.OrderDetails)
+	snapshot["clean/w_orders.tsv"] = modelRows(transfer.AggregateOrders(cleanWriter.Operators, cleanWriter.Orders, cleanWriter.OrderDetails))
+	snapshot["clean/w_order_details.tsv"] = modelRows(transfer.AggregateOrderDetails(cleanWriter.Products, cleanWriter.OrderDetails))
+	for table, rows := range cleanWriter.Quarantined {
+		snapshot[fmt.Sprintf("clean/quarantine.%s.tsv", table)] = quarantineRows(table, rows)
+	}
+	snapshot["clean/change_log.tsv"] = changeLogRows(cleanWriter.ChangeLogs)
+	snapshot["product/operators.tsv"] = modelRows(productWriter.Operators)
+	snapshot["product/products.tsv"] = modelRows(productWriter.Products)
+	snapshot["product/orders.tsv"] = modelRows(productWriter.Orders)
+	snapshot["product/order_details.tsv"] = modelRows(productWriter.OrderDetails)
+	return snapshot, nil
+}
+
+// FUNCTION: モデルの内容(infra.SnapshotTableと同じ形式、カラムはboilタグ、SNAPSHOT_EXCLUDESのカラムは除外)
+func modelRows[T any](models []T) string {
+	modelType := reflect.TypeFor[T]()
+	header, indexes := []string{}, []int{}
+	for i := 0; i < modelType.NumField(); i++ {
+		name := modelType.Field(i).Tag.Get("boil")
+		if name == "" || name == "-" || modelType.Field(i).Name == "R" || modelType.Field(i).Name == "L" || slices.Contains(SNAPSHOT_EXCLUDES, name) {
+			continue
+		}
+		header, indexes = append(header, name), append(indexes, i)
+	}
+
+	rows := make([][]string, len(models))
+	for i, model := range models {
+		value := reflect.ValueOf(model)
+		rows[i] = make([]string, len(indexes))
+		for j, index := range indexes {
+			rows[i][j] = fieldStr(value.Field(index).Interface())
+		}
+	}
+	return tsv(header, rows)
+}
+
+// FUNCTION: カラム値のテキスト(NULLは`\N`、日付は`2006-01-02`)
+func fieldStr(value any) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil || v == nil {
+			return infra.NULL_STR
+		}
+		value = v
+	}
+	if t, ok := value.(time.Time); ok {
+		return t.Format("2006-01-02")
+	}
+	return fmt.Sprint(value)
+}
+
+// FUNCTION: 隔離データの内容
+func quarantineRows(table string, quarantined []cleansing.QuarantineRow) string {
+	header := append([]string{"legacy_key", "rule_ids", "messages"}, cleansing.QUARANTINE_TABLES[table]...)
+	rows := make([][]string, len(quarantined))
+	for i, row := range quarantined {
+		rows[i] = []string{row.LegacyKey, row.RuleIds, row.Messages}
+		for _, value := range row.Values {
+			rows[i] = append(rows[i], fieldStr(value))
+		}
+	}
+	return tsv(header, rows)
+}
+
+// FUNCTION: 変更履歴の内容
+func changeLogRows(changeLogs map[string][]cleansing.ChangeLogRow) string {
+	header := []string{"table_name", "legacy_key", "column_name", "original_value", "cleansed_value", "rule_id"}
+	rows := [][]string{}
+	for _, logs := range changeLogs {
+		for _, row := range logs {
+			for _, change := range row.Changes {
+				rows = append(rows, []string{row.Table, row.LegacyKey, change.Column, change.Original, change.Cleansed, change.RuleLabel()})
+			}
+		}
+	}
+	return tsv(header, rows)
+}
+
+// FUNCTION: タブ区切り(1行目はカラム名、行はソート)
+func tsv(header []string, rows [][]string) string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		values := make([]string, len(row))
+		for j, value := range row {
+			values[j] = infra.TSV_ESCAPER.Replace(value)
+		}
+		lines[i] = strings.Join(values, "\t")
+	}
+	slices.Sort(lines)
+	return strings.Join(append([]string{strings.Join(header, "\t")}, lines...), "\n") + "\n"
+}
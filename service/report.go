@@ -0,0 +1,124 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/service/cleansing"
+	"github.com/teru-0529/data-transfer-sandbox/service/transfer"
+)
+
+// TITLE: レポート出力(markdown/HTML/JSON)
+
+// STRUCT: レポート
+// INFO: Bodyはmarkdownの本文(従来のログ)、Sections/Detailsは構造化していないmarkdownのセクション/詳細
+type Report struct {
+	Title     string            `json:"title"`
+	Items     []ReportItem      `json:"items"`
+	Sections  []string          `json:"sections"`
+	Cleansing *cleansing.Report `json:"cleansing,omitempty"`
+	Transfer  *transfer.Report  `json:"transfer,omitempty"`
+	Details   string            `json:"-"`
+	Body      string            `json:"-"`
+}
+
+// STRUCT: レポートの項目(alertは強調表示)
+type ReportItem struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Alert bool   `json:"alert,omitempty"`
+}
+
+// FUNCTION: 項目の追加
+func (r *Report) AddItem(key string, value string) {
+	r.Items = append(r.Items, ReportItem{Key: key, Value: value})
+}
+
+// FUNCTION: 項目の追加(複数)
+func (r *Report) AddItems(items ...ReportItem) {
+	r.Items = append(r.Items, items...)
+}
+
+// FUNCTION: 項目のmarkdown
+func ItemsMsg(items []ReportItem) string {
+	msg := ""
+	for _, item := range items {
+		value := item.Value
+		if item.Alert {
+			value = fmt.Sprintf("<span style=\"color:red;\">%s</span>", value)
+		}
+		msg += fmt.Sprintf("- **%s**: %s\n", item.Key, value)
+	}
+	return msg
+}
+
+// STRUCT: レンダラー
+type Renderer interface {
+	Ext() string
+	Render(report Report) (string, error)
+}
+
+// STRUCT: 出力するレンダラー(ログファイル名の拡張子毎)
+var RENDERERS = []Renderer{MarkdownRenderer{}, HtmlRenderer{}, JsonRenderer{}}
+
+// FUNCTION: レポートの出力(レンダラー毎に{name}{ext}に出力、timestampを指定した場合は履歴も出力)
+func (r Report) Write(dir string, name string, timestamp *time.Time) error {
+	for _, renderer := range RENDERERS {
+		content, err := renderer.Render(r)
+		if err != nil {
+			return fmt.Errorf("cannot render report(%s): %s", renderer.Ext(), err.Error())
+		}
+		if err := infra.WriteLog(path.Join(dir, name+renderer.Ext()), content, timestamp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FUNCTION: レポートのファイル名(レンダラー毎)
+func ReportFiles(name string) []string {
+	files := make([]string, len(RENDERERS))
+	for i, renderer := range RENDERERS {
+		files[i] = name + renderer.Ext()
+	}
+	return files
+}
+
+// STRUCT: markdown(従来のログ)
+type MarkdownRenderer struct{}
+
+// FUNCTION:
+func (MarkdownRenderer) Ext() string {
+	return ".md"
+}
+
+// FUNCTION:
+func (MarkdownRenderer) Render(report Report) (string, error) {
+	msg := fmt.Sprintf("# %s\n\n", report.Title)
+	msg += ItemsMsg(report.Items)
+	msg += report.Body
+	return msg, nil
+}
+
+// STRUCT: JSON(構造化データ)
+type JsonRenderer struct{}
+
+// FUNCTION:
+func (JsonRenderer) Ext() string {
+	return ".json"
+}
+
+// FUNCTION:
+func (JsonRenderer) Render(report Report) (string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
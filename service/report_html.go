@@ -0,0 +1,413 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package service
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/service/cleansing"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// TITLE: レポート出力(HTML)
+
+// STRUCT: HTML(単一ファイルで完結するダッシュボード)
+// INFO: 指摘は並べ替え/絞込み可能なルール毎の表、受入率はテーブル毎の帯グラフ、レコード毎のメッセージは折りたたみで表示する
+type HtmlRenderer struct{}
+
+// FUNCTION:
+func (HtmlRenderer) Ext() string {
+	return ".html"
+}
+
+// FUNCTION:
+func (HtmlRenderer) Render(report Report) (string, error) {
+	page := htmlPage{Report: report}
+
+	// PROCESS: 構造化していないセクション/詳細
+	converter := &mdConverter{}
+	for _, section := range report.Sections {
+		page.Sections = append(page.Sections, converter.convert(section))
+	}
+	if report.Cleansing == nil && report.Details != "" {
+		page.Details = converter.convert(report.Details)
+	}
+
+	// PROCESS: ルール毎の指摘
+	if report.Cleansing != nil {
+		keys, groups := report.Cleansing.FindingsByRule()
+		for _, key := range keys {
+			rule := htmlRule{RuleId: key, Findings: groups[key]}
+			if i := slices.IndexFunc(report.Cleansing.Rules, func(stat cleansing.RuleStat) bool { return stat.Id == key }); i >= 0 {
+				rule.Stat = &report.Cleansing.Rules[i]
+			}
+			page.Rules = append(page.Rules, rule)
+		}
+	}
+
+	var sb strings.Builder
+	if err := HTML_TEMPLATE.Execute(&sb, page); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// STRUCT: テンプレートの入力
+type htmlPage struct {
+	Report   Report
+	Sections []template.HTML
+	Details  template.HTML
+	Rules    []htmlRule
+}
+
+// STRUCT: ルール毎の指摘(RuleIdが空の場合はルールIDのない指摘)
+type htmlRule struct {
+	RuleId   string
+	Stat     *cleansing.RuleStat
+	Findings []cleansing.Finding
+}
+
+// FUNCTION: 要素ID
+func (r htmlRule) Anchor() string {
+	if r.RuleId == "" {
+		return "rule-other"
+	}
+	return "rule-" + strings.TrimPrefix(r.RuleId, "#")
+}
+
+// FUNCTION: 見出し
+func (r htmlRule) Name() string {
+	if r.RuleId == "" {
+		return "(no rule id)"
+	}
+	return r.RuleId
+}
+
+// STRUCT: 数値の表示(3桁区切り)
+var HTML_PRINTER = message.NewPrinter(language.Japanese)
+
+// STRUCT: テンプレート関数
+var HTML_FUNCS = template.FuncMap{
+	"num": func(v int) string {
+		return HTML_PRINTER.Sprintf("%d", v)
+	},
+	"signed": func(v int) string {
+		return HTML_PRINTER.Sprintf("%+d", v)
+	},
+	"rate": func(v float64) string {
+		return fmt.Sprintf("%3.1f%%", v)
+	},
+	"sec": func(v float64) string {
+		return fmt.Sprintf("%3.2fs", v)
+	},
+	"ratio": func(v int, total int) string {
+		if total == 0 {
+			return "0%"
+		}
+		return fmt.Sprintf("%.2f%%", float64(v)/float64(total)*100)
+	},
+	"css": func(v string) template.CSS {
+		return template.CSS(v)
+	},
+	"join": strings.Join,
+}
+
+// STRUCT: テンプレート
+var HTML_TEMPLATE = template.Must(template.New("report").Funcs(HTML_FUNCS).Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>{{.Report.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { border-bottom: 2px solid #444; }
+h2 { border-bottom: 1px solid #999; margin-top: 2em; }
+table { border-collapse: collapse; margin: 0.5em 0 1em; }
+th, td { border: 1px solid #bbb; padding: 4px 8px; vertical-align: top; }
+th { background: #eee; }
+table.sortable th { cursor: pointer; user-select: none; }
+table.sortable th[data-order="asc"]::after { content: " ▲"; }
+table.sortable th[data-order="desc"]::after { content: " ▼"; }
+td.num { text-align: right; }
+td.center { text-align: center; }
+.alert { color: #c00; font-weight: bold; }
+.filter { margin: 0.5em 0; padding: 4px; width: 20em; }
+.chart { display: flex; width: 40em; height: 1.2em; border: 1px solid #999; }
+.chart div { height: 100%; }
+.unchange { background: #4caf50; }
+.modify { background: #ffb300; }
+.remove { background: #e53935; }
+.legend span { display: inline-block; width: 1em; height: 1em; margin: 0 0.3em 0 1em; vertical-align: middle; }
+.MODIFY { color: #b26a00; }
+.REMOVE, .CHECK { color: #c00; }
+summary { cursor: pointer; }
+code { background: #f4f4f4; padding: 0 3px; }
+</style>
+</head>
+<body>
+<h1>{{.Report.Title}}</h1>
+<ul>
+{{- range .Report.Items}}
+<li><strong>{{.Key}}</strong>: {{if .Alert}}<span class="alert">{{.Value}}</span>{{else}}{{.Value}}{{end}}</li>
+{{- end}}
+</ul>
+{{range .Sections}}{{.}}{{end}}
+{{- with .Report.Cleansing}}
+<h2>Legacy Data Check and Cleansing</h2>
+<table class="sortable">
+<thead><tr><th>#</th><th>TABLE</th><th>ENTRY</th><th>ELAPSED</th><th>RETRY</th><th>UNCHANGE</th><th>MODIFY</th><th>REMOVE</th><th>DB CHECK</th><th>ACCEPT</th><th>RATE</th></tr></thead>
+<tbody>
+{{- range .Tables}}
+<tr><td class="num">{{.Num}}</td><td>{{.Table}}({{.TableJp}}){{if .Interrupted}} <span class="alert">(INTERRUPTED)</span>{{end}}</td><td class="num">{{num .Entry}}</td><td class="num">{{sec .Elapsed}}</td><td class="num">{{num .Retry}}</td><td class="num">{{num .Unchange}}</td><td class="num">{{num .Modify}}</td><td class="num">{{num .Remove}}</td><td class="num">{{if .DbCheck}}<span class="alert">{{num .DbCheck}}</span>{{else}}0{{end}}</td><td class="num">{{num .Accept}}</td><td class="num">{{rate .AcceptRate}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Accept Rate</h2>
+<p class="legend"><span class="unchange"></span>UNCHANGE<span class="modify"></span>MODIFY<span class="remove"></span>REMOVE</p>
+<table>
+{{- range .Tables}}
+<tr><td>{{.Table}}({{.TableJp}})</td><td><div class="chart" title="{{rate .AcceptRate}}"><div class="unchange" style="width: {{css (ratio .Unchange .Entry)}}"></div><div class="modify" style="width: {{css (ratio .Modify .Entry)}}"></div><div class="remove" style="width: {{css (ratio .Remove .Entry)}}"></div></div></td><td class="num">{{rate .AcceptRate}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Cleansing Rule Summary</h2>
+<table class="sortable">
+<thead><tr><th>RULE</th><th>TABLE</th><th>DESCRIPTION</th><th>SEVERITY</th><th>HIT</th><th>APPROVED</th><th>PENDING</th></tr></thead>
+<tbody>
+{{- range .Rules}}
+<tr><td><a href="{{.Link}}">{{.Id}}</a></td><td>{{.Table}}</td><td>{{.Description}}</td><td class="center {{.Severity}}">{{.Severity}}</td><td class="num">{{if .Disabled}}(disabled){{else}}{{num .Hit}}{{end}}</td><td class="num">{{num .Approved}}</td><td class="num">{{num .Pending}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- with .ChangeStats}}
+
+<h2>Cleansing Change Log</h2>
+<table class="sortable">
+<thead><tr><th>TABLE</th><th>COLUMN</th><th>RULE</th><th>CHANGES</th></tr></thead>
+<tbody>
+{{- range .}}
+<tr><td>{{.Table}}</td><td>{{.Column}}</td><td>{{.RuleId}}</td><td class="num">{{num .Count}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- end}}
+{{- if .Rules}}
+
+<h2>Findings by Rule</h2>
+{{- range .Rules}}
+<h3 id="{{.Anchor}}">{{.Name}} ({{len .Findings}}){{with .Stat}} <a href="{{.Link}}">{{.Description}}</a>{{end}}</h3>
+<input class="filter" type="search" placeholder="filter ..." data-table="{{.Anchor}}-table">
+<table class="sortable" id="{{.Anchor}}-table">
+<thead><tr><th>TABLE</th><th>LEGACY KEY</th><th>RESULT</th><th>OVERRIDE</th><th>APPROVED</th><th>RULES</th><th>CHANGES</th><th>MESSAGE</th></tr></thead>
+<tbody>
+{{- range .Findings}}
+<tr><td>{{.Table}}</td><td>{{.LegacyKey}}</td><td class="center {{.Result}}">{{.Result}}</td><td class="center">{{if .Override}}OVERRIDE{{end}}</td><td class="center {{.Approve}}">{{.Approve}}</td><td>{{join .RuleIds ", "}}</td><td>{{range .Changes}}{{.Column}}: {{.Original}} → {{.Cleansed}} ({{.RuleLabel}})<br>{{end}}</td><td><details><summary>{{len .Messages}} message(s)</summary><ul>{{range .Messages}}<li>{{.}}</li>{{end}}</ul></details></td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- end}}
+{{- with .Report.Transfer}}
+
+<h2>Data Transfer to Production DB</h2>
+<table class="sortable">
+<thead><tr><th>#</th><th>SCHEMA</th><th>TABLE</th><th>ENTRY</th><th>ELAPSED</th><th>RETRY</th><th>CHANGE</th><th>ACCEPT</th><th>CHECK</th></tr></thead>
+<tbody>
+{{- range .Tables}}
+<tr><td class="num">{{.Num}}</td><td>{{.Schema}}</td><td>{{.Table}}({{.TableJp}}){{if .Interrupted}} <span class="alert">(INTERRUPTED)</span>{{end}}</td><td class="num">{{num .Entry}}</td><td class="num">{{sec .Elapsed}}</td><td class="num">{{num .Retry}}</td><td class="num">{{signed .Change}}</td><td class="num">{{num .Accept}}</td><td class="center">{{if not .Consistent}}<span class="alert">NG</span>{{end}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- if .Details}}
+
+<h2>Details</h2>
+<details><summary>(open) modify and remove detail info</summary>
+{{.Details}}
+</details>
+{{- end}}
+<script>
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var tbody = th.closest("table").tBodies[0];
+    var index = Array.prototype.indexOf.call(th.parentNode.children, th);
+    var asc = th.dataset.order !== "asc";
+    th.parentNode.querySelectorAll("th").forEach(function (h) { delete h.dataset.order; });
+    th.dataset.order = asc ? "asc" : "desc";
+    var value = function (row) { return row.cells[index].innerText.trim(); };
+    var rows = Array.prototype.slice.call(tbody.rows).sort(function (a, b) {
+      var x = value(a), y = value(b);
+      var nx = parseFloat(x.replace(/[,%s+]/g, "")), ny = parseFloat(y.replace(/[,%s+]/g, ""));
+      var c = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y, "ja");
+      return asc ? c : -c;
+    });
+    rows.forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+document.querySelectorAll("input.filter").forEach(function (input) {
+  input.addEventListener("input", function () {
+    var query = input.value.toLowerCase();
+    var table = document.getElementById(input.dataset.table);
+    Array.prototype.forEach.call(table.tBodies[0].rows, function (row) {
+      row.style.display = row.innerText.toLowerCase().indexOf(query) >= 0 ? "" : "none";
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// STRUCT: markdownの変換(ログのmarkdownで使用している見出し/表/箇条書き/区切り線のみ)
+// INFO: テキストはエスケープし、ツールが出力する固定のHTML(MD_ALLOWED_HTML)のみ復元する(移行元の値に含まれるHTMLは出力しない)
+type mdConverter struct {
+	tables int
+}
+
+// STRUCT: インライン要素
+var MD_CODE = regexp.MustCompile("`([^`]*)`")
+var MD_STRONG = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+var MD_HEADING = regexp.MustCompile(`^(#{1,6}) (.*)$`)
+var MD_SEPARATOR = regexp.MustCompile(`^:?-+:?$`)
+
+// STRUCT: ツールが出力する固定のHTML(エスケープ後に復元する)
+var MD_ALLOWED_HTML = []string{
+	"<br>",
+	`<span style="color:red;">`,
+	`<span style="color:orange;">`,
+	"</span>",
+}
+
+// STRUCT: 固定のHTMLの復元
+var MD_HTML_RESTORER = func() *strings.Replacer {
+	pairs := []string{}
+	for _, fragment := range MD_ALLOWED_HTML {
+		pairs = append(pairs, html.EscapeString(fragment), fragment)
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// FUNCTION: 変換
+func (c *mdConverter) convert(md string) template.HTML {
+	var sb strings.Builder
+	lines := strings.Split(md, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "|"):
+			// PROCESS: 表(連続する行)
+			rows := []string{}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, strings.TrimSpace(lines[i]))
+			}
+			i--
+			sb.WriteString(c.table(rows))
+		case strings.HasPrefix(line, "- "):
+			// PROCESS: 箇条書き(連続する行)
+			sb.WriteString("<ul>\n")
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "- "); i++ {
+				sb.WriteString(fmt.Sprintf("<li>%s</li>\n", inline(strings.TrimPrefix(strings.TrimSpace(lines[i]), "- "))))
+			}
+			i--
+			sb.WriteString("</ul>\n")
+		case MD_HEADING.MatchString(line):
+			m := MD_HEADING.FindStringSubmatch(line)
+			sb.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", len(m[1]), inline(m[2]), len(m[1])))
+		case strings.Trim(line, "-") == "":
+			sb.WriteString("<hr>\n")
+		case strings.HasPrefix(line, "<"):
+			sb.WriteString(inline(line) + "\n")
+		default:
+			sb.WriteString(fmt.Sprintf("<p>%s</p>\n", inline(line)))
+		}
+	}
+	return template.HTML(sb.String())
+}
+
+// FUNCTION: 表(2行目が区切り行の場合は見出し行とする、並べ替え/絞込み可能)
+func (c *mdConverter) table(rows []string) string {
+	c.tables++
+	id := fmt.Sprintf("md-table-%d", c.tables)
+
+	var head []string
+	aligns := []string{}
+	if len(rows) > 1 && isSeparator(cells(rows[1])) {
+		head = cells(rows[0])
+		for _, cell := range cells(rows[1]) {
+			switch {
+			case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+				aligns = append(aligns, "center")
+			case strings.HasSuffix(cell, ":"):
+				aligns = append(aligns, "num")
+			default:
+				aligns = append(aligns, "")
+			}
+		}
+		rows = rows[2:]
+	}
+
+	var sb strings.Builder
+	if len(rows) > 5 {
+		sb.WriteString(fmt.Sprintf("<input class=\"filter\" type=\"search\" placeholder=\"filter ...\" data-table=\"%s\">\n", id))
+	}
+	sb.WriteString(fmt.Sprintf("<table class=\"sortable\" id=\"%s\">\n", id))
+	if head != nil {
+		sb.WriteString("<thead><tr>")
+		for _, cell := range head {
+			sb.WriteString(fmt.Sprintf("<th>%s</th>", inline(cell)))
+		}
+		sb.WriteString("</tr></thead>\n")
+	}
+	sb.WriteString("<tbody>\n")
+	for _, row := range rows {
+		sb.WriteString("<tr>")
+		for i, cell := range cells(row) {
+			class := ""
+			if i < len(aligns) && aligns[i] != "" {
+				class = fmt.Sprintf(" class=\"%s\"", aligns[i])
+			}
+			sb.WriteString(fmt.Sprintf("<td%s>%s</td>", class, inline(cell)))
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("</tbody>\n</table>\n")
+	return sb.String()
+}
+
+// FUNCTION: 表のセル
+func cells(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	results := strings.Split(row, "|")
+	for i, cell := range results {
+		results[i] = strings.TrimSpace(cell)
+	}
+	return results
+}
+
+// FUNCTION: 区切り行
+func isSeparator(cells []string) bool {
+	for _, cell := range cells {
+		if !MD_SEPARATOR.MatchString(cell) {
+			return false
+		}
+	}
+	return true
+}
+
+// FUNCTION: インライン要素(エスケープ後に固定のHTMLを復元、コード/強調)
+func inline(text string) string {
+	text = MD_HTML_RESTORER.Replace(html.EscapeString(text))
+	text = MD_CODE.ReplaceAllString(text, "<code>$1</code>")
+	return MD_STRONG.ReplaceAllString(text, "<strong>$1</strong>")
+}
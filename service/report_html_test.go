@@ -0,0 +1,46 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// TITLE: レポート出力(HTML)
+
+// FUNCTION: インライン要素(エスケープ、固定のHTMLのみ復元)
+func TestInline(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "担当者", "担当者"},
+		{"code and strong", "`AB1` **REMOVE**", "<code>AB1</code> <strong>REMOVE</strong>"},
+		{"tool html", "<span style=\"color:red;\">error</span><br>next", "<span style=\"color:red;\">error</span><br>next"},
+		{"legacy value html", "`<script>alert(1)</script>`", "<code>&lt;script&gt;alert(1)&lt;/script&gt;</code>"},
+		{"other attributes", "<span style=\"color:red;\" onclick=\"x()\">a</span>", "&lt;span style=&#34;color:red;&#34; onclick=&#34;x()&#34;&gt;a</span>"},
+		{"ampersand and quote", "A&B 'C'", "A&amp;B &#39;C&#39;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inline(tt.text); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// FUNCTION: 表のセルもエスケープする
+func TestConvertTable(t *testing.T) {
+	md := "  | # | operator_name |\n  |--:|---|\n  | 1 | <img src=x onerror=alert(1)> |\n"
+	got := string((&mdConverter{}).convert(md))
+	if strings.Contains(got, "<img") {
+		t.Errorf("html in cell is not escaped: %s", got)
+	}
+	if !strings.Contains(got, "&lt;img src=x onerror=alert(1)&gt;") {
+		t.Errorf("escaped cell not found: %s", got)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+)
+
+// TITLE: 件数検証(ダンプ作成時の件数とロード後の件数を比較)
+
+// STRUCT: テーブル毎の件数(`schema.table` → 件数)
+type RowCounts map[string]int64
+
+// FUNCTION: テーブル毎の件数
+func CountRows(ctx context.Context, db *sql.DB, schemas []string) (RowCounts, error) {
+	tables, err := schemaTables(ctx, db, schemas)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get table names: %v", err)
+	}
+
+	counts := RowCounts{}
+	for _, table := range tables {
+		name := fmt.Sprintf("%s.%s", table.Schemaname, table.Tablename)
+		var count int64
+		if err := queries.Raw(fmt.Sprintf("SELECT count(*) FROM %s;", name)).QueryRowContext(ctx, db).Scan(&count); err != nil {
+			return nil, fmt.Errorf("cannot count %s: %v", name, err)
+		}
+		counts[name] = count
+	}
+	return counts, nil
+}
+
+// FUNCTION: 件数ファイルの出力
+func (c RowCounts) Save(filePath string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return infra.WriteText(filePath, string(data)+"\n")
+}
+
+// FUNCTION: 件数ファイルの読込み(ファイルが存在しない場合はエラー)
+func LoadRowCounts(filePath string) (RowCounts, error) {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("not exist row counts file[%s] (use --skip-verify to load without verification)", filePath)
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read row counts file: %s", err.Error())
+	}
+
+	counts := RowCounts{}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return nil, fmt.Errorf("cannot parse row counts file[%s]: %s", filePath, err.Error())
+	}
+	return counts, nil
+}
+
+// FUNCTION: 件数の検証(期待値がない(検証をスキップした)場合は件数のみ出力)
+func VerifyRowCounts(expected RowCounts, actual RowCounts) (string, error) {
+	names := []string{}
+	for name := range actual {
+		names = append(names, name)
+	}
+	for name := range expected {
+		if _, exist := actual[name]; !exist {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	msg := "\n## Load Verification\n\n"
+	if expected == nil {
+		msg += "- <span style=\"color:orange;\">verification skipped … count only</span>\n\n"
+	}
+	msg += "  | # | TABLE | EXPECTED | LOADED | RESULT |\n"
+	msg += "  |--:|---|--:|--:|:-:|\n"
+
+	ngCount := 0
+	for i, name := range names {
+		exp, hasExp := expected[name]
+		act, hasAct := actual[name]
+		result := "⭕"
+		switch {
+		case expected == nil:
+			result = "-"
+		case !hasExp || !hasAct || exp != act:
+			result = "❌"
+			ngCount++
+		}
+		msg += fmt.Sprintf("  | %d. | %s | %s | %s | %s |\n", i+1, name, countStr(exp, hasExp), countStr(act, hasAct), result)
+	}
+
+	if ngCount > 0 {
+		return msg, fmt.Errorf("load verification failed: %d table(s) mismatched", ngCount)
+	}
+	return msg, nil
+}
+
+// FUNCTION: 件数の表示(存在しない場合は`-`)
+func countStr(count int64, exist bool) string {
+	if !exist {
+		return "-"
+	}
+	return fmt.Sprintf("%d", count)
+}
@@ -4,18 +4,51 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package service
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/teru-0529/data-transfer-sandbox/service/cleansing"
 	"github.com/teru-0529/data-transfer-sandbox/service/transfer"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/legacy"
 )
 
 // TITLE: サービス共通
 
-// FUNCTION: クレンジング
-func Cleansing(conns infra.DbConnection) string {
-	controller := cleansing.New(conns)
-
+// FUNCTION: クレンジング(スキーマに破壊的な差分がある場合は、allowDriftの指定がなければTRUNCATE前に中止する)
+// サブセットの抽出条件を指定した場合は、参照整合性を保った移行元データのサブセットのみクレンジングする
+func Cleansing(run context.Context, config infra.Config, conns infra.DbConnection, allowDrift bool, spec cleansing.SubsetSpec) (Report, error) {
 	msg := NewMessage()
+
+	// PROCESS: スキーマ差分の検証
+	check, err := cleansing.CheckSchema(run, config, conns)
+	if err != nil {
+		return msg.report(), err
+	}
+	msg.addSection(check.Report())
+	if reasons := check.Breaking(); len(reasons) > 0 {
+		if !allowDrift {
+			return msg.report(), fmt.Errorf("schema drift detected (use --allow-drift to continue): %s", strings.Join(reasons, "; "))
+		}
+		log.Printf("schema drift ignored (--allow-drift): %s\n", strings.Join(reasons, "; "))
+	}
+
+	// PROCESS: サブセットの解決
+	var subset *cleansing.Subset
+	if spec.Enabled() {
+		if subset, err = cleansing.ResolveSubset(run, config, conns.LegacyDB, spec); err != nil {
+			return msg.report(), err
+		}
+		msg.addSection(subset.Report())
+	}
+
+	return cleanse(msg, cleansing.New(run, config, conns, subset)), nil
+}
+
+// FUNCTION: テーブル毎のクレンジング(ルール毎/変更履歴の集計を含む)
+func cleanse(msg *Message, controller *cleansing.Controller) Report {
 	msg.addHead(controller.Head())
 	var inv *cleansing.Invoker
 
@@ -35,14 +68,62 @@ func Cleansing(conns infra.DbConnection) string {
 	inv = controller.CreateInvocer(cleansing.NewOrderDetailsCmd())
 	msg.add(inv.Execute())
 
-	return msg.str()
+	// PROCESS: ルール毎の集計
+	msg.add(controller.RuleSummary(), "")
+
+	// PROCESS: 変更履歴の集計
+	msg.add(controller.ChangeSummary(), "")
+
+	report := msg.report()
+	report.Cleansing = controller.Report()
+	return report
 }
 
-// FUNCTION: 移行
-func Transfer(conns infra.DbConnection) string {
-	controller := transfer.New(conns)
+// FUNCTION: スキーマ差分の検証(破壊的な差分がある場合はエラー)
+func CheckSchema(run context.Context, config infra.Config, conns infra.DbConnection) (string, error) {
+	check, err := cleansing.CheckSchema(run, config, conns)
+	if err != nil {
+		return "", err
+	}
+	if reasons := check.Breaking(); len(reasons) > 0 {
+		return check.Report(), fmt.Errorf("schema drift detected: %d breaking change(s)", len(reasons))
+	}
+	return check.Report(), nil
+}
 
+// FUNCTION: 移行(移行先スキーマに破壊的な差分がある場合は、allowDriftの指定がなければTRUNCATE前に中止する)
+// 受注分割の内訳は、詳細メッセージと構造化データ(Report.Transfer.Splits)の両方で返す
+func Transfer(run context.Context, config infra.Config, conns infra.DbConnection, allowDrift bool) (Report, error) {
 	msg := NewMessage()
+
+	// PROCESS: 移行先スキーマの検証
+	check, err := transfer.CheckSchema(run, config, conns.ProductDB)
+	if err != nil {
+		return msg.report(), err
+	}
+	msg.addSection(check.Report())
+	if reasons := check.Breaking(); len(reasons) > 0 {
+		if !allowDrift {
+			return msg.report(), fmt.Errorf("product schema drift detected (use --allow-drift to continue): %s", strings.Join(reasons, "; "))
+		}
+		log.Printf("product schema drift ignored (--allow-drift): %s\n", strings.Join(reasons, "; "))
+	}
+
+	controller := transfer.New(run, config, conns)
+
+	// PROCESS: 受注/受注明細の集約結果とビューの突合せ(差分がある場合はTRUNCATE前に中止する)
+	if config.Run.TransferViewCheck {
+		check, err := controller.ViewCheck()
+		msg.addSection(check)
+		if err != nil {
+			return msg.report(), err
+		}
+	}
+	return transferAll(msg, controller), nil
+}
+
+// FUNCTION: テーブル毎の移行(受注分割の内訳を含む)
+func transferAll(msg *Message, controller *transfer.Controller) Report {
 	msg.addHead(controller.Head())
 	var inv *transfer.Invoker
 
@@ -62,5 +143,42 @@ func Transfer(conns infra.DbConnection) string {
 	inv = controller.CreateInvocer(transfer.NewOrderDetailsCmd())
 	msg.add(inv.Execute())
 
-	return msg.str()
+	// PROCESS: 受注分割の内訳(登録した受注/受注明細)
+	msg.add("", controller.ShowSplits())
+
+	report := msg.report()
+	report.Transfer = controller.Report()
+	return report
+}
+
+// FUNCTION: 再登録(承認済みの隔離データを次回のクレンジング対象に戻す)
+func Reinstate(config infra.Config, conns infra.DbConnection) (string, error) {
+	counts, err := cleansing.Reinstate(context.Background(), conns.WorkDB, config.ReinstateFile())
+	if err != nil {
+		return "", err
+	}
+
+	msg := "\n## Reinstate Quarantined Records\n\n"
+	msg += "  | TABLE | REINSTATE |\n"
+	msg += "  |---|--:|\n"
+	for _, table := range []string{legacy.TableNames.Operators, legacy.TableNames.Products, legacy.TableNames.Orders, legacy.TableNames.OrderDetails} {
+		msg += fmt.Sprintf("  | %s | %d |\n", table, counts[table])
+	}
+	return msg, nil
+}
+
+// FUNCTION: 承認の取込み(レビューブックで承認された指摘を次回のクレンジングで承認済みとする)
+func ImportApprovals(config infra.Config, bookFile string) (string, error) {
+	counts, err := cleansing.ImportApprovals(bookFile, config.ApprovalFile())
+	if err != nil {
+		return "", err
+	}
+
+	msg := "\n## Import Approvals\n\n"
+	msg += "  | TABLE | APPROVED |\n"
+	msg += "  |---|--:|\n"
+	for _, table := range cleansing.REVIEW_TABLES {
+		msg += fmt.Sprintf("  | %s | %d |\n", table, counts[table])
+	}
+	return msg, nil
 }
@@ -4,15 +4,12 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package transfer
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
 
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/teru-0529/data-transfer-sandbox/spec/product/orders"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
-	"github.com/volatiletech/sqlboiler/v4/boil"
-	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 // STRUCT: 詳細メッセージ
@@ -28,7 +25,7 @@ type OperatorRecord struct {
 }
 
 // FUNCTION: 更新
-func (r *OperatorRecord) persist(ctx infra.AppCtx, db *sql.DB) int {
+func (r *OperatorRecord) persist(ctx infra.AppCtx, writer ProductWriter) int {
 	// PROCESS: データ登録
 	rec := orders.Operator{
 		OperatorID:   r.record.OperatorID,
@@ -36,10 +33,15 @@ func (r *OperatorRecord) persist(ctx infra.AppCtx, db *sql.DB) int {
 		CreatedBy:    ctx.OperationUser,
 		UpdatedBy:    ctx.OperationUser,
 	}
-	err := rec.Insert(ctx.Ctx, db, boil.Infer())
+	err := infra.RetryInsert(ctx, func(c context.Context) error {
+		return writer.InsertOperator(c, rec)
+	})
 
-	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納)
+	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納、SQLのタイムアウト/キャンセルの場合は中断)
 	if err != nil {
+		if ctx.InterruptIf(err) {
+			return 0
+		}
 		return r.setError(err)
 	}
 	return 0
@@ -76,25 +78,29 @@ func (cmd *OperatorsCmd) getTableInfo() TableInfo {
 }
 
 // FUNCTION: 入力データ量
-func (cmd *OperatorsCmd) entryCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := clean.Operators().Count(ctx.Ctx, con)
+func (cmd *OperatorsCmd) entryCount(ctx infra.AppCtx, reader CleanReader) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return reader.Count(c, clean.TableNames.Operators)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	cmd.entry = int(num) //INFO: 処理データ量=入力データ量
 	return int(num)
 }
 
 // FUNCTION: 処理データ量(通常は、処理データ量=入力データ量)
-func (cmd *OperatorsCmd) operationCount(ctx infra.AppCtx, con *sql.DB) int {
+func (cmd *OperatorsCmd) operationCount(ctx infra.AppCtx, reader CleanReader) int {
 	return cmd.entry
 }
 
 // FUNCTION: 処理対象レコードのフェッチ
-func (cmd *OperatorsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm.QueryMod) []Record {
-	records, err := clean.Operators(qmArray...).All(ctx.Ctx, con)
+func (cmd *OperatorsCmd) fetchRecords(ctx infra.AppCtx, reader CleanReader, limit int, offset int) []Record {
+	records, err := infra.Retry(ctx, func(c context.Context) (clean.OperatorSlice, error) {
+		return reader.FetchOperators(c, limit, offset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 
 	results := make([]Record, len(records))
@@ -105,10 +111,12 @@ func (cmd *OperatorsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []q
 }
 
 // FUNCTION: 結果データ量
-func (cmd *OperatorsCmd) resultCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := orders.Operators().Count(ctx.Ctx, con)
+func (cmd *OperatorsCmd) resultCount(ctx infra.AppCtx, writer ProductWriter) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return writer.Count(c, orders.TableNames.Operators)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
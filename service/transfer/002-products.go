@@ -4,15 +4,12 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package transfer
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
 
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/teru-0529/data-transfer-sandbox/spec/product/orders"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
-	"github.com/volatiletech/sqlboiler/v4/boil"
-	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 // STRUCT: 詳細メッセージ
@@ -23,31 +20,65 @@ type ProductMsg struct {
 
 // STRUCT: レコード
 type ProductRecord struct {
-	record  clean.Product
-	details *[]ProductMsg
+	record      clean.Product
+	details     *[]ProductMsg
+	operatorIds map[string]struct{}
 }
 
 // FUNCTION: 更新
-func (r *ProductRecord) persist(ctx infra.AppCtx, db *sql.DB) int {
+func (r *ProductRecord) persist(ctx infra.AppCtx, writer ProductWriter) int {
+	// PROCESS: 商品担当者(個別指定値が存在する場合は個別指定値)
+	productPic, overridden, invalid := r.productPic(ctx)
+
 	// PROCESS: データ登録
 	rec := orders.Product{
 		ProductID:     r.record.WProductID,
 		ProductName:   r.record.ProductName,
 		CostPrice:     r.record.CostPrice,
-		ProductPic:    "Z9999",
+		ProductPic:    productPic,
 		ProductStatus: orders.ProductStatusON_SALE,
 		CreatedBy:     ctx.OperationUser,
 		UpdatedBy:     ctx.OperationUser,
 	}
-	err := rec.Insert(ctx.Ctx, db, boil.Infer())
+	err := infra.RetryInsert(ctx, func(c context.Context) error {
+		return writer.InsertProduct(c, rec)
+	})
 
-	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納)
+	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納、SQLのタイムアウト/キャンセルの場合は中断)
 	if err != nil {
+		if ctx.InterruptIf(err) {
+			return 0
+		}
 		return r.setError(err)
 	}
+
+	// PROCESS: 個別指定値を適用した場合
+	if overridden {
+		return r.setOverridden(productPic)
+	}
+
+	// PROCESS: 個別指定値が不正な場合(既定値を適用)
+	if invalid != "" {
+		return r.setInvalidOverride(invalid, productPic)
+	}
 	return 0
 }
 
+// FUNCTION: 商品担当者(個別指定値:商品名単位)
+// 個別指定値が[担当者]に存在しない場合は、既定値を適用し不正な個別指定値を返す。
+func (r *ProductRecord) productPic(ctx infra.AppCtx) (string, bool, string) {
+	const ID = "product_pic"
+	const PRODUCT_PIC = "Z9999"
+	value, exist := ctx.Overrides.Lookup(ID, r.record.ProductName)
+	if !exist {
+		return PRODUCT_PIC, false, ""
+	}
+	if _, valid := r.operatorIds[value]; !valid {
+		return PRODUCT_PIC, false, value
+	}
+	return value, true, ""
+}
+
 // FUNCTION: 更新(エラー)
 func (r *ProductRecord) setError(err error) int {
 	msg := ProductMsg{
@@ -58,10 +89,31 @@ func (r *ProductRecord) setError(err error) int {
 	return msg.bp.count
 }
 
+// FUNCTION: 更新(個別指定値)
+func (r *ProductRecord) setOverridden(productPic string) int {
+	msg := ProductMsg{
+		ProductName: r.record.ProductName,
+		bp:          modifiedPiece(fmt.Sprintf("product_pic(商品担当者) に`%s`(個別指定値) を設定しました。", productPic), 0),
+	}
+	*r.details = append(*r.details, msg)
+	return msg.bp.count
+}
+
+// FUNCTION: 更新(個別指定値が不正)
+func (r *ProductRecord) setInvalidOverride(value string, productPic string) int {
+	msg := ProductMsg{
+		ProductName: r.record.ProductName,
+		bp:          modifiedPiece(fmt.Sprintf("product_pic(商品担当者) の個別指定値`%s`が[担当者]に存在しません。<br>`%s`(既定) を設定しました。", value, productPic), 0),
+	}
+	*r.details = append(*r.details, msg)
+	return msg.bp.count
+}
+
 // STRUCT: コマンド
 type ProductsCmd struct {
-	details []ProductMsg
-	entry   int
+	details     []ProductMsg
+	entry       int
+	operatorIds map[string]struct{}
 }
 
 // FUNCTION: New
@@ -79,39 +131,57 @@ func (cmd *ProductsCmd) getTableInfo() TableInfo {
 }
 
 // FUNCTION: 入力データ量
-func (cmd *ProductsCmd) entryCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := clean.Products().Count(ctx.Ctx, con)
+func (cmd *ProductsCmd) entryCount(ctx infra.AppCtx, reader CleanReader) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return reader.Count(c, clean.TableNames.Products)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	cmd.entry = int(num) //INFO: 通常は、処理データ量=入力データ量
 	return int(num)
 }
 
 // FUNCTION: 処理データ量(通常は、処理データ量=入力データ量)
-func (cmd *ProductsCmd) operationCount(ctx infra.AppCtx, con *sql.DB) int {
+func (cmd *ProductsCmd) operationCount(ctx infra.AppCtx, reader CleanReader) int {
 	return cmd.entry
 }
 
 // FUNCTION: 処理対象レコードのフェッチ
-func (cmd *ProductsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm.QueryMod) []Record {
-	records, err := clean.Products(qmArray...).All(ctx.Ctx, con)
+func (cmd *ProductsCmd) fetchRecords(ctx infra.AppCtx, reader CleanReader, limit int, offset int) []Record {
+	records, err := infra.Retry(ctx, func(c context.Context) (clean.ProductSlice, error) {
+		return reader.FetchProducts(c, limit, offset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
+	}
+
+	// PROCESS: 担当者IDの取得(個別指定値の検証用、初回のみ)
+	if cmd.operatorIds == nil {
+		operators, err := fetchAll(ctx, reader.FetchOperators)
+		if err != nil {
+			ctx.Fatal(err)
+		}
+		cmd.operatorIds = map[string]struct{}{}
+		for _, operator := range operators {
+			cmd.operatorIds[operator.OperatorID] = struct{}{}
+		}
 	}
 
 	results := make([]Record, len(records))
 	for i, record := range records {
-		results[i] = Record{rec: &ProductRecord{record: *record, details: &cmd.details}}
+		results[i] = Record{rec: &ProductRecord{record: *record, details: &cmd.details, operatorIds: cmd.operatorIds}}
 	}
 	return results
 }
 
 // FUNCTION: 結果データ量
-func (cmd *ProductsCmd) resultCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := orders.Products().Count(ctx.Ctx, con)
+func (cmd *ProductsCmd) resultCount(ctx infra.AppCtx, writer ProductWriter) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return writer.Count(c, orders.TableNames.Products)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
@@ -4,15 +4,12 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package transfer
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
 
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/teru-0529/data-transfer-sandbox/spec/product/orders"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
-	"github.com/volatiletech/sqlboiler/v4/boil"
-	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 // STRUCT: 詳細メッセージ
@@ -28,7 +25,7 @@ type OrderRecord struct {
 }
 
 // FUNCTION: 更新
-func (r *OrderRecord) persist(ctx infra.AppCtx, db *sql.DB) int {
+func (r *OrderRecord) persist(ctx infra.AppCtx, writer ProductWriter) int {
 
 	// PROCESS: 明細が存在しない場合
 	if !r.record.Register.Bool {
@@ -47,10 +44,15 @@ func (r *OrderRecord) persist(ctx infra.AppCtx, db *sql.DB) int {
 		CreatedBy:           ctx.OperationUser,
 		UpdatedBy:           ctx.OperationUser,
 	}
-	err := rec.Insert(ctx.Ctx, db, boil.Infer())
+	err := infra.RetryInsert(ctx, func(c context.Context) error {
+		return writer.InsertOrder(c, rec)
+	})
 
-	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納)
+	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納、SQLのタイムアウト/キャンセルの場合は中断)
 	if err != nil {
+		if ctx.InterruptIf(err) {
+			return 0
+		}
 		return r.setError(err)
 	}
 
@@ -126,28 +128,34 @@ func (cmd *OrdersCmd) getTableInfo() TableInfo {
 }
 
 // FUNCTION: 入力データ量
-func (cmd *OrdersCmd) entryCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := clean.Orders().Count(ctx.Ctx, con)
+func (cmd *OrdersCmd) entryCount(ctx infra.AppCtx, reader CleanReader) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return reader.Count(c, clean.TableNames.Orders)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
 
 // FUNCTION: 処理データ量(OrderView)
-func (cmd *OrdersCmd) operationCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := clean.WOrders().Count(ctx.Ctx, con)
+func (cmd *OrdersCmd) operationCount(ctx infra.AppCtx, reader CleanReader) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return reader.Count(c, clean.ViewNames.WOrders)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
 
 // FUNCTION: 処理対象レコードのフェッチ
-func (cmd *OrdersCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm.QueryMod) []Record {
-	records, err := clean.WOrders(qmArray...).All(ctx.Ctx, con)
+func (cmd *OrdersCmd) fetchRecords(ctx infra.AppCtx, reader CleanReader, limit int, offset int) []Record {
+	records, err := infra.Retry(ctx, func(c context.Context) (clean.WOrderSlice, error) {
+		return reader.FetchWOrders(c, limit, offset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 
 	results := make([]Record, len(records))
@@ -158,10 +166,12 @@ func (cmd *OrdersCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm.Q
 }
 
 // FUNCTION: 結果データ量
-func (cmd *OrdersCmd) resultCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := orders.Orders().Count(ctx.Ctx, con)
+func (cmd *OrdersCmd) resultCount(ctx infra.AppCtx, writer ProductWriter) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return writer.Count(c, orders.TableNames.Orders)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
@@ -4,15 +4,12 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package transfer
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
 
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/teru-0529/data-transfer-sandbox/spec/product/orders"
 	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
-	"github.com/volatiletech/sqlboiler/v4/boil"
-	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 // STRUCT: 詳細メッセージ
@@ -29,7 +26,7 @@ type OrderDetailRecord struct {
 }
 
 // FUNCTION: 更新
-func (r *OrderDetailRecord) persist(ctx infra.AppCtx, db *sql.DB) int {
+func (r *OrderDetailRecord) persist(ctx infra.AppCtx, writer ProductWriter) int {
 
 	// PROCESS: 明細を集約した場合
 	if !r.record.Register.Bool {
@@ -50,10 +47,15 @@ func (r *OrderDetailRecord) persist(ctx infra.AppCtx, db *sql.DB) int {
 		CreatedBy:         ctx.OperationUser,
 		UpdatedBy:         ctx.OperationUser,
 	}
-	err := rec.Insert(ctx.Ctx, db, boil.Infer())
+	err := infra.RetryInsert(ctx, func(c context.Context) error {
+		return writer.InsertOrderDetail(c, rec)
+	})
 
-	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納)
+	// PROCESS: 登録に失敗した場合は、削除(エラーログを格納、SQLのタイムアウト/キャンセルの場合は中断)
 	if err != nil {
+		if ctx.InterruptIf(err) {
+			return 0
+		}
 		return r.setError(err)
 	}
 
@@ -113,28 +115,34 @@ func (cmd *OrderDetailsCmd) getTableInfo() TableInfo {
 }
 
 // FUNCTION: 入力データ量
-func (cmd *OrderDetailsCmd) entryCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := clean.OrderDetails().Count(ctx.Ctx, con)
+func (cmd *OrderDetailsCmd) entryCount(ctx infra.AppCtx, reader CleanReader) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return reader.Count(c, clean.TableNames.OrderDetails)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
 
 // FUNCTION: 処理データ量(OrderDetailView)
-func (cmd *OrderDetailsCmd) operationCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := clean.WOrderDetails().Count(ctx.Ctx, con)
+func (cmd *OrderDetailsCmd) operationCount(ctx infra.AppCtx, reader CleanReader) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return reader.Count(c, clean.ViewNames.WOrderDetails)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
 
 // FUNCTION: 処理対象レコードのフェッチ
-func (cmd *OrderDetailsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray []qm.QueryMod) []Record {
-	records, err := clean.WOrderDetails(qmArray...).All(ctx.Ctx, con)
+func (cmd *OrderDetailsCmd) fetchRecords(ctx infra.AppCtx, reader CleanReader, limit int, offset int) []Record {
+	records, err := infra.Retry(ctx, func(c context.Context) (clean.WOrderDetailSlice, error) {
+		return reader.FetchWOrderDetails(c, limit, offset)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 
 	results := make([]Record, len(records))
@@ -145,10 +153,12 @@ func (cmd *OrderDetailsCmd) fetchRecords(ctx infra.AppCtx, con *sql.DB, qmArray
 }
 
 // FUNCTION: 結果データ量
-func (cmd *OrderDetailsCmd) resultCount(ctx infra.AppCtx, con *sql.DB) int {
-	num, err := orders.OrderDetails().Count(ctx.Ctx, con)
+func (cmd *OrderDetailsCmd) resultCount(ctx infra.AppCtx, writer ProductWriter) int {
+	num, err := infra.Retry(ctx, func(c context.Context) (int64, error) {
+		return writer.Count(c, orders.TableNames.OrderDetails)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		ctx.Fatal(err)
 	}
 	return int(num)
 }
@@ -0,0 +1,361 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package transfer
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
+	"github.com/volatiletech/null/v8"
+)
+
+// TITLE: 受注/受注明細の集約(clean.w_orders/clean.w_order_detailsビューと同じ変換)
+
+// STRUCT: 差分の最大出力件数(ビューとの突合せ)
+const VIEW_CHECK_LIMIT = 20
+
+// STRUCT: 集約結果
+type Aggregation struct {
+	WOrders       []clean.WOrder
+	WOrderDetails []clean.WOrderDetail
+}
+
+// STRUCT: 明細グループ(受注番号(WORK)/商品名単位)
+// INFO: 受注番号(WORK)は受注番号/商品名/販売単価/商品原価の組合せ毎に採番されるため、販売単価/商品原価はグループ内で同一
+type detailGroup struct {
+	wOrderNo    string
+	orderNo     int
+	productName string
+	details     []clean.OrderDetail //受注明細番号:昇順
+}
+
+// STRUCT: 受注グループ(受注番号(WORK)単位)
+type orderGroup struct {
+	wOrderNo            string
+	totalOrderPrice     int
+	remainingOrderPrice int
+	shippingQuantity    int
+	remainingQuantity   int
+}
+
+// FUNCTION: 集約(cleanスキーマの全件を読込み、ビューと同じ結果を作成)
+func LoadAggregation(ctx infra.AppCtx, reader CleanReader) (Aggregation, error) {
+	operators, err := fetchAll(ctx, reader.FetchOperators)
+	if err != nil {
+		return Aggregation{}, err
+	}
+	products, err := fetchAll(ctx, reader.FetchProducts)
+	if err != nil {
+		return Aggregation{}, err
+	}
+	orders, err := fetchAll(ctx, reader.FetchOrders)
+	if err != nil {
+		return Aggregation{}, err
+	}
+	details, err := fetchAll(ctx, reader.FetchOrderDetails)
+	if err != nil {
+		return Aggregation{}, err
+	}
+	return Aggregate(operators, products, orders, details), nil
+}
+
+// FUNCTION: 集約
+func Aggregate(operators []clean.Operator, products []clean.Product, orders []clean.Order, details []clean.OrderDetail) Aggregation {
+	return Aggregation{
+		WOrders:       AggregateOrders(operators, orders, details),
+		WOrderDetails: AggregateOrderDetails(products, details),
+	}
+}
+
+// FUNCTION: 受注の集約(w_orders)
+//   - 受注番号(WORK)毎に1件、明細が存在しない受注は登録対象外(register=false)として1件
+//   - 受注番号(WORK)の昇順で2件目以降は分割としてログ出力の対象(logging=true)
+//   - change_count: 受注番号(WORK)の件数-1(明細が存在しない場合は-1)
+func AggregateOrders(operators []clean.Operator, orders []clean.Order, details []clean.OrderDetail) []clean.WOrder {
+	operatorIds := map[string]string{}
+	for _, operator := range operators {
+		operatorIds[operator.OperatorName] = operator.OperatorID
+	}
+
+	// PROCESS: 受注番号(WORK)毎の集計
+	groups := map[int][]*orderGroup{}
+	index := map[string]*orderGroup{}
+	for _, detail := range details {
+		group, exist := index[detail.WOrderNo]
+		if !exist {
+			group = &orderGroup{wOrderNo: detail.WOrderNo}
+			index[detail.WOrderNo] = group
+			groups[detail.OrderNo] = append(groups[detail.OrderNo], group)
+		}
+		group.totalOrderPrice += detail.WTotalOrderPrice
+		group.remainingOrderPrice += detail.WRemainingOrderPrice
+		group.shippingQuantity += detail.WShippingQuantity
+		group.remainingQuantity += detail.WRemainingQuantity
+	}
+
+	results := []clean.WOrder{}
+	for _, order := range orders {
+		// INFO: 受注担当者が[担当者]に存在しない受注は対象外(INNER JOIN)
+		operatorId, exist := operatorIds[order.OrderPic]
+		if !exist {
+			continue
+		}
+		base := clean.WOrder{
+			OrderNo:      null.IntFrom(order.OrderNo),
+			OrderDate:    null.TimeFrom(order.OrderDate),
+			OperatorID:   null.StringFrom(operatorId),
+			OrderPic:     null.StringFrom(order.OrderPic),
+			CustomerName: null.StringFrom(order.CustomerName),
+		}
+
+		// PROCESS: 明細が存在しない場合
+		orderGroups := groups[order.OrderNo]
+		if len(orderGroups) == 0 {
+			base.Register = null.BoolFrom(false)
+			base.Logging = null.BoolFrom(true)
+			base.ChangeCount = null.Int64From(-1)
+			results = append(results, base)
+			continue
+		}
+
+		slices.SortFunc(orderGroups, func(a, b *orderGroup) int { return strings.Compare(a.wOrderNo, b.wOrderNo) })
+		for i, group := range orderGroups {
+			rec := base
+			rec.Register = null.BoolFrom(true)
+			rec.Logging = null.BoolFrom(i > 0)
+			rec.WOrderNo = null.StringFrom(group.wOrderNo)
+			rec.ChangeCount = null.Int64From(int64(len(orderGroups) - 1))
+			rec.WTotalOrderPrice = null.Int64From(int64(group.totalOrderPrice))
+			rec.WRemainingOrderPrice = null.Int64From(int64(group.remainingOrderPrice))
+			rec.IsShipped = null.BoolFrom(group.shippingQuantity > 0)
+			rec.IsRemaining = null.BoolFrom(group.remainingQuantity == 0)
+			results = append(results, rec)
+		}
+	}
+
+	// PROCESS: 受注番号(WORK):昇順(明細が存在しない受注は末尾)
+	slices.SortStableFunc(results, func(a, b clean.WOrder) int {
+		if a.WOrderNo.Valid != b.WOrderNo.Valid {
+			if a.WOrderNo.Valid {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(strings.Compare(a.WOrderNo.String, b.WOrderNo.String), cmp.Compare(a.OrderNo.Int, b.OrderNo.Int))
+	})
+	return results
+}
+
+// FUNCTION: 受注明細の集約(w_order_details)
+//   - 受注明細毎に1件、受注番号(WORK)/商品名が同一の明細は数量を合算し、受注明細番号が最大の明細のみ登録対象(register=true)
+func AggregateOrderDetails(products []clean.Product, details []clean.OrderDetail) []clean.WOrderDetail {
+	productIds := map[string]string{}
+	for _, product := range products {
+		productIds[product.ProductName] = product.WProductID
+	}
+
+	// PROCESS: 受注番号(WORK)/商品名毎のグループ(出現順)
+	groups := []*detailGroup{}
+	index := map[[2]string]*detailGroup{}
+	for _, detail := range details {
+		key := [2]string{detail.WOrderNo, detail.ProductName}
+		group, exist := index[key]
+		if !exist {
+			group = &detailGroup{wOrderNo: detail.WOrderNo, orderNo: detail.OrderNo, productName: detail.ProductName}
+			index[key] = group
+			groups = append(groups, group)
+		}
+		group.details = append(group.details, detail)
+	}
+
+	results := []clean.WOrderDetail{}
+	for _, group := range groups {
+		// INFO: 商品名が[商品]に存在しない明細は対象外(INNER JOIN)
+		productId, exist := productIds[group.productName]
+		if !exist {
+			continue
+		}
+		slices.SortFunc(group.details, func(a, b clean.OrderDetail) int { return cmp.Compare(a.OrderDetailNo, b.OrderDetailNo) })
+
+		var receiving, shipping, cancel, remaining int
+		detailNos := make([]string, len(group.details))
+		for i, detail := range group.details {
+			receiving += detail.ReceivingQuantity
+			shipping += detail.WShippingQuantity
+			cancel += detail.WCancelQuantity
+			remaining += detail.WRemainingQuantity
+			detailNos[i] = strconv.Itoa(detail.OrderDetailNo)
+		}
+		last := group.details[len(group.details)-1]
+		for _, detail := range group.details {
+			results = append(results, clean.WOrderDetail{
+				Register:           null.BoolFrom(detail.OrderDetailNo == last.OrderDetailNo),
+				WOrderNo:           null.StringFrom(group.wOrderNo),
+				OrderNo:            null.IntFrom(group.orderNo),
+				AggregatedDetails:  null.StringFrom(strings.Join(detailNos, ",")),
+				DetailCount:        null.Int64From(int64(len(group.details))),
+				WProductID:         null.StringFrom(productId),
+				ProductName:        null.StringFrom(group.productName),
+				ReceivingQuantity:  null.Int64From(int64(receiving)),
+				WShippingQuantity:  null.Int64From(int64(shipping)),
+				WCancelQuantity:    null.Int64From(int64(cancel)),
+				WRemainingQuantity: null.Int64From(int64(remaining)),
+				SellingPrice:       null.IntFrom(last.SellingPrice),
+				CostPrice:          null.IntFrom(last.CostPrice),
+				IsShipped:          null.BoolFrom(shipping > 0),
+				IsRemaining:        null.BoolFrom(remaining == 0),
+			})
+		}
+	}
+
+	// PROCESS: 受注番号(WORK)、商品ID:昇順
+	slices.SortStableFunc(results, func(a, b clean.WOrderDetail) int {
+		return cmp.Or(strings.Compare(a.WOrderNo.String, b.WOrderNo.String), strings.Compare(a.WProductID.String, b.WProductID.String))
+	})
+	return results
+}
+
+// FUNCTION: 集約結果を読込むリポジトリ(w_orders/w_order_detailsはビューではなく集約結果を返す)
+func (a Aggregation) Reader(reader CleanReader) CleanReader {
+	return &aggregatedReader{CleanReader: reader, aggregation: a}
+}
+
+// STRUCT: 集約結果を読込むリポジトリ
+type aggregatedReader struct {
+	CleanReader
+	aggregation Aggregation
+}
+
+// FUNCTION: 件数
+func (r *aggregatedReader) Count(ctx context.Context, table string) (int64, error) {
+	switch table {
+	case clean.ViewNames.WOrders:
+		return int64(len(r.aggregation.WOrders)), nil
+	case clean.ViewNames.WOrderDetails:
+		return int64(len(r.aggregation.WOrderDetails)), nil
+	}
+	return r.CleanReader.Count(ctx, table)
+}
+
+// FUNCTION: 受注(集約結果)
+func (r *aggregatedReader) FetchWOrders(ctx context.Context, limit int, offset int) (clean.WOrderSlice, error) {
+	return page(r.aggregation.WOrders, limit, offset), nil
+}
+
+// FUNCTION: 受注明細(集約結果)
+func (r *aggregatedReader) FetchWOrderDetails(ctx context.Context, limit int, offset int) (clean.WOrderDetailSlice, error) {
+	return page(r.aggregation.WOrderDetails, limit, offset), nil
+}
+
+// FUNCTION: ビューとの突合せ(差分がある場合はエラー)
+func (a Aggregation) CrossCheck(ctx infra.AppCtx, reader CleanReader) (string, error) {
+	wOrders, err := fetchAll(ctx, reader.FetchWOrders)
+	if err != nil {
+		return "", err
+	}
+	wOrderDetails, err := fetchAll(ctx, reader.FetchWOrderDetails)
+	if err != nil {
+		return "", err
+	}
+
+	msg := "\n## Aggregation Cross-Check (Go / SQL View)\n\n"
+	msg += "  | VIEW | GO | SQL | RESULT |\n"
+	msg += "  |---|--:|--:|:-:|\n"
+	orderDiffs := diffRows(mapRows(a.WOrders, wOrderKey), mapRows(wOrders, wOrderKey))
+	detailDiffs := diffRows(mapRows(a.WOrderDetails, wOrderDetailKey), mapRows(wOrderDetails, wOrderDetailKey))
+	msg += fmt.Sprintf("  | %s | %d | %d | %s |\n", clean.ViewNames.WOrders, len(a.WOrders), len(wOrders), checkMark(len(orderDiffs) == 0))
+	msg += fmt.Sprintf("  | %s | %d | %d | %s |\n", clean.ViewNames.WOrderDetails, len(a.WOrderDetails), len(wOrderDetails), checkMark(len(detailDiffs) == 0))
+
+	diffs := append(orderDiffs, detailDiffs...)
+	if len(diffs) == 0 {
+		return msg, nil
+	}
+	msg += "\n  | # | SOURCE | ROW |\n"
+	msg += "  |--:|:-:|---|\n"
+	for i, diff := range diffs[:min(len(diffs), VIEW_CHECK_LIMIT)] {
+		msg += fmt.Sprintf("  | %d. | %s |\n", i+1, diff)
+	}
+	return msg, fmt.Errorf("aggregation differs from sql views: %d rows", len(diffs))
+}
+
+// FUNCTION: 比較用の行(集約明細番号は昇順に正規化、SQLのSTRING_AGGは順序不定のため)
+func wOrderKey(r clean.WOrder) string {
+	return fmt.Sprintf("%v|%v|%s|%d|%d|%s|%s|%s|%s|%d|%d|%v|%v",
+		r.Register.Bool, r.Logging.Bool, r.WOrderNo.String, r.OrderNo.Int, r.ChangeCount.Int64,
+		r.OrderDate.Time.Format("2006-01-02"), r.OperatorID.String, r.OrderPic.String, r.CustomerName.String,
+		r.WTotalOrderPrice.Int64, r.WRemainingOrderPrice.Int64, r.IsShipped.Bool, r.IsRemaining.Bool)
+}
+
+func wOrderDetailKey(r clean.WOrderDetail) string {
+	detailNos := strings.Split(r.AggregatedDetails.String, ",")
+	slices.SortFunc(detailNos, func(a, b string) int {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return cmp.Compare(x, y)
+	})
+	return fmt.Sprintf("%v|%s|%d|%s|%d|%s|%s|%d|%d|%d|%d|%d|%d|%v|%v",
+		r.Register.Bool, r.WOrderNo.String, r.OrderNo.Int, strings.Join(detailNos, ","), r.DetailCount.Int64,
+		r.WProductID.String, r.ProductName.String, r.ReceivingQuantity.Int64, r.WShippingQuantity.Int64,
+		r.WCancelQuantity.Int64, r.WRemainingQuantity.Int64, r.SellingPrice.Int, r.CostPrice.Int,
+		r.IsShipped.Bool, r.IsRemaining.Bool)
+}
+
+// FUNCTION: 比較用の行の件数
+func mapRows[T any](rows []T, key func(T) string) map[string]int {
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[key(row)]++
+	}
+	return counts
+}
+
+// FUNCTION: 行の差分(`GO`/`SQL`の一方のみに存在する行)
+func diffRows(goRows map[string]int, sqlRows map[string]int) []string {
+	diffs := []string{}
+	for row, count := range goRows {
+		for i := sqlRows[row]; i < count; i++ {
+			diffs = append(diffs, fmt.Sprintf("GO | `%s`", row))
+		}
+	}
+	for row, count := range sqlRows {
+		for i := goRows[row]; i < count; i++ {
+			diffs = append(diffs, fmt.Sprintf("SQL | `%s`", row))
+		}
+	}
+	slices.Sort(diffs)
+	return diffs
+}
+
+// FUNCTION: 突合せ結果
+func checkMark(ok bool) string {
+	if ok {
+		return "⭕"
+	}
+	return "❌"
+}
+
+// FUNCTION: 全件の読込み(FETCH_LIMIT毎)
+func fetchAll[T any, S ~[]*T](ctx infra.AppCtx, fetch func(context.Context, int, int) (S, error)) ([]T, error) {
+	results := []T{}
+	for offset := 0; ; offset += ctx.Limit {
+		rows, err := infra.Retry(ctx, func(c context.Context) (S, error) {
+			return fetch(c, ctx.Limit, offset)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			results = append(results, *row)
+		}
+		if len(rows) < ctx.Limit {
+			return results, nil
+		}
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package transfer
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
+	"github.com/volatiletech/null/v8"
+)
+
+// TITLE: 受注/受注明細の集約(clean.w_orders/clean.w_order_detailsビューとの比較)
+
+// FUNCTION: 受注の集約(w_orders)
+func TestAggregateOrders(t *testing.T) {
+	operators := []clean.Operator{{OperatorID: "AB001", OperatorName: "山田太郎"}}
+	orderDate := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	order := func(orderNo int, orderPic string) clean.Order {
+		return clean.Order{OrderNo: orderNo, OrderDate: orderDate, OrderPic: orderPic, CustomerName: "A商事"}
+	}
+	// INFO: ビューの1行(oda:受注番号(WORK)毎の集計、明細が存在しない場合はNULL)
+	row := func(orderNo int, wOrderNo string, logging bool, changeCount int64, total int64, remaining int64, shipped bool, isRemaining bool) clean.WOrder {
+		return clean.WOrder{
+			Register:             null.BoolFrom(true),
+			Logging:              null.BoolFrom(logging),
+			WOrderNo:             null.StringFrom(wOrderNo),
+			OrderNo:              null.IntFrom(orderNo),
+			ChangeCount:          null.Int64From(changeCount),
+			OrderDate:            null.TimeFrom(orderDate),
+			OperatorID:           null.StringFrom("AB001"),
+			OrderPic:             null.StringFrom("山田太郎"),
+			CustomerName:         null.StringFrom("A商事"),
+			WTotalOrderPrice:     null.Int64From(total),
+			WRemainingOrderPrice: null.Int64From(remaining),
+			IsShipped:            null.BoolFrom(shipped),
+			IsRemaining:          null.BoolFrom(isRemaining),
+		}
+	}
+	noDetail := func(orderNo int) clean.WOrder {
+		return clean.WOrder{
+			Register:     null.BoolFrom(false),
+			Logging:      null.BoolFrom(true),
+			OrderNo:      null.IntFrom(orderNo),
+			ChangeCount:  null.Int64From(-1),
+			OrderDate:    null.TimeFrom(orderDate),
+			OperatorID:   null.StringFrom("AB001"),
+			OrderPic:     null.StringFrom("山田太郎"),
+			CustomerName: null.StringFrom("A商事"),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		orders  []clean.Order
+		details []clean.OrderDetail
+		want    []clean.WOrder
+	}{
+		{
+			name:    "one w_order_no (sum of details)",
+			orders:  []clean.Order{order(1001, "山田太郎")},
+			details: []clean.OrderDetail{cleanDetail(1001, 1, "W1001-1", "ボールペン", 2, 150, true), cleanDetail(1001, 2, "W1001-1", "消しゴム", 1, 80, false)},
+			want:    []clean.WOrder{row(1001, "W1001-1", false, 0, 380, 80, true, false)},
+		},
+		{
+			name:    "split order (logging from the second w_order_no)",
+			orders:  []clean.Order{order(1001, "山田太郎")},
+			details: []clean.OrderDetail{cleanDetail(1001, 1, "W1001-2", "ボールペン", 3, 160, true), cleanDetail(1001, 2, "W1001-1", "ボールペン", 2, 150, true)},
+			want:    []clean.WOrder{row(1001, "W1001-1", false, 1, 300, 0, true, true), row(1001, "W1001-2", true, 1, 480, 0, true, true)},
+		},
+		{
+			name:    "order without details (register=false, last)",
+			orders:  []clean.Order{order(1001, "山田太郎"), order(1002, "山田太郎")},
+			details: []clean.OrderDetail{cleanDetail(1002, 1, "W1002-1", "ボールペン", 2, 150, false)},
+			want:    []clean.WOrder{row(1002, "W1002-1", false, 0, 300, 300, false, false), noDetail(1001)},
+		},
+		{
+			name:    "unknown order_pic (inner join)",
+			orders:  []clean.Order{order(1001, "佐藤花子")},
+			details: []clean.OrderDetail{cleanDetail(1001, 1, "W1001-1", "ボールペン", 2, 150, false)},
+			want:    []clean.WOrder{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AggregateOrders(operators, tt.orders, tt.details)
+			assertKeys(t, got, tt.want, wOrderKey)
+		})
+	}
+}
+
+// FUNCTION: 受注明細の集約(w_order_details)
+func TestAggregateOrderDetails(t *testing.T) {
+	products := []clean.Product{
+		{ProductName: "ボールペン", CostPrice: 100, WProductID: "P0002"},
+		{ProductName: "消しゴム", CostPrice: 50, WProductID: "P0001"},
+	}
+	// INFO: ビューの1行(oda:受注番号(WORK)/商品名毎の集計、最大の受注明細番号のみ登録対象)
+	row := func(register bool, wOrderNo string, aggregated string, count int64, productId string, productName string, receiving int64, shipping int64, remaining int64, price int) clean.WOrderDetail {
+		return clean.WOrderDetail{
+			Register:           null.BoolFrom(register),
+			WOrderNo:           null.StringFrom(wOrderNo),
+			OrderNo:            null.IntFrom(1001),
+			AggregatedDetails:  null.StringFrom(aggregated),
+			DetailCount:        null.Int64From(count),
+			WProductID:         null.StringFrom(productId),
+			ProductName:        null.StringFrom(productName),
+			ReceivingQuantity:  null.Int64From(receiving),
+			WShippingQuantity:  null.Int64From(shipping),
+			WCancelQuantity:    null.Int64From(0),
+			WRemainingQuantity: null.Int64From(remaining),
+			SellingPrice:       null.IntFrom(price),
+			CostPrice:          null.IntFrom(price / 2),
+			IsShipped:          null.BoolFrom(shipping > 0),
+			IsRemaining:        null.BoolFrom(remaining == 0),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		details []clean.OrderDetail
+		want    []clean.WOrderDetail
+	}{
+		{
+			name:    "one detail",
+			details: []clean.OrderDetail{cleanDetail(1001, 1, "W1001-1", "ボールペン", 2, 150, false)},
+			want:    []clean.WOrderDetail{row(true, "W1001-1", "1", 1, "P0002", "ボールペン", 2, 0, 2, 150)},
+		},
+		{
+			name: "aggregate same product (register only the last order_detail_no)",
+			details: []clean.OrderDetail{
+				cleanDetail(1001, 3, "W1001-1", "ボールペン", 3, 150, false),
+				cleanDetail(1001, 1, "W1001-1", "ボールペン", 2, 150, true),
+				cleanDetail(1001, 2, "W1001-1", "消しゴム", 1, 80, false),
+			},
+			want: []clean.WOrderDetail{
+				row(true, "W1001-1", "2", 1, "P0001", "消しゴム", 1, 0, 1, 80),
+				row(false, "W1001-1", "1,3", 2, "P0002", "ボールペン", 5, 2, 3, 150),
+				row(true, "W1001-1", "1,3", 2, "P0002", "ボールペン", 5, 2, 3, 150),
+			},
+		},
+		{
+			name: "split order (not aggregated across w_order_no)",
+			details: []clean.OrderDetail{
+				cleanDetail(1001, 1, "W1001-2", "ボールペン", 3, 160, true),
+				cleanDetail(1001, 2, "W1001-1", "ボールペン", 2, 150, true),
+			},
+			want: []clean.WOrderDetail{
+				row(true, "W1001-1", "2", 1, "P0002", "ボールペン", 2, 2, 0, 150),
+				row(true, "W1001-2", "1", 1, "P0002", "ボールペン", 3, 3, 0, 160),
+			},
+		},
+		{
+			name:    "unknown product_name (inner join)",
+			details: []clean.OrderDetail{cleanDetail(1001, 1, "W1001-1", "定規", 2, 150, false)},
+			want:    []clean.WOrderDetail{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AggregateOrderDetails(products, tt.details)
+			assertKeys(t, got, tt.want, wOrderDetailKey)
+		})
+	}
+}
+
+// FUNCTION: 集約結果の比較(ビューとの突合せと同じ比較用の行、ビューのORDER BYと同じ順序)
+func assertKeys[T any](t *testing.T, got []T, want []T, key func(T) string) {
+	t.Helper()
+	gotKeys, wantKeys := []string{}, []string{}
+	for _, row := range got {
+		gotKeys = append(gotKeys, key(row))
+	}
+	for _, row := range want {
+		wantKeys = append(wantKeys, key(row))
+	}
+	if !slices.Equal(gotKeys, wantKeys) {
+		t.Errorf("\ngot  %q\nwant %q", gotKeys, wantKeys)
+	}
+}
@@ -4,22 +4,20 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package transfer
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/cheggaaa/pb/v3"
 	"github.com/teru-0529/data-transfer-sandbox/infra"
-	"github.com/volatiletech/sqlboiler/v4/queries"
-	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
 // TITLE: データ変換インボーカー
 
 // STRUCT: レコードインターフェース
 type CleanRecord interface {
-	persist(ctx infra.AppCtx, db *sql.DB) int
+	persist(ctx infra.AppCtx, writer ProductWriter) int
 }
 
 // STRUCT: レコード(ラッパー)
@@ -28,35 +26,40 @@ type Record struct {
 }
 
 // FUNCTION:
-func (r *Record) save(ctx infra.AppCtx, db *sql.DB) int {
-	return r.rec.persist(ctx, db)
+func (r *Record) save(ctx infra.AppCtx, writer ProductWriter) int {
+	return r.rec.persist(ctx, writer)
 }
 
 // STRUCT: コマンドインターフェース
 type Command interface {
 	getTableInfo() TableInfo
-	entryCount(ctx infra.AppCtx, db *sql.DB) int
-	operationCount(ctx infra.AppCtx, db *sql.DB) int
-	fetchRecords(ctx infra.AppCtx, db *sql.DB, qmArray []qm.QueryMod) []Record
-	resultCount(ctx infra.AppCtx, db *sql.DB) int
+	entryCount(ctx infra.AppCtx, reader CleanReader) int
+	operationCount(ctx infra.AppCtx, reader CleanReader) int
+	fetchRecords(ctx infra.AppCtx, reader CleanReader, limit int, offset int) []Record
+	resultCount(ctx infra.AppCtx, writer ProductWriter) int
 	showDetails(ctx infra.AppCtx, tableName string) string
 }
 
 // STRUCT: インボーカー
 type Invoker struct {
-	num   int
-	ctx   infra.AppCtx
-	conns infra.DbConnection
-	cmd   Command
+	num    int
+	ctx    infra.AppCtx
+	reader CleanReader
+	writer ProductWriter
+	cmd    Command
+	report *Report
 }
 
 // FUNCTION:
-func NewInvoker(num int, ctx infra.AppCtx, conns infra.DbConnection, cmd Command) *Invoker {
+// INFO: テーブル毎の結果件数はreportに追加する
+func NewInvoker(num int, ctx infra.AppCtx, reader CleanReader, writer ProductWriter, report *Report, cmd Command) *Invoker {
 	return &Invoker{
-		num:   num,
-		ctx:   ctx,
-		conns: conns,
-		cmd:   cmd,
+		num:    num,
+		ctx:    ctx,
+		reader: reader,
+		writer: writer,
+		cmd:    cmd,
+		report: report,
 	}
 }
 
@@ -67,59 +70,90 @@ func (inv *Invoker) Execute() (string, string) {
 
 	// PROCESS: テーブル名称取得
 	table := inv.cmd.getTableInfo()
+
+	// PROCESS: 中断済みの場合は処理しない
+	if inv.ctx.Interrupted() {
+		log.Printf("[%s] table transfer skipped (interrupted)", table.tableEn)
+		result.interrupted = true
+		inv.report.Tables = append(inv.report.Tables, result.tableResult(inv.num, table, 0, 0))
+		return inv.showRecord(table, result, 0), ""
+	}
 	log.Printf("[%s] table transfer ...", table.tableEn)
 
+	// PROCESS: リトライ回数(テーブル単位)
+	inv.ctx.Retries = infra.NewRetryCounter()
+
 	// PROCESS: 入力データ量
-	result.entryCount = inv.cmd.entryCount(inv.ctx, inv.conns.WorkDB)
+	result.entryCount = inv.cmd.entryCount(inv.ctx, inv.reader)
 
 	// PROCESS: 移行先のtruncate
-	_, err := queries.Raw(table.truncateSql()).ExecContext(inv.ctx.Ctx, inv.conns.ProductDB)
+	err := infra.RetryExec(inv.ctx, func(c context.Context) error {
+		return inv.writer.Truncate(c, table.tableEn)
+	})
 	if err != nil {
-		log.Fatalln(err)
+		inv.ctx.Fatal(err)
 	}
 
 	// PROCESS: データ取得/登録
-	result.changeCount = inv.iterate(inv.cmd.operationCount(inv.ctx, inv.conns.WorkDB))
+	operationCount := inv.cmd.operationCount(inv.ctx, inv.reader)
+	result.changeCount, result.interrupted = inv.iterate(operationCount)
 
-	// PROCESS: 結果データ量
-	result.resultCount = inv.cmd.resultCount(inv.ctx, inv.conns.ProductDB)
+	// PROCESS: 結果データ量(中断時も取得する)
+	result.resultCount = inv.cmd.resultCount(inv.ctx.Detached(), inv.writer)
 
 	// PROCESS: 後処理
 	duration := time.Since(s).Seconds()
 	log.Printf("transfer completed … %3.2fs\n", duration)
+	inv.report.Tables = append(inv.report.Tables, result.tableResult(inv.num, table, duration, inv.ctx.Retries.Count()))
 	return inv.showRecord(table, result, duration), inv.cmd.showDetails(inv.ctx, table.Name())
 }
 
-// FUNCTION: データ取得/登録
-func (inv *Invoker) iterate(count int) int {
+// FUNCTION: データ取得/登録(中断時は中断有無を返す)
+func (inv *Invoker) iterate(count int) (int, bool) {
 	changeCount := 0
+	interrupted := false
 	bar := pb.Default.Start(count)
 	bar.SetMaxWidth(80)
 
 	// PROCESS: SQLによる取得を分割する
 	for lap := 0; lap < inv.ctx.LapNumber(count); lap++ {
-		qmArray := []qm.QueryMod{qm.Limit(inv.ctx.Limit), qm.Offset(lap * inv.ctx.Limit)}
-
-		records := inv.cmd.fetchRecords(inv.ctx, inv.conns.WorkDB, qmArray)
+		// PROCESS: 中断時は以降の取得を行わない
+		if inv.ctx.Interrupted() {
+			interrupted = true
+			break
+		}
+		records := inv.cmd.fetchRecords(inv.ctx, inv.reader, inv.ctx.Limit, lap*inv.ctx.Limit)
 
 		for _, record := range records {
+			// PROCESS: 中断時は以降の登録を行わない
+			if inv.ctx.Interrupted() {
+				interrupted = true
+				break
+			}
+
 			// PROCESS: レコード毎のデータ登録
-			changeCount += record.save(inv.ctx, inv.conns.ProductDB)
+			changeCount += record.save(inv.ctx, inv.writer)
 			bar.Increment()
 		}
 	}
 	bar.Finish()
-	return changeCount
+
+	// INFO: 取得/登録中のSQLのタイムアウト/キャンセルによる中断
+	if inv.ctx.Interrupted() {
+		interrupted = true
+	}
+	return changeCount, interrupted
 }
 
 // FUNCTION: メッセージの出力
 func (inv *Invoker) showRecord(t TableInfo, r ResultCount, duration float64) string {
-	return fmt.Sprintf("  | %d. | %s | %s | %s | %s | … | %s | … | %s | %s |\n",
+	return fmt.Sprintf("  | %d. | %s | %s | %s | %s | %s | … | %s | … | %s | %s |\n",
 		inv.num,
 		t.schema,
-		t.Name(),
+		t.Name()+r.interruptedStr(),
 		inv.ctx.Printer.Sprintf("%d", r.entryCount),
 		inv.ctx.Printer.Sprintf("%3.2fs", duration),
+		inv.ctx.Printer.Sprintf("%d", inv.ctx.Retries.Count()),
 		inv.ctx.Printer.Sprintf("%+d", r.changeCount),
 		inv.ctx.Printer.Sprintf("%d", r.resultCount),
 		r.checkRecord(),
@@ -138,16 +172,12 @@ func (t TableInfo) Name() string {
 	return fmt.Sprintf("%s(%s)", t.tableEn, t.tableJp)
 }
 
-// FUNCTION: truuncate文
-func (t TableInfo) truncateSql() string {
-	return fmt.Sprintf("TRUNCATE %s.%s CASCADE;", t.schema, t.tableEn)
-}
-
 // STRUCT: 結果件数
 type ResultCount struct {
 	entryCount  int
 	changeCount int
 	resultCount int
+	interrupted bool
 }
 
 // FUNCTION: 件数推移チェック
@@ -158,3 +188,12 @@ func (r ResultCount) checkRecord() string {
 		return "❎"
 	}
 }
+
+// FUNCTION: 中断時の表示
+func (r ResultCount) interruptedStr() string {
+	if r.interrupted {
+		return "<span style=\"color:red;\">(⏸INTERRUPTED)</span>"
+	} else {
+		return ""
+	}
+}
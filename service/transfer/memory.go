@@ -0,0 +1,203 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package transfer
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/spec/product/orders"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
+)
+
+// TITLE: インメモリリポジトリ(DBを使用しない変換の検証用)
+
+// INFO: インターフェースの実装確認
+var _ CleanReader = (*MemoryCleanReader)(nil)
+var _ ProductWriter = (*MemoryProductWriter)(nil)
+
+// STRUCT: クレンジング結果(インメモリ)
+// INFO: w_orders/w_order_detailsはビューの結果として指定する(集約結果を使用する場合はAggregation.Readerで置き換える)
+type MemoryCleanReader struct {
+	Operators     []clean.Operator
+	Products      []clean.Product
+	Orders        []clean.Order
+	OrderDetails  []clean.OrderDetail
+	WOrders       []clean.WOrder
+	WOrderDetails []clean.WOrderDetail
+}
+
+// FUNCTION: 件数
+func (r *MemoryCleanReader) Count(ctx context.Context, table string) (int64, error) {
+	switch table {
+	case clean.TableNames.Operators:
+		return int64(len(r.Operators)), nil
+	case clean.TableNames.Products:
+		return int64(len(r.Products)), nil
+	case clean.TableNames.Orders:
+		return int64(len(r.Orders)), nil
+	case clean.TableNames.OrderDetails:
+		return int64(len(r.OrderDetails)), nil
+	case clean.ViewNames.WOrders:
+		return int64(len(r.WOrders)), nil
+	case clean.ViewNames.WOrderDetails:
+		return int64(len(r.WOrderDetails)), nil
+	}
+	return 0, fmt.Errorf("unknown clean table: %s", table)
+}
+
+// FUNCTION: 担当者(担当者ID:昇順)
+func (r *MemoryCleanReader) FetchOperators(ctx context.Context, limit int, offset int) (clean.OperatorSlice, error) {
+	rows := slices.SortedStableFunc(slices.Values(r.Operators), func(a, b clean.Operator) int { return strings.Compare(a.OperatorID, b.OperatorID) })
+	return page(rows, limit, offset), nil
+}
+
+// FUNCTION: 商品(商品名:昇順)
+func (r *MemoryCleanReader) FetchProducts(ctx context.Context, limit int, offset int) (clean.ProductSlice, error) {
+	rows := slices.SortedStableFunc(slices.Values(r.Products), func(a, b clean.Product) int { return strings.Compare(a.ProductName, b.ProductName) })
+	return page(rows, limit, offset), nil
+}
+
+// FUNCTION: 受注(受注番号:昇順)
+func (r *MemoryCleanReader) FetchOrders(ctx context.Context, limit int, offset int) (clean.OrderSlice, error) {
+	rows := slices.SortedStableFunc(slices.Values(r.Orders), func(a, b clean.Order) int { return cmp.Compare(a.OrderNo, b.OrderNo) })
+	return page(rows, limit, offset), nil
+}
+
+// FUNCTION: 受注明細(受注番号、受注明細番号:昇順)
+func (r *MemoryCleanReader) FetchOrderDetails(ctx context.Context, limit int, offset int) (clean.OrderDetailSlice, error) {
+	rows := slices.SortedStableFunc(slices.Values(r.OrderDetails), func(a, b clean.OrderDetail) int {
+		return cmp.Or(cmp.Compare(a.OrderNo, b.OrderNo), cmp.Compare(a.OrderDetailNo, b.OrderDetailNo))
+	})
+	return page(rows, limit, offset), nil
+}
+
+// FUNCTION: 受注(OrderView)
+func (r *MemoryCleanReader) FetchWOrders(ctx context.Context, limit int, offset int) (clean.WOrderSlice, error) {
+	return page(r.WOrders, limit, offset), nil
+}
+
+// FUNCTION: 受注明細(OrderDetailView)
+func (r *MemoryCleanReader) FetchWOrderDetails(ctx context.Context, limit int, offset int) (clean.WOrderDetailSlice, error) {
+	return page(r.WOrderDetails, limit, offset), nil
+}
+
+// FUNCTION: ページング(レコードはコピーを返す)
+func page[T any](rows []T, limit int, offset int) []*T {
+	results := []*T{}
+	for i := offset; i < len(rows) && i < offset+limit; i++ {
+		row := rows[i]
+		results = append(results, &row)
+	}
+	return results
+}
+
+// STRUCT: 移行先(インメモリ)
+// INFO: 主キー/外部キーはProductDBと同じ動作とする
+type MemoryProductWriter struct {
+	Operators    []orders.Operator
+	Products     []orders.Product
+	Orders       []orders.Order
+	OrderDetails []orders.OrderDetail
+}
+
+// FUNCTION: truncate(参照元テーブルも削除する)
+func (w *MemoryProductWriter) Truncate(ctx context.Context, table string) error {
+	switch table {
+	case orders.TableNames.Operators:
+		w.Operators, w.Products, w.Orders, w.OrderDetails = nil, nil, nil, nil
+	case orders.TableNames.Products:
+		w.Products, w.OrderDetails = nil, nil
+	case orders.TableNames.Orders:
+		w.Orders, w.OrderDetails = nil, nil
+	case orders.TableNames.OrderDetails:
+		w.OrderDetails = nil
+	default:
+		return fmt.Errorf("unknown product table: %s", table)
+	}
+	return nil
+}
+
+// FUNCTION: 件数
+func (w *MemoryProductWriter) Count(ctx context.Context, table string) (int64, error) {
+	switch table {
+	case orders.TableNames.Operators:
+		return int64(len(w.Operators)), nil
+	case orders.TableNames.Products:
+		return int64(len(w.Products)), nil
+	case orders.TableNames.Orders:
+		return int64(len(w.Orders)), nil
+	case orders.TableNames.OrderDetails:
+		return int64(len(w.OrderDetails)), nil
+	}
+	return 0, fmt.Errorf("unknown product table: %s", table)
+}
+
+// FUNCTION: 担当者
+func (w *MemoryProductWriter) InsertOperator(ctx context.Context, rec orders.Operator) error {
+	if slices.ContainsFunc(w.Operators, func(row orders.Operator) bool { return row.OperatorID == rec.OperatorID }) {
+		return duplicateKeyError("operators_pkey", rec.OperatorID)
+	}
+	w.Operators = append(w.Operators, rec)
+	return nil
+}
+
+// FUNCTION: 商品
+func (w *MemoryProductWriter) InsertProduct(ctx context.Context, rec orders.Product) error {
+	if slices.ContainsFunc(w.Products, func(row orders.Product) bool { return row.ProductID == rec.ProductID }) {
+		return duplicateKeyError("products_pkey", rec.ProductID)
+	}
+	if !w.operatorExists(rec.ProductPic) {
+		return foreignKeyError("products_foreignKey_1", rec.ProductPic)
+	}
+	w.Products = append(w.Products, rec)
+	return nil
+}
+
+// FUNCTION: 受注
+func (w *MemoryProductWriter) InsertOrder(ctx context.Context, rec orders.Order) error {
+	if slices.ContainsFunc(w.Orders, func(row orders.Order) bool { return row.OrderNo == rec.OrderNo }) {
+		return duplicateKeyError("orders_pkey", rec.OrderNo)
+	}
+	if !w.operatorExists(rec.OrderPic) {
+		return foreignKeyError("orders_foreignKey_1", rec.OrderPic)
+	}
+	w.Orders = append(w.Orders, rec)
+	return nil
+}
+
+// FUNCTION: 受注明細
+func (w *MemoryProductWriter) InsertOrderDetail(ctx context.Context, rec orders.OrderDetail) error {
+	if slices.ContainsFunc(w.OrderDetails, func(row orders.OrderDetail) bool {
+		return row.OrderNo == rec.OrderNo && row.ProductID == rec.ProductID
+	}) {
+		return duplicateKeyError("order_details_pkey", fmt.Sprintf("%s, %s", rec.OrderNo, rec.ProductID))
+	}
+	if !slices.ContainsFunc(w.Orders, func(row orders.Order) bool { return row.OrderNo == rec.OrderNo }) {
+		return foreignKeyError("order_details_foreignKey_1", rec.OrderNo)
+	}
+	if !slices.ContainsFunc(w.Products, func(row orders.Product) bool { return row.ProductID == rec.ProductID }) {
+		return foreignKeyError("order_details_foreignKey_2", rec.ProductID)
+	}
+	w.OrderDetails = append(w.OrderDetails, rec)
+	return nil
+}
+
+// FUNCTION: 担当者の存在確認
+func (w *MemoryProductWriter) operatorExists(operatorId string) bool {
+	return slices.ContainsFunc(w.Operators, func(row orders.Operator) bool { return row.OperatorID == operatorId })
+}
+
+// FUNCTION: 一意制約違反(PostgreSQLのメッセージに合わせる)
+func duplicateKeyError(constraint string, key any) error {
+	return fmt.Errorf("duplicate key value violates unique constraint \"%s\" (%v)", constraint, key)
+}
+
+// FUNCTION: 外部キー制約違反(PostgreSQLのメッセージに合わせる)
+func foreignKeyError(constraint string, key any) error {
+	return fmt.Errorf("insert violates foreign key constraint \"%s\" (%v)", constraint, key)
+}
@@ -0,0 +1,44 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package transfer
+
+// TITLE: 移行結果(レポート出力用の構造化データ)
+
+// STRUCT: 移行結果
+type Report struct {
+	Tables []TableResult `json:"tables"`
+	Splits OrderSplits   `json:"splits"`
+}
+
+// STRUCT: テーブル毎の結果件数
+type TableResult struct {
+	Num         int     `json:"num"`
+	Schema      string  `json:"schema"`
+	Table       string  `json:"table"`
+	TableJp     string  `json:"table_jp"`
+	Entry       int     `json:"entry"`
+	Elapsed     float64 `json:"elapsed"`
+	Retry       int     `json:"retry"`
+	Change      int     `json:"change"`
+	Accept      int     `json:"accept"`
+	Consistent  bool    `json:"consistent"`
+	Interrupted bool    `json:"interrupted"`
+}
+
+// FUNCTION: テーブル毎の結果件数
+func (r ResultCount) tableResult(num int, t TableInfo, duration float64, retry int) TableResult {
+	return TableResult{
+		Num:         num,
+		Schema:      t.schema,
+		Table:       t.tableEn,
+		TableJp:     t.tableJp,
+		Entry:       r.entryCount,
+		Elapsed:     duration,
+		Retry:       retry,
+		Change:      r.changeCount,
+		Accept:      r.resultCount,
+		Consistent:  r.checkRecord() == "",
+		Interrupted: r.interrupted,
+	}
+}
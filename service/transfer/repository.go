@@ -0,0 +1,151 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package transfer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/teru-0529/data-transfer-sandbox/spec/product/orders"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
+
+// TITLE: リポジトリ(クレンジング結果の読込み/移行先への登録)
+
+// STRUCT: クレンジング結果の読込み(cleanスキーマのテーブル/ビュー)
+type CleanReader interface {
+	Count(ctx context.Context, table string) (int64, error)
+	FetchOperators(ctx context.Context, limit int, offset int) (clean.OperatorSlice, error)
+	FetchProducts(ctx context.Context, limit int, offset int) (clean.ProductSlice, error)
+	FetchOrders(ctx context.Context, limit int, offset int) (clean.OrderSlice, error)
+	FetchOrderDetails(ctx context.Context, limit int, offset int) (clean.OrderDetailSlice, error)
+	FetchWOrders(ctx context.Context, limit int, offset int) (clean.WOrderSlice, error)
+	FetchWOrderDetails(ctx context.Context, limit int, offset int) (clean.WOrderDetailSlice, error)
+}
+
+// STRUCT: 移行先への登録(ordersスキーマ)
+type ProductWriter interface {
+	Truncate(ctx context.Context, table string) error
+	Count(ctx context.Context, table string) (int64, error)
+	InsertOperator(ctx context.Context, rec orders.Operator) error
+	InsertProduct(ctx context.Context, rec orders.Product) error
+	InsertOrder(ctx context.Context, rec orders.Order) error
+	InsertOrderDetail(ctx context.Context, rec orders.OrderDetail) error
+}
+
+// STRUCT: クレンジング結果の読込み(WorkDB)
+type sqlCleanReader struct {
+	db *sql.DB
+}
+
+// FUNCTION:
+func NewCleanReader(db *sql.DB) CleanReader {
+	return &sqlCleanReader{db: db}
+}
+
+// FUNCTION: 件数
+func (r *sqlCleanReader) Count(ctx context.Context, table string) (int64, error) {
+	switch table {
+	case clean.TableNames.Operators:
+		return clean.Operators().Count(ctx, r.db)
+	case clean.TableNames.Products:
+		return clean.Products().Count(ctx, r.db)
+	case clean.TableNames.Orders:
+		return clean.Orders().Count(ctx, r.db)
+	case clean.TableNames.OrderDetails:
+		return clean.OrderDetails().Count(ctx, r.db)
+	case clean.ViewNames.WOrders:
+		return clean.WOrders().Count(ctx, r.db)
+	case clean.ViewNames.WOrderDetails:
+		return clean.WOrderDetails().Count(ctx, r.db)
+	}
+	return 0, fmt.Errorf("unknown clean table: %s", table)
+}
+
+// FUNCTION: 担当者(担当者ID:昇順)
+func (r *sqlCleanReader) FetchOperators(ctx context.Context, limit int, offset int) (clean.OperatorSlice, error) {
+	return clean.Operators(qm.OrderBy("operator_id ASC"), qm.Limit(limit), qm.Offset(offset)).All(ctx, r.db)
+}
+
+// FUNCTION: 商品(商品名:昇順)
+func (r *sqlCleanReader) FetchProducts(ctx context.Context, limit int, offset int) (clean.ProductSlice, error) {
+	return clean.Products(qm.OrderBy("product_name ASC"), qm.Limit(limit), qm.Offset(offset)).All(ctx, r.db)
+}
+
+// FUNCTION: 受注(受注番号:昇順)
+func (r *sqlCleanReader) FetchOrders(ctx context.Context, limit int, offset int) (clean.OrderSlice, error) {
+	return clean.Orders(qm.OrderBy("order_no ASC"), qm.Limit(limit), qm.Offset(offset)).All(ctx, r.db)
+}
+
+// FUNCTION: 受注明細(受注番号、受注明細番号:昇順)
+func (r *sqlCleanReader) FetchOrderDetails(ctx context.Context, limit int, offset int) (clean.OrderDetailSlice, error) {
+	return clean.OrderDetails(qm.OrderBy("order_no ASC, order_detail_no ASC"), qm.Limit(limit), qm.Offset(offset)).All(ctx, r.db)
+}
+
+// FUNCTION: 受注(OrderView、受注番号(WORK)、受注番号:昇順、明細が存在しない受注は末尾)
+// INFO: ビューは主キーがないため、集約結果(AggregateOrders)と同じ順序とする
+func (r *sqlCleanReader) FetchWOrders(ctx context.Context, limit int, offset int) (clean.WOrderSlice, error) {
+	return clean.WOrders(qm.OrderBy("w_order_no ASC NULLS LAST, order_no ASC"), qm.Limit(limit), qm.Offset(offset)).All(ctx, r.db)
+}
+
+// FUNCTION: 受注明細(OrderDetailView、受注番号(WORK)、商品ID:昇順、登録対象は末尾)
+// INFO: ビューは主キーがないため、集約結果(AggregateOrderDetails)と同じ順序とする(同一グループの登録対象外の行は同じ値)
+func (r *sqlCleanReader) FetchWOrderDetails(ctx context.Context, limit int, offset int) (clean.WOrderDetailSlice, error) {
+	return clean.WOrderDetails(qm.OrderBy("w_order_no ASC, w_product_id ASC, register ASC"), qm.Limit(limit), qm.Offset(offset)).All(ctx, r.db)
+}
+
+// STRUCT: 移行先への登録(ProductDB)
+type sqlProductWriter struct {
+	db *sql.DB
+}
+
+// FUNCTION:
+func NewProductWriter(db *sql.DB) ProductWriter {
+	return &sqlProductWriter{db: db}
+}
+
+// FUNCTION: truncate(シーケンスは初期化する)
+func (w *sqlProductWriter) Truncate(ctx context.Context, table string) error {
+	_, err := queries.Raw(fmt.Sprintf("TRUNCATE %s.%s RESTART IDENTITY CASCADE;", SCHEMA, table)).ExecContext(ctx, w.db)
+	return err
+}
+
+// FUNCTION: 件数
+func (w *sqlProductWriter) Count(ctx context.Context, table string) (int64, error) {
+	switch table {
+	case orders.TableNames.Operators:
+		return orders.Operators().Count(ctx, w.db)
+	case orders.TableNames.Products:
+		return orders.Products().Count(ctx, w.db)
+	case orders.TableNames.Orders:
+		return orders.Orders().Count(ctx, w.db)
+	case orders.TableNames.OrderDetails:
+		return orders.OrderDetails().Count(ctx, w.db)
+	}
+	return 0, fmt.Errorf("unknown product table: %s", table)
+}
+
+// FUNCTION: 担当者
+func (w *sqlProductWriter) InsertOperator(ctx context.Context, rec orders.Operator) error {
+	return rec.Insert(ctx, w.db, boil.Infer())
+}
+
+// FUNCTION: 商品
+func (w *sqlProductWriter) InsertProduct(ctx context.Context, rec orders.Product) error {
+	return rec.Insert(ctx, w.db, boil.Infer())
+}
+
+// FUNCTION: 受注
+func (w *sqlProductWriter) InsertOrder(ctx context.Context, rec orders.Order) error {
+	return rec.Insert(ctx, w.db, boil.Infer())
+}
+
+// FUNCTION: 受注明細
+func (w *sqlProductWriter) InsertOrderDetail(ctx context.Context, rec orders.OrderDetail) error {
+	return rec.Insert(ctx, w.db, boil.Infer())
+}
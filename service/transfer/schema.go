@@ -0,0 +1,204 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package transfer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/spec/product/orders"
+)
+
+// TITLE: 移行先スキーマの検証(マイグレーションバージョン/モデルとのカラム差分)
+
+// STRUCT: 移行先のスキーマ
+const SCHEMA = "orders"
+
+// STRUCT: モデル定義(sqlboilerのモデルが前提とするカラム)
+var MODEL_COLUMNS = []struct {
+	table   string
+	columns []string
+}{
+	{orders.TableNames.Operators, infra.ModelColumns(orders.OperatorColumns)},
+	{orders.TableNames.Products, infra.ModelColumns(orders.ProductColumns)},
+	{orders.TableNames.Orders, infra.ModelColumns(orders.OrderColumns)},
+	{orders.TableNames.OrderDetails, infra.ModelColumns(orders.OrderDetailColumns)},
+}
+
+// STRUCT: 検証結果
+type SchemaCheck struct {
+	AppVersion       string
+	MigrationVersion string //取得できない場合は空
+	ExpectedVersion  string //数値型の場合はMIGRATION_VERSION、文字列型の場合はAPP_VERSION
+	MigrationErr     error
+	Drifts           []infra.ColumnDrift
+	schema           infra.DbSchema
+	matched          bool
+}
+
+// FUNCTION: 移行先スキーマの検証(TRUNCATE前に実行する)
+func CheckSchema(ctx context.Context, config infra.Config, db *sql.DB) (SchemaCheck, error) {
+	check := SchemaCheck{AppVersion: config.Base.AppVersion}
+
+	// PROCESS: マイグレーションバージョンの取得(テーブルが存在しない場合は中止理由とする)
+	check.MigrationVersion, check.ExpectedVersion, check.matched, check.MigrationErr = migrationVersion(ctx, db, config.Base)
+
+	// PROCESS: カラムの差分
+	schema, err := infra.FetchSchema(ctx, db, "postgres", SCHEMA)
+	if err != nil {
+		return check, err
+	}
+	check.schema = schema
+	for _, model := range MODEL_COLUMNS {
+		check.Drifts = append(check.Drifts, infra.CompareColumns(model.table, model.columns, schema))
+	}
+	return check, nil
+}
+
+// FUNCTION: マイグレーションバージョンの取得と比較(最新/期待するバージョン、一致有無)
+// INFO: 数値型(golang-migrate等)はMIGRATION_VERSION、文字列型はAPP_VERSIONとセマンティックバージョンとして比較する
+func migrationVersion(ctx context.Context, db *sql.DB, base infra.BaseConfig) (string, string, bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %s;", base.MigrationTable))
+	if err != nil {
+		return "", "", false, err
+	}
+	defer rows.Close()
+
+	// PROCESS: 最新バージョン
+	var latest []int64
+	current := ""
+	numeric := false
+	for rows.Next() {
+		var value any
+		if err := rows.Scan(&value); err != nil {
+			return "", "", false, err
+		}
+		var version []int64
+		var text string
+		switch v := value.(type) {
+		case int64:
+			numeric, version, text = true, []int64{v}, strconv.FormatInt(v, 10)
+		case []byte:
+			text = string(v)
+			version, err = parseSemver(text)
+		case string:
+			text = v
+			version, err = parseSemver(text)
+		default:
+			err = fmt.Errorf("unsupported version type %T", value)
+		}
+		if err != nil {
+			return "", "", false, fmt.Errorf("invalid migration version in %s: %s", base.MigrationTable, err.Error())
+		}
+		if latest == nil || slices.Compare(version, latest) > 0 {
+			latest, current = version, text
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", false, err
+	}
+	if latest == nil {
+		return "", "", false, fmt.Errorf("no migration version in %s", base.MigrationTable)
+	}
+
+	// PROCESS: 期待するバージョンとの比較
+	if numeric {
+		if base.MigrationVersion == 0 {
+			return current, "", false, fmt.Errorf("MIGRATION_VERSION is not set (%s.version is numeric)", base.MigrationTable)
+		}
+		return current, strconv.FormatInt(base.MigrationVersion, 10), latest[0] == base.MigrationVersion, nil
+	}
+	expected, err := parseSemver(base.AppVersion)
+	if err != nil {
+		return current, base.AppVersion, false, fmt.Errorf("invalid APP_VERSION: %s", err.Error())
+	}
+	return current, base.AppVersion, slices.Equal(latest, expected), nil
+}
+
+// FUNCTION: セマンティックバージョンの解析(先頭の`v`、プレリリース/ビルド情報は無視し、不足は0で補う)
+func parseSemver(str string) ([]int64, error) {
+	core, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(str), "v"), "-")
+	core, _, _ = strings.Cut(core, "+")
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("`%s` is not a semantic version", str)
+	}
+	version := make([]int64, 3)
+	for i, part := range parts {
+		num, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || num < 0 {
+			return nil, fmt.Errorf("`%s` is not a semantic version", str)
+		}
+		version[i] = num
+	}
+	return version, nil
+}
+
+// FUNCTION: 移行を中止すべき差分(バージョン不一致、カラム不足、既定値のないNOT NULLカラムの追加)
+func (c SchemaCheck) Breaking() []string {
+	reasons := []string{}
+	if c.MigrationErr != nil {
+		reasons = append(reasons, fmt.Sprintf("migration version is unknown: %v", c.MigrationErr))
+	} else if !c.matched {
+		reasons = append(reasons, fmt.Sprintf("migration version `%s` does not match expected version `%s`", c.MigrationVersion, c.ExpectedVersion))
+	}
+	for _, drift := range c.Drifts {
+		if len(drift.Missing) > 0 {
+			reasons = append(reasons, fmt.Sprintf("%s.%s: missing columns %v", SCHEMA, drift.Table, drift.Missing))
+		}
+		for _, name := range drift.Extra {
+			column := c.schema[drift.Table][name]
+			if !column.Nullable && !column.HasDefault {
+				reasons = append(reasons, fmt.Sprintf("%s.%s: extra NOT NULL column without default `%s`", SCHEMA, drift.Table, name))
+			}
+		}
+	}
+	return reasons
+}
+
+// FUNCTION: 検証結果のメッセージ
+func (c SchemaCheck) Report() string {
+	msg := "\n## Product Schema Check\n\n"
+
+	// PROCESS: バージョン
+	switch {
+	case c.MigrationErr != nil:
+		msg += fmt.Sprintf("- **migration version**: <span style=\"color:red;\">UNKNOWN</span> (%v)\n", c.MigrationErr)
+	case c.matched:
+		msg += fmt.Sprintf("- **migration version**: %s ✅\n", c.MigrationVersion)
+	default:
+		msg += fmt.Sprintf("- **migration version**: <span style=\"color:red;\">%s (expected: %s)</span>\n", c.MigrationVersion, c.ExpectedVersion)
+	}
+
+	// PROCESS: カラムの差分
+	msg += "\n  | TABLE | MISSING COLUMNS | EXTRA COLUMNS | RESULT |\n"
+	msg += "  |---|---|---|:-:|\n"
+	for _, drift := range c.Drifts {
+		result := "⭕"
+		if drift.HasDrift() {
+			result = "⚠"
+		}
+		msg += fmt.Sprintf("  | %s.%s | %s | %s | %s |\n",
+			SCHEMA,
+			drift.Table,
+			strings.Join(drift.Missing, "<br>"),
+			strings.Join(drift.Extra, "<br>"),
+			result,
+		)
+	}
+
+	// PROCESS: 中止理由
+	if reasons := c.Breaking(); len(reasons) > 0 {
+		msg += "\n<span style=\"color:red;\">**BREAKING DRIFT**</span>\n\n"
+		for _, reason := range reasons {
+			msg += fmt.Sprintf("- %s\n", reason)
+		}
+	}
+	return msg
+}
@@ -0,0 +1,226 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package transfer
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/spec/product/orders"
+)
+
+// TITLE: 受注分割の内訳(販売単価/商品原価が異なる同一商品による受注番号(新)の分割)
+
+// STRUCT: 受注分割の内訳(構造化出力)
+type OrderSplits []OrderSplit
+
+// STRUCT: 受注分割の内訳(受注番号(旧)単位)
+type OrderSplit struct {
+	OrderNo int          `json:"order_no"`
+	Orders  []SplitOrder `json:"orders"`
+}
+
+// STRUCT: 分割後の受注(受注番号(新)単位)
+type SplitOrder struct {
+	WOrderNo            string      `json:"w_order_no"`
+	TotalOrderPrice     int64       `json:"total_order_price"`
+	RemainingOrderPrice int64       `json:"remaining_order_price"`
+	Lines               []SplitLine `json:"lines"`
+}
+
+// STRUCT: 分割後の受注明細(受注番号(新)/商品単位、集約した受注明細番号)
+type SplitLine struct {
+	ProductName       string `json:"product_name"`
+	ProductID         string `json:"product_id"`
+	SellingPrice      int    `json:"selling_price"`
+	CostPrice         int    `json:"cost_price"`
+	ReceivingQuantity int64  `json:"receiving_quantity"`
+	ShippingQuantity  int64  `json:"shipping_quantity"`
+	CancelQuantity    int64  `json:"cancel_quantity"`
+	RemainingQuantity int64  `json:"remaining_quantity"`
+	TotalPrice        int64  `json:"total_price"`
+	OrderDetailNos    []int  `json:"order_detail_nos"`
+}
+
+// FUNCTION: 受注分割の内訳(登録した受注番号(新)が複数となった受注、登録したw_order_detailsの登録対象レコードから作成)
+func (a Aggregation) Splits(inserted *insertedRows) OrderSplits {
+	// PROCESS: 登録した受注(受注番号(旧)単位)
+	splits := OrderSplits{}
+	for _, order := range a.WOrders {
+		if !order.Register.Bool || !inserted.order(order.WOrderNo.String) {
+			continue
+		}
+		i := slices.IndexFunc(splits, func(s OrderSplit) bool { return s.OrderNo == order.OrderNo.Int })
+		if i < 0 {
+			splits = append(splits, OrderSplit{OrderNo: order.OrderNo.Int})
+			i = len(splits) - 1
+		}
+		splits[i].Orders = append(splits[i].Orders, SplitOrder{
+			WOrderNo:            order.WOrderNo.String,
+			TotalOrderPrice:     order.WTotalOrderPrice.Int64,
+			RemainingOrderPrice: order.WRemainingOrderPrice.Int64,
+			Lines:               []SplitLine{},
+		})
+	}
+
+	// PROCESS: 分割した受注番号(旧)のみ
+	splits = slices.DeleteFunc(splits, func(s OrderSplit) bool { return len(s.Orders) < 2 })
+	index := map[string][2]int{} //受注番号(新) → 内訳の位置
+	for i, split := range splits {
+		for j, order := range split.Orders {
+			index[order.WOrderNo] = [2]int{i, j}
+		}
+	}
+
+	// PROCESS: 登録した受注明細(集約後)
+	for _, detail := range a.WOrderDetails {
+		pos, exist := index[detail.WOrderNo.String]
+		if !detail.Register.Bool || !exist || !inserted.orderDetail(detail.WOrderNo.String, detail.WProductID.String) {
+			continue
+		}
+		order := &splits[pos[0]].Orders[pos[1]]
+		order.Lines = append(order.Lines, SplitLine{
+			ProductName:       detail.ProductName.String,
+			ProductID:         detail.WProductID.String,
+			SellingPrice:      detail.SellingPrice.Int,
+			CostPrice:         detail.CostPrice.Int,
+			ReceivingQuantity: detail.ReceivingQuantity.Int64,
+			ShippingQuantity:  detail.WShippingQuantity.Int64,
+			CancelQuantity:    detail.WCancelQuantity.Int64,
+			RemainingQuantity: detail.WRemainingQuantity.Int64,
+			TotalPrice:        detail.ReceivingQuantity.Int64 * int64(detail.SellingPrice.Int),
+			OrderDetailNos:    detailNos(detail.AggregatedDetails.String),
+		})
+	}
+
+	slices.SortFunc(splits, func(a, b OrderSplit) int { return cmp.Compare(a.OrderNo, b.OrderNo) })
+	return splits
+}
+
+// STRUCT: 登録した受注/受注明細(受注分割の内訳用)
+type insertedRows struct {
+	orders       map[string]struct{}    //受注番号(新)
+	orderDetails map[[2]string]struct{} //受注番号(新)/商品ID
+	failed       map[string]struct{}    //登録に失敗したキー(リトライ後の一意制約違反の判定用)
+}
+
+// FUNCTION:
+func newInsertedRows() *insertedRows {
+	return &insertedRows{orders: map[string]struct{}{}, orderDetails: map[[2]string]struct{}{}, failed: map[string]struct{}{}}
+}
+
+// FUNCTION: 受注の登録有無
+func (r *insertedRows) order(wOrderNo string) bool {
+	_, exist := r.orders[wOrderNo]
+	return exist
+}
+
+// FUNCTION: 受注明細の登録有無
+func (r *insertedRows) orderDetail(wOrderNo string, productId string) bool {
+	_, exist := r.orderDetails[[2]string{wOrderNo, productId}]
+	return exist
+}
+
+// FUNCTION: 登録結果の判定
+// INFO: infra.RetryInsertと同様に、登録に失敗したキーのリトライ後の一意制約違反は登録済みとする
+func (r *insertedRows) succeeded(key string, err error) bool {
+	if err == nil {
+		return true
+	}
+	_, retried := r.failed[key]
+	r.failed[key] = struct{}{}
+	return retried && infra.IsDuplicate(err)
+}
+
+// STRUCT: 登録した受注/受注明細を記録するリポジトリ
+type recordingWriter struct {
+	ProductWriter
+	inserted *insertedRows
+}
+
+// FUNCTION: 受注
+func (w *recordingWriter) InsertOrder(ctx context.Context, rec orders.Order) error {
+	err := w.ProductWriter.InsertOrder(ctx, rec)
+	if w.inserted.succeeded("order:"+rec.OrderNo, err) {
+		w.inserted.orders[rec.OrderNo] = struct{}{}
+	}
+	return err
+}
+
+// FUNCTION: 受注明細
+func (w *recordingWriter) InsertOrderDetail(ctx context.Context, rec orders.OrderDetail) error {
+	err := w.ProductWriter.InsertOrderDetail(ctx, rec)
+	if w.inserted.succeeded("order_detail:"+rec.OrderNo+"|"+rec.ProductID, err) {
+		w.inserted.orderDetails[[2]string{rec.OrderNo, rec.ProductID}] = struct{}{}
+	}
+	return err
+}
+
+// FUNCTION: 受注分割の内訳ファイルの出力
+func (s OrderSplits) Save(filePath string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return infra.WriteText(filePath, string(data))
+}
+
+// FUNCTION: 集約した受注明細番号(昇順)
+func detailNos(str string) []int {
+	nos := []int{}
+	for _, s := range strings.Split(str, ",") {
+		if no, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			nos = append(nos, no)
+		}
+	}
+	slices.Sort(nos)
+	return nos
+}
+
+// FUNCTION: 受注分割の内訳の出力
+func showSplits(ctx infra.AppCtx, splits OrderSplits) string {
+	if len(splits) == 0 {
+		return ""
+	}
+
+	var msg string
+	msg += "\n### orders(受注) split breakdown\n"
+	for _, split := range splits {
+		msg += fmt.Sprintf("\n#### order_no: %d → %d orders\n\n", split.OrderNo, len(split.Orders))
+		msg += "  | w_order_no | product_name | selling_price | cost_price | receiving | shipping | cancel | remaining | price | order_detail_no |\n"
+		msg += "  |---|---|--:|--:|--:|--:|--:|--:|--:|---|\n"
+		for _, order := range split.Orders {
+			for _, line := range order.Lines {
+				nos := make([]string, len(line.OrderDetailNos))
+				for i, no := range line.OrderDetailNos {
+					nos[i] = strconv.Itoa(no)
+				}
+				msg += fmt.Sprintf("  | %s | %s | %s | %s | %s | %s | %s | %s | %s | %s |\n",
+					order.WOrderNo,
+					line.ProductName,
+					ctx.Printer.Sprintf("%d", line.SellingPrice),
+					ctx.Printer.Sprintf("%d", line.CostPrice),
+					ctx.Printer.Sprintf("%d", line.ReceivingQuantity),
+					ctx.Printer.Sprintf("%d", line.ShippingQuantity),
+					ctx.Printer.Sprintf("%d", line.CancelQuantity),
+					ctx.Printer.Sprintf("%d", line.RemainingQuantity),
+					ctx.Printer.Sprintf("%d", line.TotalPrice),
+					strings.Join(nos, ", "),
+				)
+			}
+			msg += fmt.Sprintf("  | **%s** | total | | | | | | | **%s**<br>(remaining %s) | |\n",
+				order.WOrderNo,
+				ctx.Printer.Sprintf("%d", order.TotalOrderPrice),
+				ctx.Printer.Sprintf("%d", order.RemainingOrderPrice),
+			)
+		}
+	}
+	return msg
+}
@@ -4,7 +4,9 @@ Copyright © 2024 Teruaki Sato <andrea.pirlo.0529@gmail.com>
 package transfer
 
 import (
+	"context"
 	"fmt"
+	"log"
 
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 )
@@ -49,30 +51,85 @@ func modifiedPiece(msg string, count int) *Piece {
 
 // STRUCT: コントローラー
 type Controller struct {
-	num   int
-	ctx   infra.AppCtx
-	conns infra.DbConnection
+	num         int
+	ctx         infra.AppCtx
+	reader      CleanReader
+	writer      ProductWriter
+	source      CleanReader
+	aggregation Aggregation
+	inserted    *insertedRows
+	report      *Report
 }
 
 // FUNCTION:
-func New(conns infra.DbConnection) *Controller {
-	return &Controller{
-		num:   0,
-		ctx:   infra.NewCtx(),
-		conns: conns,
+func New(run context.Context, config infra.Config, conns infra.DbConnection) *Controller {
+	// PROCESS: 個別指定値の読込み
+	overrides, err := infra.LoadOverrides(config.Base.OverrideDir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx := infra.NewCtx(run, config.Run, config.Run.TransferStatementTimeout, overrides)
+	controller, err := NewController(ctx, NewCleanReader(conns.WorkDB), NewProductWriter(conns.ProductDB))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return controller
+}
+
+// FUNCTION: リポジトリを指定して生成(インメモリリポジトリによる検証用)
+// INFO: 受注/受注明細は、w_orders/w_order_detailsビューに代えてcleanスキーマの集約結果を移行する
+func NewController(ctx infra.AppCtx, source CleanReader, writer ProductWriter) (*Controller, error) {
+	// INFO: SQLのタイムアウト/キャンセルの場合は中断(各テーブルの移行をスキップ)
+	aggregation, err := LoadAggregation(ctx, source)
+	if err != nil && !ctx.InterruptIf(err) {
+		return nil, err
 	}
+	// INFO: 受注分割の内訳は、登録した受注/受注明細から作成する
+	inserted := newInsertedRows()
+	return &Controller{
+		num:         0,
+		ctx:         ctx,
+		reader:      aggregation.Reader(source),
+		writer:      &recordingWriter{ProductWriter: writer, inserted: inserted},
+		source:      source,
+		aggregation: aggregation,
+		inserted:    inserted,
+		report:      &Report{Tables: []TableResult{}, Splits: OrderSplits{}},
+	}, nil
+}
+
+// FUNCTION: 集約結果とビューの突合せ
+func (c *Controller) ViewCheck() (string, error) {
+	return c.aggregation.CrossCheck(c.ctx, c.source)
+}
+
+// FUNCTION: 受注分割の内訳(登録済みの受注/受注明細)
+func (c *Controller) Splits() OrderSplits {
+	return c.aggregation.Splits(c.inserted)
+}
+
+// FUNCTION: 受注分割の内訳(詳細メッセージ)
+func (c *Controller) ShowSplits() string {
+	return showSplits(c.ctx, c.Splits())
 }
 
 // FUNCTION: インボーカーの生成
 func (c *Controller) CreateInvocer(cmd Command) *Invoker {
 	c.num++
-	return NewInvoker(c.num, c.ctx, c.conns, cmd)
+	return NewInvoker(c.num, c.ctx, c.reader, c.writer, c.report, cmd)
+}
+
+// FUNCTION: 移行結果(レポート出力用、受注分割の内訳は呼び出し時点の登録済みの受注/受注明細から作成)
+func (c *Controller) Report() *Report {
+	c.report.Splits = c.Splits()
+	return c.report
 }
 
 // FUNCTION: ヘッダーメッセージ
 func (c *Controller) Head() string {
 	msg := "\n## Data Transfer to Production DB\n\n"
-	msg += "  | # | SCHEMA | TABLE | ENTRY | ELAPSED | … | CHANGE | … | ACCEPT | CHECK |\n"
-	msg += "  |--:|---|---|--:|--:|---|--:|---|--:|:--:|\n"
+	msg += "  | # | SCHEMA | TABLE | ENTRY | ELAPSED | RETRY | … | CHANGE | … | ACCEPT | CHECK |\n"
+	msg += "  |--:|---|---|--:|--:|--:|---|--:|---|--:|:--:|\n"
 	return msg
 }
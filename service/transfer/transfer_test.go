@@ -0,0 +1,255 @@
+/*
+Copyright © 2025 Teruaki Sato <andrea.pirlo.0529@gmail.com>
+*/
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/teru-0529/data-transfer-sandbox/infra"
+	"github.com/teru-0529/data-transfer-sandbox/spec/product/orders"
+	"github.com/teru-0529/data-transfer-sandbox/spec/source/clean"
+)
+
+// TITLE: 移行(インメモリリポジトリ)
+
+// STRUCT: テーブル毎の結果件数(ENTRY/CHANGE/ACCEPT)
+type resultCount struct {
+	entry  int
+	change int
+	accept int
+}
+
+// FUNCTION: 移行元(担当者/商品は共通)
+func cleanSource(cleanOrders []clean.Order, details []clean.OrderDetail) *MemoryCleanReader {
+	return &MemoryCleanReader{
+		Operators: []clean.Operator{
+			{OperatorID: "AB001", OperatorName: "山田太郎"},
+			{OperatorID: "Z9999", OperatorName: "N/A"},
+		},
+		Products: []clean.Product{
+			{ProductName: "ボールペン", CostPrice: 100, WProductID: "P0001"},
+			{ProductName: "消しゴム", CostPrice: 50, WProductID: "P0002"},
+		},
+		Orders:       cleanOrders,
+		OrderDetails: details,
+	}
+}
+
+// FUNCTION: 受注明細(cleanスキーマのトリガーと同じ数量/金額)
+func cleanDetail(orderNo int, detailNo int, wOrderNo string, productName string, quantity int, price int, shipped bool) clean.OrderDetail {
+	rec := clean.OrderDetail{
+		OrderNo:           orderNo,
+		OrderDetailNo:     detailNo,
+		ProductName:       productName,
+		ReceivingQuantity: quantity,
+		ShippingFlag:      shipped,
+		SellingPrice:      price,
+		CostPrice:         price / 2,
+		WOrderNo:          wOrderNo,
+		WTotalOrderPrice:  quantity * price,
+	}
+	if shipped {
+		rec.WShippingQuantity = quantity
+	} else {
+		rec.WRemainingQuantity = quantity
+		rec.WRemainingOrderPrice = quantity * price
+	}
+	return rec
+}
+
+// FUNCTION: 受注の分割/明細の集約による件数の増減と登録レコード
+func TestController(t *testing.T) {
+	order := func(orderNo int) clean.Order {
+		return clean.Order{OrderNo: orderNo, OrderDate: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), OrderPic: "山田太郎", CustomerName: "A商事"}
+	}
+	tests := []struct {
+		name         string
+		source       *MemoryCleanReader
+		counts       [4]resultCount //担当者/商品/受注/受注明細
+		orders       []string
+		orderDetails []string
+	}{
+		{
+			name:         "unchange",
+			source:       cleanSource([]clean.Order{order(1001)}, []clean.OrderDetail{cleanDetail(1001, 1, "W1001-1", "ボールペン", 2, 150, false)}),
+			counts:       [4]resultCount{{2, 0, 2}, {2, 0, 2}, {1, 0, 1}, {1, 0, 1}},
+			orders:       []string{"W1001-1:AB001:300:300"},
+			orderDetails: []string{"W1001-1:P0001:2:0:2"},
+		},
+		{
+			name: "split order (same product, different selling_price)",
+			source: cleanSource([]clean.Order{order(1001)}, []clean.OrderDetail{
+				cleanDetail(1001, 1, "W1001-1", "ボールペン", 2, 150, true),
+				cleanDetail(1001, 2, "W1001-2", "ボールペン", 3, 160, false),
+			}),
+			counts:       [4]resultCount{{2, 0, 2}, {2, 0, 2}, {1, 1, 2}, {2, 0, 2}},
+			orders:       []string{"W1001-1:AB001:300:0", "W1001-2:AB001:480:480"},
+			orderDetails: []string{"W1001-1:P0001:2:2:0", "W1001-2:P0001:3:0:3"},
+		},
+		{
+			name: "aggregate order details (same product, same selling_price)",
+			source: cleanSource([]clean.Order{order(1001)}, []clean.OrderDetail{
+				cleanDetail(1001, 1, "W1001-1", "ボールペン", 2, 150, true),
+				cleanDetail(1001, 2, "W1001-1", "消しゴム", 1, 80, false),
+				cleanDetail(1001, 3, "W1001-1", "ボールペン", 3, 150, false),
+			}),
+			counts:       [4]resultCount{{2, 0, 2}, {2, 0, 2}, {1, 0, 1}, {3, -1, 2}},
+			orders:       []string{"W1001-1:AB001:830:530"},
+			orderDetails: []string{"W1001-1:P0001:5:2:3", "W1001-1:P0002:1:0:1"},
+		},
+		{
+			name:         "order without details",
+			source:       cleanSource([]clean.Order{order(1001), order(1002)}, []clean.OrderDetail{cleanDetail(1001, 1, "W1001-1", "ボールペン", 2, 150, false)}),
+			counts:       [4]resultCount{{2, 0, 2}, {2, 0, 2}, {2, -1, 1}, {1, 0, 1}},
+			orders:       []string{"W1001-1:AB001:300:300"},
+			orderDetails: []string{"W1001-1:P0001:2:0:2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := infra.NewCtx(context.Background(), infra.RunConfig{FetchLimit: 2, DateLayout: "20060102"}, 0, infra.Overrides{})
+			writer := &MemoryProductWriter{}
+			controller, err := NewController(ctx, tt.source, writer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			controller.CreateInvocer(NewOperatorsCmd()).Execute()
+			controller.CreateInvocer(NewProductsCmd()).Execute()
+			controller.CreateInvocer(NewOrdersCmd()).Execute()
+			controller.CreateInvocer(NewOrderDetailsCmd()).Execute()
+
+			// PROCESS: 結果件数
+			tables := controller.Report().Tables
+			if len(tables) != len(tt.counts) {
+				t.Fatalf("tables: got %d, want %d", len(tables), len(tt.counts))
+			}
+			for i, want := range tt.counts {
+				got := resultCount{tables[i].Entry, tables[i].Change, tables[i].Accept}
+				if got != want {
+					t.Errorf("%s: got %+v, want %+v", tables[i].Table, got, want)
+				}
+				if !tables[i].Consistent {
+					t.Errorf("%s: ENTRY%+d != ACCEPT", tables[i].Table, tables[i].Change)
+				}
+			}
+
+			// PROCESS: 登録レコード
+			assertRows(t, orders.TableNames.Operators, writer.Operators, []string{"AB001:山田太郎", "Z9999:N/A"}, func(r orders.Operator) string {
+				return fmt.Sprintf("%s:%s", r.OperatorID, r.OperatorName)
+			})
+			assertRows(t, orders.TableNames.Products, writer.Products, []string{"P0001:ボールペン:Z9999", "P0002:消しゴム:Z9999"}, func(r orders.Product) string {
+				return fmt.Sprintf("%s:%s:%s", r.ProductID, r.ProductName, r.ProductPic)
+			})
+			assertRows(t, orders.TableNames.Orders, writer.Orders, tt.orders, func(r orders.Order) string {
+				return fmt.Sprintf("%s:%s:%d:%d", r.OrderNo, r.OrderPic, r.TotalOrderPrice, r.RemainingOrderPrice)
+			})
+			assertRows(t, orders.TableNames.OrderDetails, writer.OrderDetails, tt.orderDetails, func(r orders.OrderDetail) string {
+				return fmt.Sprintf("%s:%s:%d:%d:%d", r.OrderNo, r.ProductID, r.ReceivingQuantity, r.ShippingQuantity, r.RemainingQuantity)
+			})
+		})
+	}
+}
+
+// FUNCTION: 登録レコードの比較(順不同)
+func assertRows[T any](t *testing.T, table string, rows []T, want []string, key func(T) string) {
+	t.Helper()
+	got := []string{}
+	for _, row := range rows {
+		got = append(got, key(row))
+	}
+	slices.Sort(got)
+	want = slices.Sorted(slices.Values(want))
+	if !slices.Equal(got, want) {
+		t.Errorf("%s: got %v, want %v", table, got, want)
+	}
+}
+
+// STRUCT: 指定した受注番号(新)の登録に失敗する移行先
+type failingWriter struct {
+	*MemoryProductWriter
+	wOrderNo string
+}
+
+// FUNCTION: 受注
+func (w *failingWriter) InsertOrder(ctx context.Context, rec orders.Order) error {
+	if rec.OrderNo == w.wOrderNo {
+		return fmt.Errorf("insert failed: %s", rec.OrderNo)
+	}
+	return w.MemoryProductWriter.InsertOrder(ctx, rec)
+}
+
+// FUNCTION: 受注明細
+func (w *failingWriter) InsertOrderDetail(ctx context.Context, rec orders.OrderDetail) error {
+	if rec.OrderNo == w.wOrderNo {
+		return fmt.Errorf("insert failed: %s", rec.OrderNo)
+	}
+	return w.MemoryProductWriter.InsertOrderDetail(ctx, rec)
+}
+
+// FUNCTION: 受注分割の内訳(登録した受注/受注明細から作成)
+func TestSplits(t *testing.T) {
+	order := clean.Order{OrderNo: 1001, OrderDate: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), OrderPic: "山田太郎", CustomerName: "A商事"}
+	details := []clean.OrderDetail{
+		cleanDetail(1001, 1, "W1001-1", "ボールペン", 2, 150, false),
+		cleanDetail(1001, 2, "W1001-2", "ボールペン", 3, 160, false),
+		cleanDetail(1001, 3, "W1001-3", "ボールペン", 1, 170, false),
+		cleanDetail(1001, 4, "W1001-3", "消しゴム", 1, 80, false),
+	}
+	tests := []struct {
+		name     string
+		details  []clean.OrderDetail
+		wOrderNo string
+		want     []string //受注番号(新):商品ID
+	}{
+		{
+			name:    "all inserted",
+			details: details,
+			want:    []string{"W1001-1:P0001", "W1001-2:P0001", "W1001-3:P0001", "W1001-3:P0002"},
+		},
+		{
+			name:     "one of the split orders failed",
+			details:  details,
+			wOrderNo: "W1001-3",
+			want:     []string{"W1001-1:P0001", "W1001-2:P0001"},
+		},
+		{
+			name:     "only one order inserted (not split)",
+			details:  details[:2],
+			wOrderNo: "W1001-2",
+			want:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := infra.NewCtx(context.Background(), infra.RunConfig{FetchLimit: 2, DateLayout: "20060102"}, 0, infra.Overrides{})
+			writer := &failingWriter{MemoryProductWriter: &MemoryProductWriter{}, wOrderNo: tt.wOrderNo}
+			controller, err := NewController(ctx, cleanSource([]clean.Order{order}, tt.details), writer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			controller.CreateInvocer(NewOperatorsCmd()).Execute()
+			controller.CreateInvocer(NewProductsCmd()).Execute()
+			controller.CreateInvocer(NewOrdersCmd()).Execute()
+			controller.CreateInvocer(NewOrderDetailsCmd()).Execute()
+
+			got := []string{}
+			for _, split := range controller.Report().Splits {
+				for _, order := range split.Orders {
+					for _, line := range order.Lines {
+						got = append(got, fmt.Sprintf("%s:%s", order.WOrderNo, line.ProductID))
+					}
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,8 +5,10 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
+	"github.com/lib/pq"
 	"github.com/teru-0529/data-transfer-sandbox/infra"
 	"github.com/volatiletech/sqlboiler/v4/boil"
 	"github.com/volatiletech/sqlboiler/v4/queries"
@@ -14,21 +16,39 @@ import (
 
 // TITLE: トランケート
 
-// STRUCT: テーブル名
-type TableNameOnly struct {
-	Tablename string `boil:"tablename"`
+// STRUCT: workDBのロード対象スキーマ(cleanDB/隔離データ)
+var WORK_SCHEMAS = []string{"clean", "quarantine"}
+
+// STRUCT: スキーマ名/テーブル名
+type SchemaTableName struct {
+	Schemaname string `boil:"schemaname"`
+	Tablename  string `boil:"tablename"`
 }
 
-// FUNCTION: cleanDBのテーブルを全てtruncate
-func TruncateCleanDbAll(conns infra.DbConnection) {
+// FUNCTION: cleanDB(隔離データを含む)のテーブルを全てtruncate
+func TruncateCleanDbAll(conns infra.DbConnection) error {
 	boil.DebugMode = true
+	defer func() { boil.DebugMode = false }()
 
 	var ctx context.Context = context.Background()
-	var tables []TableNameOnly
-	queries.Raw("SELECT tablename FROM pg_catalog.pg_tables WHERE schemaname = 'clean'").Bind(ctx, conns.WorkDB, &tables)
+	tables, err := schemaTables(ctx, conns.WorkDB, WORK_SCHEMAS)
+	if err != nil {
+		return fmt.Errorf("cannot get table names: %v", err)
+	}
 	for _, table := range tables {
-		queries.Raw(fmt.Sprintf("truncate clean.%s CASCADE;", table.Tablename)).ExecContext(ctx, conns.WorkDB)
+		if _, err := queries.Raw(fmt.Sprintf("truncate %s.%s CASCADE;", table.Schemaname, table.Tablename)).ExecContext(ctx, conns.WorkDB); err != nil {
+			return fmt.Errorf("cannot truncate %s.%s: %v", table.Schemaname, table.Tablename, err)
+		}
 	}
+	return nil
+}
 
-	boil.DebugMode = false
+// FUNCTION: スキーマ内のテーブル名一覧
+func schemaTables(ctx context.Context, db *sql.DB, schemas []string) ([]SchemaTableName, error) {
+	var tables []SchemaTableName
+	err := queries.Raw(
+		"SELECT schemaname, tablename FROM pg_catalog.pg_tables WHERE schemaname = ANY($1) ORDER BY schemaname, tablename",
+		pq.Array(schemas),
+	).Bind(ctx, db, &tables)
+	return tables, err
 }
@@ -23,3 +23,4 @@ CREATE EVENT TRIGGER pgrst_watch
   EXECUTE PROCEDURE pgrst_watch();
 
 CREATE SCHEMA IF NOT EXISTS clean;
+CREATE SCHEMA IF NOT EXISTS quarantine;
@@ -0,0 +1,53 @@
+-- is_master_table=false
+
+-- 変更履歴(change_log)
+
+-- Create Table
+DROP TABLE IF EXISTS clean.change_log CASCADE;
+CREATE TABLE clean.change_log (
+  change_log_id serial NOT NULL,
+  table_name text NOT NULL,
+  legacy_key text NOT NULL,
+  column_name text NOT NULL,
+  original_value text NOT NULL,
+  cleansed_value text NOT NULL,
+  rule_id text,
+  created_at timestamp NOT NULL DEFAULT current_timestamp,
+  updated_at timestamp NOT NULL DEFAULT current_timestamp,
+  created_by varchar(58),
+  updated_by varchar(58)
+);
+
+-- Set Table Comment
+COMMENT ON TABLE clean.change_log IS '変更履歴';
+
+-- Set Column Comment
+COMMENT ON COLUMN clean.change_log.change_log_id IS '変更履歴ID';
+COMMENT ON COLUMN clean.change_log.table_name IS 'テーブル名';
+COMMENT ON COLUMN clean.change_log.legacy_key IS '移行元キー';
+COMMENT ON COLUMN clean.change_log.column_name IS 'カラム名';
+COMMENT ON COLUMN clean.change_log.original_value IS '移行元の値';
+COMMENT ON COLUMN clean.change_log.cleansed_value IS 'クレンジング後の値';
+COMMENT ON COLUMN clean.change_log.rule_id IS 'ルールID(NULLは再登録データによる変更)';
+COMMENT ON COLUMN clean.change_log.created_at IS '作成日時';
+COMMENT ON COLUMN clean.change_log.updated_at IS '更新日時';
+COMMENT ON COLUMN clean.change_log.created_by IS '作成者';
+COMMENT ON COLUMN clean.change_log.updated_by IS '更新者';
+
+-- Set PK Constraint
+ALTER TABLE clean.change_log ADD PRIMARY KEY (
+  change_log_id
+);
+
+-- Create Index
+CREATE INDEX change_log_idx_1 ON clean.change_log (
+  table_name,
+  legacy_key
+);
+
+-- Create 'set_update_at' Trigger
+CREATE TRIGGER set_updated_at
+  BEFORE UPDATE
+  ON clean.change_log
+  FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at();
@@ -0,0 +1,47 @@
+-- is_master_table=false
+
+-- 1.担当者(隔離)(operators)
+
+-- Create Table
+DROP TABLE IF EXISTS quarantine.operators CASCADE;
+CREATE TABLE quarantine.operators (
+  quarantine_id serial NOT NULL,
+  legacy_key text NOT NULL,
+  operator_id text,
+  operator_name text,
+  rule_ids text NOT NULL,
+  messages text NOT NULL,
+  reinstate boolean NOT NULL DEFAULT false,
+  created_at timestamp NOT NULL DEFAULT current_timestamp,
+  updated_at timestamp NOT NULL DEFAULT current_timestamp,
+  created_by varchar(58),
+  updated_by varchar(58)
+);
+
+-- Set Table Comment
+COMMENT ON TABLE quarantine.operators IS '担当者(隔離)';
+
+-- Set Column Comment
+COMMENT ON COLUMN quarantine.operators.quarantine_id IS '隔離ID';
+COMMENT ON COLUMN quarantine.operators.legacy_key IS '移行元キー';
+COMMENT ON COLUMN quarantine.operators.operator_id IS '担当者ID';
+COMMENT ON COLUMN quarantine.operators.operator_name IS '担当者名';
+COMMENT ON COLUMN quarantine.operators.rule_ids IS 'ルールID';
+COMMENT ON COLUMN quarantine.operators.messages IS 'メッセージ';
+COMMENT ON COLUMN quarantine.operators.reinstate IS '再登録承認';
+COMMENT ON COLUMN quarantine.operators.created_at IS '作成日時';
+COMMENT ON COLUMN quarantine.operators.updated_at IS '更新日時';
+COMMENT ON COLUMN quarantine.operators.created_by IS '作成者';
+COMMENT ON COLUMN quarantine.operators.updated_by IS '更新者';
+
+-- Set PK Constraint
+ALTER TABLE quarantine.operators ADD PRIMARY KEY (
+  quarantine_id
+);
+
+-- Create 'set_update_at' Trigger
+CREATE TRIGGER set_updated_at
+  BEFORE UPDATE
+  ON quarantine.operators
+  FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at();
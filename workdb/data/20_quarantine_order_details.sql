@@ -0,0 +1,59 @@
+-- is_master_table=false
+
+-- 4.受注明細(隔離)(order_details)
+
+-- Create Table
+DROP TABLE IF EXISTS quarantine.order_details CASCADE;
+CREATE TABLE quarantine.order_details (
+  quarantine_id serial NOT NULL,
+  legacy_key text NOT NULL,
+  order_no integer,
+  order_detail_no integer,
+  product_name text,
+  receiving_quantity integer,
+  shipping_flag boolean,
+  canceled_flag boolean,
+  selling_price integer,
+  cost_price integer,
+  rule_ids text NOT NULL,
+  messages text NOT NULL,
+  reinstate boolean NOT NULL DEFAULT false,
+  created_at timestamp NOT NULL DEFAULT current_timestamp,
+  updated_at timestamp NOT NULL DEFAULT current_timestamp,
+  created_by varchar(58),
+  updated_by varchar(58)
+);
+
+-- Set Table Comment
+COMMENT ON TABLE quarantine.order_details IS '受注明細(隔離)';
+
+-- Set Column Comment
+COMMENT ON COLUMN quarantine.order_details.quarantine_id IS '隔離ID';
+COMMENT ON COLUMN quarantine.order_details.legacy_key IS '移行元キー';
+COMMENT ON COLUMN quarantine.order_details.order_no IS '受注番号';
+COMMENT ON COLUMN quarantine.order_details.order_detail_no IS '受注明細番号';
+COMMENT ON COLUMN quarantine.order_details.product_name IS '商品名';
+COMMENT ON COLUMN quarantine.order_details.receiving_quantity IS '受注数';
+COMMENT ON COLUMN quarantine.order_details.shipping_flag IS '出荷済フラグ';
+COMMENT ON COLUMN quarantine.order_details.canceled_flag IS 'キャンセルフラグ';
+COMMENT ON COLUMN quarantine.order_details.selling_price IS '販売単価';
+COMMENT ON COLUMN quarantine.order_details.cost_price IS '商品原価';
+COMMENT ON COLUMN quarantine.order_details.rule_ids IS 'ルールID';
+COMMENT ON COLUMN quarantine.order_details.messages IS 'メッセージ';
+COMMENT ON COLUMN quarantine.order_details.reinstate IS '再登録承認';
+COMMENT ON COLUMN quarantine.order_details.created_at IS '作成日時';
+COMMENT ON COLUMN quarantine.order_details.updated_at IS '更新日時';
+COMMENT ON COLUMN quarantine.order_details.created_by IS '作成者';
+COMMENT ON COLUMN quarantine.order_details.updated_by IS '更新者';
+
+-- Set PK Constraint
+ALTER TABLE quarantine.order_details ADD PRIMARY KEY (
+  quarantine_id
+);
+
+-- Create 'set_update_at' Trigger
+CREATE TRIGGER set_updated_at
+  BEFORE UPDATE
+  ON quarantine.order_details
+  FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at();
@@ -0,0 +1,51 @@
+-- is_master_table=false
+
+-- 3.受注(隔離)(orders)
+
+-- Create Table
+DROP TABLE IF EXISTS quarantine.orders CASCADE;
+CREATE TABLE quarantine.orders (
+  quarantine_id serial NOT NULL,
+  legacy_key text NOT NULL,
+  order_no integer,
+  order_date text,
+  order_pic text,
+  customer_name text,
+  rule_ids text NOT NULL,
+  messages text NOT NULL,
+  reinstate boolean NOT NULL DEFAULT false,
+  created_at timestamp NOT NULL DEFAULT current_timestamp,
+  updated_at timestamp NOT NULL DEFAULT current_timestamp,
+  created_by varchar(58),
+  updated_by varchar(58)
+);
+
+-- Set Table Comment
+COMMENT ON TABLE quarantine.orders IS '受注(隔離)';
+
+-- Set Column Comment
+COMMENT ON COLUMN quarantine.orders.quarantine_id IS '隔離ID';
+COMMENT ON COLUMN quarantine.orders.legacy_key IS '移行元キー';
+COMMENT ON COLUMN quarantine.orders.order_no IS '受注番号';
+COMMENT ON COLUMN quarantine.orders.order_date IS '受注日';
+COMMENT ON COLUMN quarantine.orders.order_pic IS '受注担当者名';
+COMMENT ON COLUMN quarantine.orders.customer_name IS '得意先名称';
+COMMENT ON COLUMN quarantine.orders.rule_ids IS 'ルールID';
+COMMENT ON COLUMN quarantine.orders.messages IS 'メッセージ';
+COMMENT ON COLUMN quarantine.orders.reinstate IS '再登録承認';
+COMMENT ON COLUMN quarantine.orders.created_at IS '作成日時';
+COMMENT ON COLUMN quarantine.orders.updated_at IS '更新日時';
+COMMENT ON COLUMN quarantine.orders.created_by IS '作成者';
+COMMENT ON COLUMN quarantine.orders.updated_by IS '更新者';
+
+-- Set PK Constraint
+ALTER TABLE quarantine.orders ADD PRIMARY KEY (
+  quarantine_id
+);
+
+-- Create 'set_update_at' Trigger
+CREATE TRIGGER set_updated_at
+  BEFORE UPDATE
+  ON quarantine.orders
+  FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at();
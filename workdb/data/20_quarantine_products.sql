@@ -0,0 +1,47 @@
+-- is_master_table=false
+
+-- 2.商品(隔離)(products)
+
+-- Create Table
+DROP TABLE IF EXISTS quarantine.products CASCADE;
+CREATE TABLE quarantine.products (
+  quarantine_id serial NOT NULL,
+  legacy_key text NOT NULL,
+  product_name text,
+  cost_price integer,
+  rule_ids text NOT NULL,
+  messages text NOT NULL,
+  reinstate boolean NOT NULL DEFAULT false,
+  created_at timestamp NOT NULL DEFAULT current_timestamp,
+  updated_at timestamp NOT NULL DEFAULT current_timestamp,
+  created_by varchar(58),
+  updated_by varchar(58)
+);
+
+-- Set Table Comment
+COMMENT ON TABLE quarantine.products IS '商品(隔離)';
+
+-- Set Column Comment
+COMMENT ON COLUMN quarantine.products.quarantine_id IS '隔離ID';
+COMMENT ON COLUMN quarantine.products.legacy_key IS '移行元キー';
+COMMENT ON COLUMN quarantine.products.product_name IS '商品名称';
+COMMENT ON COLUMN quarantine.products.cost_price IS '商品原価';
+COMMENT ON COLUMN quarantine.products.rule_ids IS 'ルールID';
+COMMENT ON COLUMN quarantine.products.messages IS 'メッセージ';
+COMMENT ON COLUMN quarantine.products.reinstate IS '再登録承認';
+COMMENT ON COLUMN quarantine.products.created_at IS '作成日時';
+COMMENT ON COLUMN quarantine.products.updated_at IS '更新日時';
+COMMENT ON COLUMN quarantine.products.created_by IS '作成者';
+COMMENT ON COLUMN quarantine.products.updated_by IS '更新者';
+
+-- Set PK Constraint
+ALTER TABLE quarantine.products ADD PRIMARY KEY (
+  quarantine_id
+);
+
+-- Create 'set_update_at' Trigger
+CREATE TRIGGER set_updated_at
+  BEFORE UPDATE
+  ON quarantine.products
+  FOR EACH ROW
+EXECUTE PROCEDURE set_updated_at();